@@ -24,33 +24,107 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/products": {
+        "/admin/jobs": {
             "get": {
-                "description": "This method get a list with all products.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieves the status of every background job: its schedule, whether it is running, when it runs next and the result of its last run.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "admin"
+                ],
+                "summary": "List the background jobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token",
+                        "name": "token",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "Get All products",
                 "responses": {
                     "200": {
-                        "description": "Successfully list of products",
+                        "description": "Successfully retrieved the jobs",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/domain.Product"
+                                "$ref": "#/definitions/scheduler.Status"
                             }
                         }
+                    },
+                    "401": {
+                        "description": "invalid_token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/admin/jobs/{name}/run": {
             "post": {
-                "description": "This method creates a new product entry in the system by taking a JSON input with the required product information. It returns an error if there is an issue with the input data, if the product already exists, or if there is an internal server error.",
+                "description": "Runs a job right away, out of its schedule, and returns the run once it finishes. A job does not run twice at the same time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Run a background job now",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token",
+                        "name": "token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully ran the job",
+                        "schema": {
+                            "$ref": "#/definitions/scheduler.Run"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "job_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "job_running",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "scheduler_not_running",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/carts": {
+            "post": {
+                "description": "Creates an open cart, empty or with some items. Items of the same product are added up.",
                 "consumes": [
                     "application/json"
                 ],
@@ -58,74 +132,102 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "orders"
                 ],
-                "summary": "Create a new product",
+                "summary": "Create a cart",
                 "parameters": [
                     {
-                        "description": "Product Information",
-                        "name": "product",
+                        "description": "Items of the cart",
+                        "name": "cart",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateProductRequest"
+                            "$ref": "#/definitions/handlers.CartRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successfully created product",
+                        "description": "Successfully created the cart",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateProductResponse"
+                            "$ref": "#/definitions/domain.PricedCart"
                         }
                     },
                     "400": {
-                        "description": "BadRequest: invalid data",
+                        "description": "validation_failed or malformed_body",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
-                    "409": {
-                        "description": "Conflict: cannot create the given product, it already exists or error date format",
+                    "404": {
+                        "description": "product_not_found",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "500": {
-                        "description": "InternalServerError: an internal error has occurred",
+                        "description": "internal_error",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     }
                 }
             }
         },
-        "/products/:id": {
-            "put": {
-                "description": "This method update a product entry in the system by taking a JSON input with the required product information and Id. It returns an error if there is an issue with the input data, if the product code already exists, or if there is an internal server error.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/carts/{id}": {
+            "get": {
+                "description": "Retrieves a cart priced at current prices with the consumer pricing rules. Items that could not be checked out right now are listed as unavailable in the quote.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "orders"
                 ],
-                "summary": "Update a product",
+                "summary": "Get a cart",
                 "parameters": [
                     {
-                        "description": "Product Information",
-                        "name": "product",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Cart ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the cart",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateProductRequest"
+                            "$ref": "#/definitions/domain.PricedCart"
+                        }
+                    },
+                    "404": {
+                        "description": "cart_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/carts/{id}/checkout": {
+            "post": {
+                "description": "Turns an open cart into a pending order at current prices. The stock of every item is taken at once, or none when any item is unavailable. A cart is checked out only once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Check out a cart",
+                "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
+                        "type": "string",
+                        "description": "Cart ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -133,33 +235,35 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successfully updated product",
+                        "description": "Successfully created the order",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateProductResponse"
+                            "$ref": "#/definitions/domain.Order"
                         }
                     },
-                    "400": {
-                        "description": "BadRequest: invalid data",
+                    "404": {
+                        "description": "cart_not_found",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "409": {
-                        "description": "Conflict: cannot update product, code already exists or error date format",
+                        "description": "cart_checked_out, empty_cart, items_unavailable or currency_mismatch",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "500": {
-                        "description": "InternalServerError: an internal error has occurred",
+                        "description": "internal_error",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "This method update a product entry in the system by taking a JSON input with the required product information and Id. It returns an error if there is an issue with the input data, if the product code already exists, or if there is an internal server error.",
+            }
+        },
+        "/carts/{id}/items": {
+            "post": {
+                "description": "Adds the quantity of a product to an open cart.",
                 "consumes": [
                     "application/json"
                 ],
@@ -167,105 +271,151 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "orders"
                 ],
-                "summary": "Update partial a product",
+                "summary": "Add an item to a cart",
                 "parameters": [
                     {
-                        "description": "Product Information",
-                        "name": "product",
+                        "type": "string",
+                        "description": "Cart ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product and quantity",
+                        "name": "item",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateProductRequest"
+                            "$ref": "#/definitions/domain.QuoteItem"
                         }
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Successfully updated product",
+                    "200": {
+                        "description": "Successfully added the item",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateProductResponse"
+                            "$ref": "#/definitions/domain.PricedCart"
                         }
                     },
                     "400": {
-                        "description": "BadRequest: invalid data",
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "cart_not_found or product_not_found",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "409": {
-                        "description": "Conflict: cannot update product, code already exists or error date format",
+                        "description": "cart_checked_out",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "500": {
-                        "description": "InternalServerError: an internal error has occurred",
+                        "description": "internal_error",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     }
                 }
             }
         },
-        "/products/consumer-price": {
-            "get": {
-                "description": "Retrieves a list of products with prices for the specified product IDs",
-                "consumes": [
-                    "application/json"
-                ],
+        "/carts/{id}/items/{product_id}": {
+            "delete": {
+                "description": "Removes a product from an open cart, whatever its quantity.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "orders"
                 ],
-                "summary": "Get consumer prices for a list of product IDs",
+                "summary": "Remove an item from a cart",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Comma-separated list of product IDs",
-                        "name": "list",
-                        "in": "query",
+                        "description": "Cart ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "product_id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully retrieved list of consumer products",
+                        "description": "Successfully removed the item",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Product"
-                            }
+                            "$ref": "#/definitions/domain.PricedCart"
                         }
                     },
                     "400": {
-                        "description": "Invalid data",
+                        "description": "validation_failed: invalid product_id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "cart_not_found or cart_item_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "cart_checked_out",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "500": {
-                        "description": "An internal error has occurred",
+                        "description": "internal_error",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     }
                 }
             }
         },
-        "/products/search": {
+        "/categories": {
             "get": {
-                "description": "Retrieves a list of products with a price greater than the specified value",
+                "description": "Retrieves every category sorted by path, each one right after its parent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "List categories",
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the categories",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Category"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a category to the tree, at the root or under a parent. Without a slug, the slug is made from the name. Siblings have different slugs.",
                 "consumes": [
                     "application/json"
                 ],
@@ -273,63 +423,61 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "Search products by price",
+                "summary": "Create a category",
                 "parameters": [
                     {
-                        "type": "number",
-                        "description": "Minimum product price",
-                        "name": "priceGt",
-                        "in": "query",
-                        "required": true
+                        "description": "Category",
+                        "name": "category",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CategoryRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Successfully retrieved list of products",
+                    "201": {
+                        "description": "Successfully created the category",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Product"
-                            }
+                            "$ref": "#/definitions/domain.Category"
                         }
                     },
                     "400": {
-                        "description": "Invalid data",
+                        "description": "validation_failed or malformed_body",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Price must be greater than 0",
+                    "409": {
+                        "description": "category_already_exists",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     }
                 }
             }
         },
-        "/products/{id}": {
+        "/categories/{id}": {
             "get": {
-                "description": "Retrieves a specific product by its ID",
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "Get product by ID",
+                "summary": "Get a category",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -337,33 +485,33 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully retrieved product",
+                        "description": "Successfully retrieved the category",
                         "schema": {
-                            "$ref": "#/definitions/domain.Product"
+                            "$ref": "#/definitions/domain.Category"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the category"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid data",
+                        "description": "validation_failed: invalid id",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "404": {
-                        "description": "Product not found",
+                        "description": "category_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     }
                 }
             },
-            "delete": {
-                "description": "Deletes a specific product by its ID",
+            "put": {
+                "description": "Renames or moves a category, which changes the paths of its descendants too. A category cannot be moved under itself or one of its descendants.",
                 "consumes": [
                     "application/json"
                 ],
@@ -371,51 +519,2644 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "Delete product by ID",
+                "summary": "Update a category",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Category",
+                        "name": "category",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CategoryRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the category read, the update fails if it was modified since",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Successfully deleted product"
+                    "200": {
+                        "description": "Successfully updated the category",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Category"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated category"
+                            }
+                        }
                     },
                     "400": {
-                        "description": "Invalid data",
+                        "description": "validation_failed or malformed_body",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
                     "404": {
-                        "description": "Product not found",
+                        "description": "category_not_found",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
                     },
-                    "500": {
-                        "description": "An internal error has occurred",
+                    "409": {
+                        "description": "category_already_exists or invalid_parent",
                         "schema": {
-                            "$ref": "#/definitions/rest.ErrorResponse"
+                            "$ref": "#/definitions/rest.Problem"
                         }
+                    },
+                    "412": {
+                        "description": "version_conflict: the category was modified since it was read, or is missing with If-Match: *",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a category without subcategories nor products.",
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Delete a category",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successfully deleted the category"
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "category_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "category_not_empty",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/lots/expiring": {
+            "get": {
+                "description": "Retrieves the lots of every product with stock left that expire within some days from today, the lots already expired included, the earliest expiration first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "List the lots expiring soon",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Days from today, 30 by default, at most 365",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the lots",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Lot"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid days",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "get": {
+                "description": "Retrieves the orders, newest first, optionally only those with a status or created between two days, both included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token",
+                        "name": "token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "paid",
+                            "shipped",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Status of the orders",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day, e.g. 2022-01-01",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day, e.g. 2022-01-31",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the orders",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the order",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Order"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/status": {
+            "post": {
+                "description": "Pending orders can be paid or cancelled and paid orders shipped or cancelled. The items of a cancelled order go back to the stock.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Change the status of an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully changed the status",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "invalid_transition",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "description": "This method get a page of products. Without limit every product is returned. Pages continue either by offset or by the opaque next_cursor of the previous page, which is stable while products are created or deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get All products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size, up to 1000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Products to skip, not allowed with cursor",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated fields, prefixed with - for descending, e.g. price,-name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path of a category, only its products are listed, e.g. beverages/wine",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "With category, list the products of its subcategories too",
+                        "name": "include_descendants",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully list of products",
+                        "schema": {
+                            "$ref": "#/definitions/rest.SuccessfulResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "first, prev and next pages"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed, invalid_page, invalid_sort or invalid_cursor",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "This method creates a new product entry in the system by taking a JSON input with the required product information. It returns an error if there is an issue with the input data, if the product already exists, or if there is an internal server error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create a new product",
+                "parameters": [
+                    {
+                        "description": "Product Information",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created product",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateProductResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_already_exists",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/:id": {
+            "put": {
+                "description": "This method update a product entry in the system by taking a JSON input with the required product information and Id. It returns an error if there is an issue with the input data, if the product code already exists, or if there is an internal server error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update a product",
+                "parameters": [
+                    {
+                        "description": "Product Information",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateProductRequest"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product read, the update fails if it was modified since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully updated product",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateProductResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_already_exists",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict: the product was modified since it was read, or is missing with If-Match: *",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "This method changes some fields of a product. The body is a JSON Merge Patch (RFC 7396, also accepted as application/json) where null resets a field, or a JSON Patch (RFC 6902) whose test operations must hold for the patch to apply. The patched product is validated like on update; id, uid, version, categories, parent_id and attributes cannot be changed and unknown fields are rejected.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update partial a product",
+                "parameters": [
+                    {
+                        "description": "Merge patch with the fields to change, or list of JSON Patch operations",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateProductRequest"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product read, the update fails if it was modified since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated product",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateProductResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed, invalid_patch, unknown_field or immutable_field",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "patch_test_failed or product_already_exists",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict: the product was modified since it was read, or is missing with If-Match: *",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "unsupported_media_type: the body is not a merge patch nor a JSON Patch",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/consumer_price": {
+            "get": {
+                "description": "Prices the published products with the specified IDs with the pricing rules, an ID listed several times counting several units. Every line lists the rules that applied to it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get consumer prices for a list of product IDs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of product IDs",
+                        "name": "list",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully priced the products",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ProductsConsumer"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid list",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/import": {
+            "post": {
+                "description": "This method creates every product of a JSON list, or none of them when any is invalid. The violations of all the products are reported at once, with fields prefixed by the index of the product, e.g. [2].price; code values must be unique within the list too.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Import products",
+                "parameters": [
+                    {
+                        "description": "Products to create",
+                        "name": "products",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CreateProductRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created products",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CreateProductResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/quote": {
+            "post": {
+                "description": "Prices the quantities of a list of products with the pricing rules, adding up the items of the same product. Products that are not found, not published or without enough stock are left out of the price and listed as unavailable, with the stock available.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Quote products",
+                "parameters": [
+                    {
+                        "description": "Products and quantities to quote",
+                        "name": "items",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.QuoteItem"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully quoted the products",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Quote"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "currency_mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/search": {
+            "get": {
+                "description": "Retrieves the products whose name matches q, ranked by a BM25 score. Matching ignores case and accents, accepts prefixes and tolerates typos in words of four or more letters.\nWithout q, retrieves the products matching a filter expression, e.g. price\u003e=100 AND is_published=true AND name~\"wine\". Fields: id, name, quantity, code_value, is_published, expiration (YYYY-MM-DD), price. Operators: = != \u003c \u003c= \u003e \u003e= ~ (contains), IN (...), BETWEEN ... AND ..., combined with AND, OR, NOT and parentheses. priceGt is kept as a shorthand for price\u003epriceGt.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Search products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Words of the product name",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum results for q, 20 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter expression",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum product price, greater than 0",
+                        "name": "priceGt",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved list of products, with score when searching by q",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/products.ScoredProduct"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed, invalid_data or invalid_filter with the reason and position",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/suggest": {
+            "get": {
+                "description": "Retrieves the products whose name, a word of their name or code value starts with prefix, ignoring case and accents. Ranked by popularity (reads since the server started) or by price, highest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Suggest products while typing",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Beginning of a name, word or code value",
+                        "name": "prefix",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum suggestions, 10 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "popularity (default) or price",
+                        "name": "rank",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved suggestions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/products.Suggestion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed, invalid_data or invalid_rank",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/trash": {
+            "get": {
+                "description": "Retrieves the deleted products, oldest deletion first, with when and by whom they were deleted. They are purged after the retention window of the trash.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List the products in the trash",
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the trash",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Product"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "get": {
+                "description": "Retrieves a specific product by its ID, or with as_of the product as its history had it at that time, without an ETag.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, e.g. 2022-01-01T12:00:00Z",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token, required with as_of",
+                        "name": "token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy of the product",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved product",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the product"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "The cached copy is still current"
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id or as_of",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_token: as_of without the token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found, or revision_not_found when the history has no revision of the product by as_of",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves a specific product to the trash, recording when and by whom it was deleted. It can be restored until it is purged, and its code value stays taken meanwhile. A product with variants cannot be deleted before its variants.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Who deletes the product, unknown by default",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successfully deleted product"
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_has_variants",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/categories": {
+            "put": {
+                "description": "Replaces the categories of a product. A product can be in any number of categories.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Set the categories of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "IDs of the categories",
+                        "name": "categories",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ProductCategoriesRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product read, the update fails if it was modified since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully set the categories",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict: the product was modified since it was read, or is missing with If-Match: *",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/history": {
+            "get": {
+                "description": "Retrieves the revisions of a product, oldest first: every create, update, patch, rename, change of the categories, delete, restore, revert, purge and change of the stock, with the product as written, the fields it changed, the actor (X-Actor header) and the request ID (X-Request-ID header). The history of deleted products is kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get the history of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token",
+                        "name": "token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the history",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/products.Revision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/history/{revision}/revert": {
+            "post": {
+                "description": "Sets the name, code value, publication, expiration and price of a product back to those of one of its revisions, recording a revert revision. The stock, categories and variant attributes are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Revert a product to a revision",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision number",
+                        "name": "revision",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product read, the revert fails if it was modified since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully reverted the product",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the reverted product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id or revision, or the revision is no longer valid, e.g. expired",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found or revision_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_already_exists or code_value_in_trash",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict: the product was modified since it was read, or is missing with If-Match: *",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/lots": {
+            "get": {
+                "description": "Retrieves the lots of a product with stock left, first-expired-first-out, which is the order their units are sold in.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "List the lots of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the lots",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Lot"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Records the receipt of a lot of a product, stock that expires on its own date. Lot codes are unique among the lots of a product. The quantity of the product adds the units received and its expiration becomes the earliest of its stock.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Receive a lot of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lot",
+                        "name": "lot",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LotRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully received the lot",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Lot"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "lot_already_exists",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/movements": {
+            "get": {
+                "description": "Retrieves the inventory ledger of a product, oldest movement first. The balance of the last movement is the quantity of the product.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "List the movements of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the movements",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Movement"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Records a receipt, sale, adjustment or return of a product and updates its quantity. The quantity of receipts, sales and returns is the positive number of units moved; adjustments add or remove stock as their quantity is positive or negative. Sales cannot take units held by reservations. A movement of a lot only changes that lot; otherwise units added are outside lots and units removed are taken first-expired-first-out, the stock outside lots expiring on the expiration of the product.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Record a movement of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Movement",
+                        "name": "movement",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MovementRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully recorded the movement",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Movement"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found or lot_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "insufficient_stock",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/restore": {
+            "post": {
+                "description": "Moves a product out of the trash, with the data it had when it was deleted. A variant is only restored once its parent is.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Restore a deleted product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID or UID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully restored the product",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the restored product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id, or attributes of a variant taken by another variant",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_in_trash",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "parent_in_trash",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/stock": {
+            "get": {
+                "description": "Retrieves the stock on hand of a product, which is its quantity, the units held by active reservations and the units still available.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Get the stock of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the stock",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Stock"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/variants": {
+            "get": {
+                "description": "Retrieves the variants, or SKUs, of a product sorted by ID. Each variant is a product with its own code value, price, stock and expiration.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "variants"
+                ],
+                "summary": "List the variants of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the variants",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed: invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a variant to a product, told apart from the other variants by its size, color or flavour. Its code value is unique among all products and variants. Without a name, the variant is named after the product and its attributes. Variants cannot have variants.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "variants"
+                ],
+                "summary": "Create a variant of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VariantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created the variant",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the variant"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_already_exists or nested_variant",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/variants/{variant_id}": {
+            "put": {
+                "description": "Replaces a variant of a product. A product that is not a variant and has no variants becomes a variant of the product.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "variants"
+                ],
+                "summary": "Update a variant of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VariantRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the variant read, the update fails if it was modified since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated the variant",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated variant"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found or variant_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_already_exists or nested_variant",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict: the variant was modified since it was read, or is missing with If-Match: *",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservations": {
+            "post": {
+                "description": "Holds the stock of some products for a while, so that quotes and carts can count on it. Items of the same product are added up. Every product must be published and have the units available, otherwise nothing is reserved.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Reserve products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token",
+                        "name": "token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Items to reserve",
+                        "name": "reservation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully reserved the items",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Reservation"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed or malformed_body",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "insufficient_stock",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservations/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Get a reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Reservation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the reservation",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Reservation"
+                        }
+                    },
+                    "404": {
+                        "description": "reservation_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservations/{id}/commit": {
+            "post": {
+                "description": "Records the sale of every item of an active reservation, all of them or none.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Commit a reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token",
+                        "name": "token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reservation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully committed the reservation",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Reservation"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "reservation_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "reservation_closed: the reservation was committed, released or expired",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservations/{id}/release": {
+            "post": {
+                "description": "Returns the stock held by an active reservation.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Release a reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token",
+                        "name": "token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reservation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully released the reservation",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Reservation"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_token",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "reservation_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "reservation_closed: the reservation was committed, released or expired",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.CartStatus": {
+            "type": "string",
+            "enum": [
+                "open",
+                "checked_out"
+            ],
+            "x-enum-varnames": [
+                "CartOpen",
+                "CartCheckedOut"
+            ]
+        },
+        "domain.Category": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Wine"
+                },
+                "parent_id": {
+                    "description": "ParentID is 0 for the categories at the root of the tree.",
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "beverages/wine"
+                },
+                "slug": {
+                    "type": "string",
+                    "example": "wine"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Lot": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "L2022-014"
+                },
+                "expiration": {
+                    "type": "string",
+                    "example": "28/01/2022"
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 24
+                },
+                "received_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.LotQuantity": {
+            "type": "object",
+            "properties": {
+                "lot": {
+                    "type": "string",
+                    "example": "L2022-014"
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": -3
+                }
+            }
+        },
+        "domain.Movement": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer",
+                    "example": 342
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "kind": {
+                    "enum": [
+                        "receipt",
+                        "sale",
+                        "adjustment",
+                        "return"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.MovementKind"
+                        }
+                    ],
+                    "example": "sale"
+                },
+                "lot": {
+                    "description": "Lot is the lot the movement is asked to change. Without one, receipts\nand returns add stock outside lots and the units removed are taken\nfirst-expired-first-out.",
+                    "type": "string",
+                    "example": "L2022-014"
+                },
+                "lots": {
+                    "description": "Lots are the units the movement changed in each lot, none when it only\nchanged stock outside lots.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.LotQuantity"
+                    }
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": -3
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "damaged in transit"
+                },
+                "reservation_id": {
+                    "type": "string",
+                    "example": "01H8XGJWBWBAQ4Z4J1TYXKRRFD"
+                }
+            }
+        },
+        "domain.MovementKind": {
+            "type": "string",
+            "enum": [
+                "receipt",
+                "sale",
+                "adjustment",
+                "return"
+            ],
+            "x-enum-varnames": [
+                "MovementReceipt",
+                "MovementSale",
+                "MovementAdjustment",
+                "MovementReturn"
+            ]
+        },
+        "domain.Order": {
+            "type": "object",
+            "properties": {
+                "cart_id": {
+                    "type": "string",
+                    "example": "01H8XGJWBWBAQ4Z4J1TYXKRRFD"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.OrderEvent"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "01H8XGKA2JY1N6V1JPSZCB5ZBM"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.PriceLine"
+                    }
+                },
+                "reservation_id": {
+                    "type": "string",
+                    "example": "01H8XGK9ZC0QK3M2R9X3T1R7QX"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "paid",
+                        "shipped",
+                        "cancelled"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.OrderStatus"
+                        }
+                    ],
+                    "example": "pending"
+                },
+                "subtotal": {
+                    "type": "number",
+                    "example": 567.05
+                },
+                "surcharges": {
+                    "type": "number",
+                    "example": 119.08
+                },
+                "total_price": {
+                    "type": "number",
+                    "example": 686.13
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.OrderEvent": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.OrderStatus"
+                        }
+                    ],
+                    "example": "paid"
+                }
+            }
+        },
+        "domain.OrderStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "paid",
+                "shipped",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "OrderPending",
+                "OrderPaid",
+                "OrderShipped",
+                "OrderCancelled"
+            ]
+        },
+        "domain.PriceAdjustment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.PriceLine": {
+            "type": "object",
+            "properties": {
+                "adjustments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.PriceAdjustment"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "subtotal": {
+                    "type": "number"
+                },
+                "total": {
+                    "type": "number"
+                },
+                "unit_price": {
+                    "type": "number"
+                }
+            }
+        },
+        "domain.PricedCart": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "01H8XGJWBWBAQ4Z4J1TYXKRRFD"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.QuoteItem"
+                    }
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "quote": {
+                    "$ref": "#/definitions/domain.Quote"
+                },
+                "status": {
+                    "enum": [
+                        "open",
+                        "checked_out"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.CartStatus"
+                        }
+                    ],
+                    "example": "open"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Product": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "description": "Attributes tell a variant apart from the other variants of its\nparent, e.g. {\"size\": \"750ml\"}.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "categories": {
+                    "description": "Categories are the IDs of the categories the product is in, sorted.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "code_value": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is when the product was moved to the trash, nil for the\nproducts out of it, and DeletedBy who moved it there.",
+                    "type": "string"
+                },
+                "deleted_by": {
+                    "type": "string"
+                },
+                "expiration": {
+                    "type": "string",
+                    "example": "28/01/2022"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_published": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "description": "ParentID is the product a variant, or SKU, belongs to, 0 for the\nproducts that are not variants.",
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number",
+                    "example": 275.47
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "uid": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ProductsConsumer": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.PriceLine"
+                    }
+                },
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Product"
+                    }
+                },
+                "subtotal": {
+                    "type": "number",
+                    "example": 352.79
+                },
+                "total_price": {
+                    "type": "number",
+                    "example": 426.88
+                }
+            }
+        },
+        "domain.Quote": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.PriceLine"
+                    }
+                },
+                "subtotal": {
+                    "type": "number",
+                    "example": 1058.37
+                },
+                "surcharges": {
+                    "type": "number",
+                    "example": 222.26
+                },
+                "total_price": {
+                    "type": "number",
+                    "example": 1280.63
+                },
+                "unavailable": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.UnavailableItem"
+                    }
+                }
+            }
+        },
+        "domain.QuoteItem": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "domain.Reservation": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "01H8XGJWBWBAQ4Z4J1TYXKRRFD"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.QuoteItem"
                     }
+                },
+                "status": {
+                    "enum": [
+                        "active",
+                        "committed",
+                        "released",
+                        "expired"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ReservationStatus"
+                        }
+                    ],
+                    "example": "active"
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.Product": {
+        },
+        "domain.ReservationStatus": {
+            "type": "string",
+            "enum": [
+                "active",
+                "committed",
+                "released",
+                "expired"
+            ],
+            "x-enum-varnames": [
+                "ReservationActive",
+                "ReservationCommitted",
+                "ReservationReleased",
+                "ReservationExpired"
+            ]
+        },
+        "domain.Stock": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer",
+                    "example": 342
+                },
+                "on_hand": {
+                    "type": "integer",
+                    "example": 345
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reserved": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "domain.UnavailableItem": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "not_found",
+                        "unpublished",
+                        "insufficient_stock"
+                    ],
+                    "example": "insufficient_stock"
+                }
+            }
+        },
+        "handlers.CartRequest": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.QuoteItem"
+                    }
+                }
+            }
+        },
+        "handlers.CategoryRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Sparkling Wine"
+                },
+                "parent_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "slug": {
+                    "description": "Slug is made from the name when empty.",
+                    "type": "string",
+                    "example": "sparkling-wine"
+                }
+            }
+        },
+        "handlers.CreateProductRequest": {
+            "type": "object",
+            "properties": {
+                "code_value": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "ARS"
+                },
+                "expiration": {
+                    "type": "string",
+                    "example": "28/01/2022"
+                },
+                "is_published": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number",
+                    "example": 275.47
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.CreateProductResponse": {
             "type": "object",
             "properties": {
                 "code_value": {
                     "type": "string"
                 },
+                "currency": {
+                    "type": "string",
+                    "example": "ARS"
+                },
                 "expiration": {
                     "type": "string"
                 },
@@ -429,28 +3170,130 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "price": {
-                    "type": "number"
+                    "description": "Price is written with the decimal places of its currency, e.g.\n275.40, and as a string, e.g. \"275.40\", with MONEY_FORMAT=string.",
+                    "type": "number",
+                    "example": 275.4
                 },
                 "quantity": {
                     "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.CreateProductRequest": {
+        "handlers.LotRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "L2022-014"
+                },
+                "expiration": {
+                    "type": "string",
+                    "example": "28/01/2022"
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 24
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "supplier delivery"
+                }
+            }
+        },
+        "handlers.MovementRequest": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "receipt",
+                        "sale",
+                        "adjustment",
+                        "return"
+                    ],
+                    "example": "receipt"
+                },
+                "lot": {
+                    "description": "Lot is the code of the lot moved, none to take the units sold\nfirst-expired-first-out.",
+                    "type": "string",
+                    "example": "L2022-014"
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "supplier delivery"
+                }
+            }
+        },
+        "handlers.OrderStatusRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "paid",
+                        "shipped",
+                        "cancelled"
+                    ],
+                    "example": "paid"
+                }
+            }
+        },
+        "handlers.ProductCategoriesRequest": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "handlers.ReservationRequest": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.QuoteItem"
+                    }
+                },
+                "ttl_seconds": {
+                    "description": "TTLSeconds is how long the reservation holds the stock, the default of\nthe server when 0.",
+                    "type": "integer",
+                    "example": 900
+                }
+            }
+        },
+        "handlers.VariantRequest": {
             "type": "object",
-            "required": [
-                "code_value",
-                "expiration",
-                "name",
-                "price",
-                "quantity"
-            ],
             "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "size": "750ml"
+                    }
+                },
                 "code_value": {
                     "type": "string"
                 },
+                "currency": {
+                    "type": "string",
+                    "example": "ARS"
+                },
                 "expiration": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "28/01/2022"
                 },
                 "is_published": {
                     "type": "boolean"
@@ -459,22 +3302,152 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "price": {
-                    "type": "number"
+                    "type": "number",
+                    "example": 275.47
                 },
                 "quantity": {
                     "type": "integer"
                 }
             }
         },
-        "handlers.CreateProductResponse": {
+        "products.Change": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "price"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "179.23"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "199.99"
+                }
+            }
+        },
+        "products.Operation": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "patch",
+                "rename",
+                "categorize",
+                "delete",
+                "restore",
+                "revert",
+                "purge",
+                "stock"
+            ],
+            "x-enum-varnames": [
+                "OperationCreate",
+                "OperationUpdate",
+                "OperationPatch",
+                "OperationRename",
+                "OperationCategorize",
+                "OperationDelete",
+                "OperationRestore",
+                "OperationRevert",
+                "OperationPurge",
+                "OperationStock"
+            ]
+        },
+        "products.Revision": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is absent for the changes of the stock, which the inventory\nmakes without one.",
+                    "type": "string",
+                    "example": "ana"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/products.Change"
+                    }
+                },
+                "operation": {
+                    "enum": [
+                        "create",
+                        "update",
+                        "patch",
+                        "rename",
+                        "categorize",
+                        "delete",
+                        "restore",
+                        "revert",
+                        "purge",
+                        "stock"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/products.Operation"
+                        }
+                    ],
+                    "example": "patch"
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "4f9c2a1b7d3e8f60"
+                },
+                "reverted_to": {
+                    "description": "RevertedTo is the revision a revert went back to.",
+                    "type": "integer"
+                },
+                "revision": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "snapshot": {
+                    "description": "Snapshot is the product as written, and as deleted for the deletes.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Product"
+                        }
+                    ]
+                }
+            }
+        },
+        "products.ScoredProduct": {
             "type": "object",
             "properties": {
+                "attributes": {
+                    "description": "Attributes tell a variant apart from the other variants of its\nparent, e.g. {\"size\": \"750ml\"}.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "categories": {
+                    "description": "Categories are the IDs of the categories the product is in, sorted.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "code_value": {
                     "type": "string"
                 },
-                "expiration": {
+                "deleted_at": {
+                    "description": "DeletedAt is when the product was moved to the trash, nil for the\nproducts out of it, and DeletedBy who moved it there.",
                     "type": "string"
                 },
+                "deleted_by": {
+                    "type": "string"
+                },
+                "expiration": {
+                    "type": "string",
+                    "example": "28/01/2022"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -484,25 +3457,152 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "parent_id": {
+                    "description": "ParentID is the product a variant, or SKU, belongs to, 0 for the\nproducts that are not variants.",
+                    "type": "integer"
+                },
                 "price": {
-                    "type": "number"
+                    "type": "number",
+                    "example": 275.47
                 },
                 "quantity": {
                     "type": "integer"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "uid": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "products.Suggestion": {
+            "type": "object",
+            "properties": {
+                "code_value": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "popularity": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
                 }
             }
         },
-        "rest.ErrorResponse": {
+        "rest.Problem": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "string"
                 },
-                "message": {
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.Violation"
+                    }
+                },
+                "instance": {
                     "type": "string"
                 },
                 "status": {
                     "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.SuccessfulResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rest.Violation": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "scheduler.Run": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "result": {},
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "scheduler.Status": {
+            "type": "object",
+            "properties": {
+                "last_run": {
+                    "$ref": "#/definitions/scheduler.Run"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "unpublish-expired"
+                },
+                "next_run": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "runs": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "type": "string",
+                    "example": "0 * * * *"
+                },
+                "skipped": {
+                    "type": "integer"
                 }
             }
         }
@@ -36,6 +36,4 @@ func main() {
 	server.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	router.Setup()
-
-	router.Engine.Run()
 }
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Andrea-Reyna/go-web/cmd/server/middlewares"
+	"github.com/Andrea-Reyna/go-web/internal/categories"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandlers struct {
+	Service  categories.Service
+	Products products.Service
+}
+
+func categoryID(ctx *gin.Context) (int, error) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return 0, rest.InvalidField("id", "type", "must be a category ID")
+	}
+	return id, nil
+}
+
+// @Summary Create a category
+// @Description Adds a category to the tree, at the root or under a parent. Without a slug, the slug is made from the name. Siblings have different slugs.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body CategoryRequest true "Category"
+// @Success 201 {object} domain.Category "Successfully created the category"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 409 {object} rest.Problem "category_already_exists"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /categories [post]
+func (handler CategoryHandlers) Create() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request CategoryRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		category := request.ToDomain()
+		if err := handler.Service.Create(&category); err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.Header("ETag", rest.ETag(category.Version))
+		ctx.JSON(http.StatusCreated, category)
+	}
+}
+
+// @Summary List categories
+// @Description Retrieves every category sorted by path, each one right after its parent.
+// @Tags categories
+// @Produce json
+// @Success 200 {array} domain.Category "Successfully retrieved the categories"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /categories [get]
+func (handler CategoryHandlers) GetAll() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		found, err := handler.Service.GetAll()
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, found)
+	}
+}
+
+// @Summary Get a category
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} domain.Category "Successfully retrieved the category"
+// @Header 200 {string} ETag "Version of the category"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 404 {object} rest.Problem "category_not_found"
+// @Router /categories/{id} [get]
+func (handler CategoryHandlers) FindById() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := categoryID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		category, err := handler.Service.FindById(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.Header("ETag", rest.ETag(category.Version))
+		ctx.JSON(http.StatusOK, category)
+	}
+}
+
+// @Summary Update a category
+// @Description Renames or moves a category, which changes the paths of its descendants too. A category cannot be moved under itself or one of its descendants.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param category body CategoryRequest true "Category"
+// @Param If-Match header string false "ETag of the category read, the update fails if it was modified since"
+// @Success 200 {object} domain.Category "Successfully updated the category"
+// @Header 200 {string} ETag "Version of the updated category"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "category_not_found"
+// @Failure 409 {object} rest.Problem "category_already_exists or invalid_parent"
+// @Failure 412 {object} rest.Problem "version_conflict: the category was modified since it was read, or is missing with If-Match: *"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /categories/{id} [put]
+func (handler CategoryHandlers) Update() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := categoryID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		var request CategoryRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		version, ok := ifMatchVersion(ctx)
+		if !ok {
+			problems.Abort(ctx, categories.ErrVersionConflict)
+			return
+		}
+		category := request.ToDomain()
+		category.ID = id
+		category.Version = version
+		if err := handler.Service.Update(&category); err != nil {
+			problems.Abort(ctx, ifMatchFound(ctx, err, categories.ErrVersionConflict, categories.ErrCategoryNotFound))
+			return
+		}
+		ctx.Header("ETag", rest.ETag(category.Version))
+		ctx.JSON(http.StatusOK, category)
+	}
+}
+
+// @Summary Delete a category
+// @Description Deletes a category without subcategories nor products.
+// @Tags categories
+// @Param id path int true "Category ID"
+// @Success 204 "Successfully deleted the category"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 404 {object} rest.Problem "category_not_found"
+// @Failure 409 {object} rest.Problem "category_not_empty"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /categories/{id} [delete]
+func (handler CategoryHandlers) Delete() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := categoryID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		if err := handler.Service.Delete(id); err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusNoContent, nil)
+	}
+}
+
+// @Summary Set the categories of a product
+// @Description Replaces the categories of a product. A product can be in any number of categories.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param categories body ProductCategoriesRequest true "IDs of the categories"
+// @Param If-Match header string false "ETag of the product read, the update fails if it was modified since"
+// @Success 200 {object} domain.Product "Successfully set the categories"
+// @Header 200 {string} ETag "Version of the updated product"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 412 {object} rest.Problem "version_conflict: the product was modified since it was read, or is missing with If-Match: *"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/categories [put]
+func (handler CategoryHandlers) Assign() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := productID(ctx, handler.Products)
+		if err != nil {
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
+			return
+		}
+		version, ok := ifMatchVersion(ctx)
+		if !ok {
+			problems.Abort(ctx, products.ErrVersionConflict)
+			return
+		}
+		var request ProductCategoriesRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		author := products.Author{Actor: middlewares.Actor(ctx), RequestID: middlewares.RequestID(ctx)}
+		product, err := handler.Service.By(author).Assign(id, version, request.Categories)
+		if err != nil {
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
+			return
+		}
+		ctx.Header("ETag", rest.ETag(product.Version))
+		ctx.JSON(http.StatusOK, product)
+	}
+}
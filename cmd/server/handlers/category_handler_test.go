@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Andrea-Reyna/go-web/internal/categories"
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createServerForTestCategoryHandler serves a wine and a cookie, kept with
+// the categories apart from the other tests.
+func createServerForTestCategoryHandler(t *testing.T) *gin.Engine {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	for _, product := range []domain.Product{
+		{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(17923, "ARS")},
+		{Name: "Cookie", Quantity: 5, CodeValue: "M7157", IsPublished: true, Price: domain.NewMoney(27547, "ARS")},
+	} {
+		require.NoError(t, storage.Create(&product))
+	}
+	categoryStorage, err := categories.NewFileRepository("")
+	require.NoError(t, err)
+	categoryService := categories.DefaultService{Storage: categoryStorage}
+	service := products.DefaultService{Storage: storage, CategoryPaths: categoryService.Paths}
+	categoryService.Products = service
+	productHandler := ProductHandlers{Service: service, Categories: categoryService}
+	handler := CategoryHandlers{Service: categoryService, Products: service}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.GET("/products", productHandler.GetAll())
+	server.PUT("/products/:id/categories", handler.Assign())
+	group := server.Group("categories")
+	group.POST("", handler.Create())
+	group.GET("", handler.GetAll())
+	group.GET("/:id", handler.FindById())
+	group.PUT("/:id", handler.Update())
+	group.DELETE("/:id", handler.Delete())
+	return server
+}
+
+func TestCategoryHandlers(t *testing.T) {
+	t.Run("should list the products of a category and its subcategories", func(t *testing.T) {
+		server := createServerForTestCategoryHandler(t)
+
+		response := serve(server, http.MethodPost, "/categories", `{"name":"Beverages"}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.JSONEq(t, `{"id":1,"name":"Beverages","slug":"beverages","path":"beverages","version":1}`, response.Body.String())
+		response = serve(server, http.MethodPost, "/categories", `{"name":"Red Wine","slug":"wine","parent_id":1}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.JSONEq(t, `{"id":2,"name":"Red Wine","slug":"wine","parent_id":1,"path":"beverages/wine","version":1}`, response.Body.String())
+
+		response = serve(server, http.MethodPut, "/products/1/categories", `{"categories":[2]}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"2"`, response.Header().Get("ETag"))
+		assert.Contains(t, response.Body.String(), `"categories":[2]`)
+
+		var page struct {
+			Data  []domain.Product `json:"data"`
+			Total int              `json:"total"`
+		}
+		response = serve(server, http.MethodGet, "/products?category=beverages", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &page))
+		assert.Equal(t, 0, page.Total)
+
+		response = serve(server, http.MethodGet, "/products?category=beverages&include_descendants=true", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &page))
+		require.Len(t, page.Data, 1)
+		assert.Equal(t, "Wine", page.Data[0].Name)
+
+		response = serve(server, http.MethodDelete, "/categories/2", "")
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "category_not_empty", "category has subcategories or products")
+	})
+
+	t.Run("should only set the categories of the version read", func(t *testing.T) {
+		server := createServerForTestCategoryHandler(t)
+		serve(server, http.MethodPost, "/categories", `{"name":"Beverages"}`)
+
+		request := httptest.NewRequest(http.MethodPut, "/products/1/categories", bytes.NewBufferString(`{"categories":[1]}`))
+		request.Header.Set("If-Match", `"1"`)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"2"`, response.Header().Get("ETag"))
+
+		request = httptest.NewRequest(http.MethodPut, "/products/1/categories", bytes.NewBufferString(`{"categories":[]}`))
+		request.Header.Set("If-Match", `"1"`)
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+		assertProblem(t, response, "version_conflict", "the product was modified since it was read")
+
+		request = httptest.NewRequest(http.MethodPut, "/products/99/categories", bytes.NewBufferString(`{"categories":[]}`))
+		request.Header.Set("If-Match", "*")
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+	})
+
+	t.Run("should report invalid categories", func(t *testing.T) {
+		server := createServerForTestCategoryHandler(t)
+		serve(server, http.MethodPost, "/categories", `{"name":"Beverages"}`)
+
+		response := serve(server, http.MethodPost, "/categories", `{"name":"Drinks","slug":"beverages"}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "category_already_exists", "another category under the same parent has this slug")
+
+		response = serve(server, http.MethodPut, "/categories/1", `{"name":"Beverages","parent_id":1}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"cycle"`)
+
+		response = serve(server, http.MethodGet, "/products?category=food", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"field":"category"`)
+
+		response = serve(server, http.MethodGet, "/categories/99", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "category_not_found", "category not found")
+	})
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import "github.com/Andrea-Reyna/go-web/internal/domain"
+
+type CategoryRequest struct {
+	Name string `json:"name" example:"Sparkling Wine"`
+	// Slug is made from the name when empty.
+	Slug     string `json:"slug,omitempty" example:"sparkling-wine"`
+	ParentID int    `json:"parent_id,omitempty" example:"1"`
+}
+
+func (request CategoryRequest) ToDomain() domain.Category {
+	return domain.Category{Name: request.Name, Slug: request.Slug, ParentID: request.ParentID}
+}
+
+type ProductCategoriesRequest struct {
+	Categories []int `json:"categories"`
+}
@@ -1,23 +1,101 @@
 package handlers
 
-import "github.com/Andrea-Reyna/go-web/internal/domain"
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
 
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Quantity    int     `json:"quantity" binding:"required"`
-	CodeValue   string  `json:"code_value" binding:"required"`
-	IsPublished bool    `json:"is_published"`
-	Expiration  string  `json:"expiration" binding:"required"`
-	Price       float64 `json:"price" binding:"required"`
+	Name        string      `json:"name"`
+	Quantity    int         `json:"quantity"`
+	CodeValue   string      `json:"code_value"`
+	IsPublished bool        `json:"is_published"`
+	Expiration  string      `json:"expiration" example:"28/01/2022"`
+	Price       json.Number `json:"price" swaggertype:"number" example:"275.47"`
+	Currency    string      `json:"currency,omitempty" example:"ARS"`
 }
 
-func (request CreateProductRequest) ToDomain() domain.Product {
+// ToDomain fails with violations of the expiration, price and currency
+// fields when they cannot be read; the other fields are validated by the
+// service.
+func (request CreateProductRequest) ToDomain() (domain.Product, error) {
+	var violations validation.Errors
+	var expiration domain.Date
+	if request.Expiration != "" {
+		var err error
+		if expiration, err = domain.ParseDate(request.Expiration); err != nil {
+			if err := appendFieldError(&violations, err); err != nil {
+				return domain.Product{}, err
+			}
+		}
+	}
+	currency := domain.DefaultCurrency()
+	if request.Currency != "" {
+		var err error
+		if currency, err = domain.ParseCurrency(request.Currency); err != nil {
+			if err := appendFieldError(&violations, err); err != nil {
+				return domain.Product{}, err
+			}
+		}
+	}
+	var price domain.Money
+	if request.Price != "" && currency != "" {
+		var err error
+		if price, err = domain.ParseMoney(request.Price.String(), currency); err != nil {
+			if err := appendFieldError(&violations, err); err != nil {
+				return domain.Product{}, err
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return domain.Product{}, violations
+	}
+	price.Currency = currency
 	return domain.Product{
 		Name:        request.Name,
 		Quantity:    request.Quantity,
 		CodeValue:   request.CodeValue,
 		IsPublished: request.IsPublished,
-		Expiration:  request.Expiration,
-		Price:       request.Price,
+		Expiration:  expiration,
+		Price:       price,
+	}, nil
+}
+
+// fieldError reports an error reading a date, an amount or a currency as a
+// violation of the field it comes from, or returns err when it is about
+// something else.
+func fieldError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrAmbiguousDate), errors.Is(err, domain.ErrInvalidDate):
+		return dateError("expiration", err)
+	case errors.Is(err, domain.ErrInvalidAmount):
+		return rest.InvalidField("price", "amount", "must be a decimal number with at most the decimal places of its currency")
+	case errors.Is(err, domain.ErrUnknownCurrency):
+		return rest.InvalidField("currency", "currency", "must be a supported ISO 4217 currency code")
+	default:
+		return err
+	}
+}
+
+// appendFieldError appends the violations fieldError reports err as to
+// violations, or returns err when it is not about a field.
+func appendFieldError(violations *validation.Errors, err error) error {
+	var fields validation.Errors
+	if !errors.As(fieldError(err), &fields) {
+		return err
+	}
+	*violations = append(*violations, fields...)
+	return nil
+}
+
+// dateError reports an error reading a date as a violation of field.
+func dateError(field string, err error) error {
+	if errors.Is(err, domain.ErrAmbiguousDate) {
+		return rest.InvalidField(field, "ambiguous_date", "is ambiguous, use DD/MM/YYYY, YYYY-MM-DD or RFC 3339 with an offset")
 	}
+	return rest.InvalidField(field, "date", "must be a date formatted DD/MM/YYYY, YYYY-MM-DD or RFC 3339")
 }
@@ -1,11 +1,15 @@
 package handlers
 
 type CreateProductResponse struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Quantity    int     `json:"quantity"`
-	CodeValue   string  `json:"code_value"`
-	IsPublished bool    `json:"is_published"`
-	Expiration  string  `json:"expiration"`
-	Price       float64 `json:"price"`
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity"`
+	CodeValue   string `json:"code_value"`
+	IsPublished bool   `json:"is_published"`
+	Expiration  string `json:"expiration"`
+	// Price is written with the decimal places of its currency, e.g.
+	// 275.40, and as a string, e.g. "275.40", with MONEY_FORMAT=string.
+	Price    float64 `json:"price" swaggertype:"number" example:"275.40"`
+	Currency string  `json:"currency" example:"ARS"`
+	Version  int     `json:"version"`
 }
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/cmd/server/middlewares"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get the history of a product
+// @Description Retrieves the revisions of a product, oldest first: every create, update, patch, rename, change of the categories, delete, restore, revert, purge and change of the stock, with the product as written, the fields it changed, the actor (X-Actor header) and the request ID (X-Request-ID header). The history of deleted products is kept.
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param token header string true "Token"
+// @Success 200 {array} products.Revision "Successfully retrieved the history"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 401 {object} rest.Problem "invalid_token"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Router /products/{id}/history [get]
+func (handler ProductHandlers) History() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := handler.productID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		revisions, err := handler.Service.Revisions(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, revisions)
+	}
+}
+
+// @Summary Revert a product to a revision
+// @Description Sets the name, code value, publication, expiration and price of a product back to those of one of its revisions, recording a revert revision. The stock, categories and variant attributes are kept.
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param revision path int true "Revision number"
+// @Param If-Match header string false "ETag of the product read, the revert fails if it was modified since"
+// @Success 200 {object} domain.Product "Successfully reverted the product"
+// @Header 200 {string} ETag "Version of the reverted product"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id or revision, or the revision is no longer valid, e.g. expired"
+// @Failure 404 {object} rest.Problem "product_not_found or revision_not_found"
+// @Failure 409 {object} rest.Problem "product_already_exists or code_value_in_trash"
+// @Failure 412 {object} rest.Problem "version_conflict: the product was modified since it was read, or is missing with If-Match: *"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/history/{revision}/revert [post]
+func (handler ProductHandlers) Revert() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := handler.productID(ctx)
+		if err != nil {
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
+			return
+		}
+		revision, err := strconv.Atoi(ctx.Param("revision"))
+		if err != nil {
+			problems.Abort(ctx, rest.InvalidField("revision", "type", "must be an integer"))
+			return
+		}
+		version, ok := ifMatchVersion(ctx)
+		if !ok {
+			problems.Abort(ctx, products.ErrVersionConflict)
+			return
+		}
+
+		product, err := handler.by(ctx).Revert(id, version, revision)
+		if err != nil {
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
+			return
+		}
+		ctx.Header("ETag", rest.ETag(product.Version))
+		ctx.JSON(http.StatusOK, product)
+	}
+}
+
+// findByIdAsOf serves FindById for the product as it was at the RFC 3339
+// time value. A past product has no ETag, since it cannot be written back.
+// The history tells who wrote the product and what it was in the trash, so
+// it takes the token, like the history itself.
+func (handler ProductHandlers) findByIdAsOf(ctx *gin.Context, id int, value string) {
+	if !middlewares.ValidToken(ctx) {
+		problems.Abort(ctx, middlewares.ErrInvalidToken)
+		return
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		problems.Abort(ctx, rest.InvalidField("as_of", "invalid", "must be an RFC 3339 time, e.g. 2022-01-01T12:00:00Z"))
+		return
+	}
+	product, err := handler.Service.FindByIdAsOf(id, at)
+	if err != nil {
+		problems.Abort(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/cmd/server/middlewares"
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createServerForTestHistoryHandler serves products with their history in a
+// file, and their stock, on a clock that only moves when now is changed. The
+// history takes the token testToken.
+func createServerForTestHistoryHandler(t *testing.T) (*gin.Engine, *time.Time) {
+	t.Setenv("TOKEN", testToken)
+	dir := t.TempDir()
+	storage, err := products.NewSQLiteRepository(filepath.Join(dir, "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	history, err := products.OpenHistory(filepath.Join(dir, "history.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { history.Close() })
+	ledger, err := inventory.OpenLedger("")
+	require.NoError(t, err)
+	stock, err := inventory.NewRepository(storage, ledger)
+	require.NoError(t, err)
+	now := testNow
+	stock.Now = func() time.Time { return now }
+	service := products.DefaultService{
+		Storage: stock,
+		Now:     func() time.Time { return now },
+		History: history,
+	}
+	stock.Changed = func(previous *domain.Product, current domain.Product) {
+		service.Record(products.OperationStock, previous, current)
+	}
+	handler := ProductHandlers{Service: service}
+	inventoryHandler := InventoryHandlers{Service: inventory.DefaultService{Storage: stock}, Products: service}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(middlewares.AssignRequestID)
+	server.POST("/products", handler.Create())
+	server.GET("/products/:id", handler.FindById())
+	server.PATCH("/products/:id", handler.UpdatePartial())
+	server.DELETE("/products/:id", handler.Delete())
+	server.POST("/products/:id/restore", handler.Restore())
+	server.GET("/products/:id/history", middlewares.ValidateToken, handler.History())
+	server.POST("/products/:id/history/:revision/revert", handler.Revert())
+	server.POST("/products/:id/movements", inventoryHandler.Record())
+	return server, &now
+}
+
+// serveAs serves a request made by actor in the request requestID.
+func serveAs(server *gin.Engine, method, target, body, actor, requestID string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	request.Header.Set(middlewares.ActorHeader, actor)
+	request.Header.Set(middlewares.RequestIDHeader, requestID)
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+const testToken = "secret"
+
+// serveWithToken serves a GET request with token.
+func serveWithToken(server *gin.Engine, target, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	request.Header.Set("token", token)
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+func history(t *testing.T, server *gin.Engine, target string) []products.Revision {
+	t.Helper()
+	response := serveWithToken(server, target, testToken)
+	require.Equal(t, http.StatusOK, response.Code)
+	var revisions []products.Revision
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &revisions))
+	return revisions
+}
+
+func TestHistoryHandlers(t *testing.T) {
+	t.Run("should record who changed a product and what it was before", func(t *testing.T) {
+		server, now := createServerForTestHistoryHandler(t)
+		response := serveAs(server, http.MethodPost, "/products", `{"name":"Wine","quantity":10,"code_value":"T65812","is_published":true,"expiration":"01/06/2030","price":179.23}`, "ana", "req-1")
+		require.Equal(t, http.StatusCreated, response.Code)
+		assert.Equal(t, "req-1", response.Header().Get(middlewares.RequestIDHeader))
+		*now = now.Add(time.Hour)
+		response = serveAs(server, http.MethodPatch, "/products/1", `{"price":199.99}`, "luis", "req-2")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		revisions := history(t, server, "/products/1/history")
+		require.Len(t, revisions, 2)
+		assert.Equal(t, 1, revisions[0].Number)
+		assert.Equal(t, products.OperationCreate, revisions[0].Operation)
+		assert.Equal(t, "ana", revisions[0].Actor)
+		assert.Equal(t, "req-1", revisions[0].RequestID)
+		assert.Equal(t, testNow, revisions[0].At)
+		assert.Equal(t, 2, revisions[1].Number)
+		assert.Equal(t, products.OperationPatch, revisions[1].Operation)
+		assert.Equal(t, "luis", revisions[1].Actor)
+		assert.Equal(t, []products.Change{{Field: "price", From: 179.23, To: 199.99}}, revisions[1].Changes)
+		assert.Equal(t, domain.NewMoney(19999, "ARS"), revisions[1].Snapshot.Price)
+	})
+
+	t.Run("should give requests without an ID one", func(t *testing.T) {
+		server, _ := createServerForTestHistoryHandler(t)
+		response := serve(server, http.MethodPost, "/products", `{"name":"Wine","quantity":10,"code_value":"T65812","is_published":true,"expiration":"01/06/2030","price":179.23}`)
+		require.Equal(t, http.StatusCreated, response.Code)
+
+		revisions := history(t, server, "/products/1/history")
+		require.Len(t, revisions, 1)
+		assert.Equal(t, middlewares.UnknownActor, revisions[0].Actor)
+		assert.NotEmpty(t, revisions[0].RequestID)
+		assert.Equal(t, response.Header().Get(middlewares.RequestIDHeader), revisions[0].RequestID)
+	})
+
+	t.Run("should read a product as it was at a time", func(t *testing.T) {
+		server, now := createServerForTestHistoryHandler(t)
+		require.Equal(t, http.StatusCreated, serve(server, http.MethodPost, "/products", `{"name":"Wine","quantity":10,"code_value":"T65812","is_published":true,"expiration":"01/06/2030","price":179.23}`).Code)
+		*now = now.Add(time.Hour)
+		require.Equal(t, http.StatusOK, serve(server, http.MethodPatch, "/products/1", `{"price":199.99}`).Code)
+		*now = now.Add(time.Hour)
+		require.Equal(t, http.StatusNoContent, serve(server, http.MethodDelete, "/products/1", "").Code)
+
+		response := serveWithToken(server, "/products/1?as_of=2022-01-01T12:30:00Z", testToken)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"price":179.23`)
+		assert.Empty(t, response.Header().Get("ETag"))
+		response = serveWithToken(server, "/products/1?as_of=2022-01-01T13:00:00Z", testToken)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"price":199.99`)
+		response = serveWithToken(server, "/products/1?as_of=2022-01-01T14:00:00Z", testToken)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveWithToken(server, "/products/1?as_of=2021-12-31T12:00:00Z", testToken)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "revision_not_found", "revision not found")
+		response = serveWithToken(server, "/products/1?as_of=yesterday", testToken)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("should record the changes of the stock", func(t *testing.T) {
+		server, now := createServerForTestHistoryHandler(t)
+		require.Equal(t, http.StatusCreated, serve(server, http.MethodPost, "/products", `{"name":"Wine","quantity":10,"code_value":"T65812","is_published":true,"expiration":"01/06/2030","price":179.23}`).Code)
+		*now = now.Add(time.Hour)
+		require.Equal(t, http.StatusCreated, serve(server, http.MethodPost, "/products/1/movements", `{"kind":"sale","qty":4}`).Code)
+
+		revisions := history(t, server, "/products/1/history")
+		require.Len(t, revisions, 2)
+		assert.Equal(t, products.OperationStock, revisions[1].Operation)
+		assert.Equal(t, []products.Change{{Field: "quantity", From: 10.0, To: 6.0}}, revisions[1].Changes)
+		response := serveWithToken(server, "/products/1?as_of=2022-01-01T12:30:00Z", testToken)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"quantity":10`)
+		response = serveWithToken(server, "/products/1?as_of=2022-01-01T13:00:00Z", testToken)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"quantity":6`)
+	})
+
+	t.Run("should keep the history of deleted products", func(t *testing.T) {
+		server, _ := createServerForTestHistoryHandler(t)
+		require.Equal(t, http.StatusCreated, serve(server, http.MethodPost, "/products", `{"name":"Wine","quantity":10,"code_value":"T65812","is_published":true,"expiration":"01/06/2030","price":179.23}`).Code)
+		require.Equal(t, http.StatusNoContent, deleteAs(server, "/products/1", "ana").Code)
+
+		revisions := history(t, server, "/products/1/history")
+		require.Len(t, revisions, 2)
+		assert.Equal(t, products.OperationDelete, revisions[1].Operation)
+		assert.Equal(t, "ana", revisions[1].Snapshot.DeletedBy)
+		require.Equal(t, http.StatusOK, serve(server, http.MethodPost, "/products/1/restore", "").Code)
+		revisions = history(t, server, "/products/1/history")
+		require.Len(t, revisions, 3)
+		assert.Equal(t, products.OperationRestore, revisions[2].Operation)
+
+		response := serveWithToken(server, "/products/2/history", testToken)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "product_not_found", "product not found")
+	})
+
+	t.Run("should require the token for the history", func(t *testing.T) {
+		server, _ := createServerForTestHistoryHandler(t)
+		require.Equal(t, http.StatusCreated, serve(server, http.MethodPost, "/products", `{"name":"Wine","quantity":10,"code_value":"T65812","is_published":true,"expiration":"01/06/2030","price":179.23}`).Code)
+
+		response := serveWithToken(server, "/products/1/history", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		response = serve(server, http.MethodGet, "/products/1?as_of=2022-01-01T12:00:00Z", "")
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assertProblem(t, response, "invalid_token", "invalid token")
+		assert.Equal(t, http.StatusOK, serve(server, http.MethodGet, "/products/1", "").Code)
+	})
+
+	t.Run("should revert a product to a revision", func(t *testing.T) {
+		server, _ := createServerForTestHistoryHandler(t)
+		require.Equal(t, http.StatusCreated, serve(server, http.MethodPost, "/products", `{"name":"Wine","quantity":10,"code_value":"T65812","is_published":true,"expiration":"01/06/2030","price":179.23}`).Code)
+		require.Equal(t, http.StatusOK, serve(server, http.MethodPatch, "/products/1", `{"name":"Red wine","price":199.99}`).Code)
+
+		response := serve(server, http.MethodPost, "/products/1/history/1/revert", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+		assert.Contains(t, response.Body.String(), `"name":"Wine"`)
+		assert.Contains(t, response.Body.String(), `"price":179.23`)
+
+		revisions := history(t, server, "/products/1/history")
+		require.Len(t, revisions, 3)
+		assert.Equal(t, products.OperationRevert, revisions[2].Operation)
+		assert.Equal(t, 1, revisions[2].RevertedTo)
+
+		response = serve(server, http.MethodPost, "/products/1/history/9/revert", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "revision_not_found", "revision not found")
+		request := httptest.NewRequest(http.MethodPost, "/products/1/history/2/revert", nil)
+		request.Header.Set("If-Match", `"1"`)
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+	})
+
+}
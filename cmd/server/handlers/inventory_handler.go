@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultExpiringDays is how far ahead Expiring looks without a days query.
+const defaultExpiringDays = 30
+
+type InventoryHandlers struct {
+	Service inventory.Service
+	// Products resolves the UIDs of products in paths.
+	Products products.Service
+}
+
+// @Summary Get the stock of a product
+// @Description Retrieves the stock on hand of a product, which is its quantity, the units held by active reservations and the units still available.
+// @Tags inventory
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} domain.Stock "Successfully retrieved the stock"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Router /products/{id}/stock [get]
+func (handler InventoryHandlers) Stock() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := productID(ctx, handler.Products)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		stock, err := handler.Service.Stock(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, stock)
+	}
+}
+
+// @Summary List the movements of a product
+// @Description Retrieves the inventory ledger of a product, oldest movement first. The balance of the last movement is the quantity of the product.
+// @Tags inventory
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} domain.Movement "Successfully retrieved the movements"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Router /products/{id}/movements [get]
+func (handler InventoryHandlers) Movements() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := productID(ctx, handler.Products)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		movements, err := handler.Service.Movements(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, movements)
+	}
+}
+
+// @Summary Record a movement of a product
+// @Description Records a receipt, sale, adjustment or return of a product and updates its quantity. The quantity of receipts, sales and returns is the positive number of units moved; adjustments add or remove stock as their quantity is positive or negative. Sales cannot take units held by reservations. A movement of a lot only changes that lot; otherwise units added are outside lots and units removed are taken first-expired-first-out, the stock outside lots expiring on the expiration of the product.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param movement body MovementRequest true "Movement"
+// @Success 201 {object} domain.Movement "Successfully recorded the movement"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "product_not_found or lot_not_found"
+// @Failure 409 {object} rest.Problem "insufficient_stock"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/movements [post]
+func (handler InventoryHandlers) Record() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := productID(ctx, handler.Products)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		var request MovementRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		movement, err := handler.Service.Record(request.ToDomain(id))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, movement)
+	}
+}
+
+// @Summary List the lots of a product
+// @Description Retrieves the lots of a product with stock left, first-expired-first-out, which is the order their units are sold in.
+// @Tags inventory
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} domain.Lot "Successfully retrieved the lots"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Router /products/{id}/lots [get]
+func (handler InventoryHandlers) Lots() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := productID(ctx, handler.Products)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		lots, err := handler.Service.Lots(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, lots)
+	}
+}
+
+// @Summary Receive a lot of a product
+// @Description Records the receipt of a lot of a product, stock that expires on its own date. Lot codes are unique among the lots of a product. The quantity of the product adds the units received and its expiration becomes the earliest of its stock.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param lot body LotRequest true "Lot"
+// @Success 201 {object} domain.Lot "Successfully received the lot"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 409 {object} rest.Problem "lot_already_exists"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/lots [post]
+func (handler InventoryHandlers) Receive() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := productID(ctx, handler.Products)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		var request LotRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		lot, err := request.ToDomain(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		lot, err = handler.Service.Receive(lot, request.Reason)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, lot)
+	}
+}
+
+// @Summary List the lots expiring soon
+// @Description Retrieves the lots of every product with stock left that expire within some days from today, the lots already expired included, the earliest expiration first.
+// @Tags inventory
+// @Produce json
+// @Param days query int false "Days from today, 30 by default, at most 365"
+// @Success 200 {array} domain.Lot "Successfully retrieved the lots"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid days"
+// @Router /lots/expiring [get]
+func (handler InventoryHandlers) Expiring() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		days, err := intQuery(ctx, "days", defaultExpiringDays)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		lots, err := handler.Service.Expiring(days)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, lots)
+	}
+}
+
+// @Summary Reserve products
+// @Description Holds the stock of some products for a while, so that quotes and carts can count on it. Items of the same product are added up. Every product must be published and have the units available, otherwise nothing is reserved.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param reservation body ReservationRequest true "Items to reserve"
+// @Success 201 {object} domain.Reservation "Successfully reserved the items"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 401 {object} rest.Problem "invalid_token"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 409 {object} rest.Problem "insufficient_stock"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /reservations [post]
+func (handler InventoryHandlers) Reserve() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request ReservationRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		reservation, err := handler.Service.Reserve(request.Items, request.TTL())
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, reservation)
+	}
+}
+
+// @Summary Get a reservation
+// @Tags inventory
+// @Produce json
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} domain.Reservation "Successfully retrieved the reservation"
+// @Failure 404 {object} rest.Problem "reservation_not_found"
+// @Router /reservations/{id} [get]
+func (handler InventoryHandlers) FindReservation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reservation, err := handler.Service.FindReservation(ctx.Param("id"))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, reservation)
+	}
+}
+
+// @Summary Commit a reservation
+// @Description Records the sale of every item of an active reservation, all of them or none.
+// @Tags inventory
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} domain.Reservation "Successfully committed the reservation"
+// @Failure 401 {object} rest.Problem "invalid_token"
+// @Failure 404 {object} rest.Problem "reservation_not_found"
+// @Failure 409 {object} rest.Problem "reservation_closed: the reservation was committed, released or expired"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /reservations/{id}/commit [post]
+func (handler InventoryHandlers) Commit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reservation, err := handler.Service.Commit(ctx.Param("id"))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, reservation)
+	}
+}
+
+// @Summary Release a reservation
+// @Description Returns the stock held by an active reservation.
+// @Tags inventory
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} domain.Reservation "Successfully released the reservation"
+// @Failure 401 {object} rest.Problem "invalid_token"
+// @Failure 404 {object} rest.Problem "reservation_not_found"
+// @Failure 409 {object} rest.Problem "reservation_closed: the reservation was committed, released or expired"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /reservations/{id}/release [post]
+func (handler InventoryHandlers) Release() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reservation, err := handler.Service.Release(ctx.Param("id"))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, reservation)
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createServerForTestInventoryHandler serves a published product with 10
+// units, kept in a database of its own so the stock movements do not leak
+// into the other tests.
+func createServerForTestInventoryHandler(t *testing.T) *gin.Engine {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	product := domain.Product{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(17923, "ARS")}
+	require.NoError(t, storage.Create(&product))
+
+	ledger, err := inventory.OpenLedger("")
+	require.NoError(t, err)
+	stock, err := inventory.NewRepository(storage, ledger)
+	require.NoError(t, err)
+	stock.Now = func() time.Time { return testNow }
+	inventoryService := inventory.DefaultService{Storage: stock}
+	service := products.DefaultService{Storage: stock, Now: func() time.Time { return testNow }, Available: inventoryService.Available, Expiration: inventoryService.Expiration}
+
+	productHandler := ProductHandlers{Service: service}
+	handler := InventoryHandlers{Service: inventoryService, Products: service}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	group := server.Group("products")
+	group.GET("/:id", productHandler.FindById())
+	group.POST("/quote", productHandler.Quote())
+	group.GET("/:id/stock", handler.Stock())
+	group.GET("/:id/movements", handler.Movements())
+	group.POST("/:id/movements", handler.Record())
+	group.GET("/:id/lots", handler.Lots())
+	group.POST("/:id/lots", handler.Receive())
+	server.GET("/lots/expiring", handler.Expiring())
+	reservations := server.Group("reservations")
+	reservations.POST("", handler.Reserve())
+	reservations.GET("/:id", handler.FindReservation())
+	reservations.POST("/:id/commit", handler.Commit())
+	reservations.POST("/:id/release", handler.Release())
+	return server
+}
+
+func serve(server *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+func TestInventoryHandlers_Record(t *testing.T) {
+	t.Run("should record a receipt and update the quantity", func(t *testing.T) {
+		server := createServerForTestInventoryHandler(t)
+
+		response := serve(server, http.MethodPost, "/products/1/movements", `{"kind":"receipt","qty":5,"reason":"supplier delivery"}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.JSONEq(t, `{"id":2,"product_id":1,"kind":"receipt","qty":5,"balance":15,"reason":"supplier delivery","created_at":"2022-01-01T12:00:00Z"}`, response.Body.String())
+
+		response = serve(server, http.MethodGet, "/products/1", "")
+		var product domain.Product
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &product))
+		assert.Equal(t, 15, product.Quantity)
+
+		response = serve(server, http.MethodGet, "/products/1/movements", "")
+		var movements []domain.Movement
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &movements))
+		assert.Len(t, movements, 2)
+	})
+
+	t.Run("should report insufficient stock", func(t *testing.T) {
+		server := createServerForTestInventoryHandler(t)
+
+		response := serve(server, http.MethodPost, "/products/1/movements", `{"kind":"sale","qty":11}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "insufficient_stock", "insufficient stock")
+	})
+
+	t.Run("should report unknown products", func(t *testing.T) {
+		server := createServerForTestInventoryHandler(t)
+
+		response := serve(server, http.MethodGet, "/products/99/stock", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestInventoryHandlers_Reservations(t *testing.T) {
+	t.Run("should hold stock until the reservation is committed", func(t *testing.T) {
+		server := createServerForTestInventoryHandler(t)
+
+		response := serve(server, http.MethodPost, "/reservations", `{"items":[{"id":1,"qty":4}],"ttl_seconds":60}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		var reservation domain.Reservation
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &reservation))
+		assert.Equal(t, domain.ReservationActive, reservation.Status)
+		assert.Equal(t, testNow.Add(time.Minute), reservation.ExpiresAt)
+
+		response = serve(server, http.MethodGet, "/products/1/stock", "")
+		assert.JSONEq(t, `{"product_id":1,"on_hand":10,"reserved":4,"available":6}`, response.Body.String())
+
+		// Quotes only count on the stock that is not reserved.
+		response = serve(server, http.MethodPost, "/products/quote", `[{"id":1,"qty":7}]`)
+		assert.Contains(t, response.Body.String(), `"unavailable":[{"id":1,"qty":7,"available":6,"reason":"insufficient_stock"}]`)
+
+		response = serve(server, http.MethodPost, "/reservations/"+reservation.ID+"/commit", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		response = serve(server, http.MethodGet, "/products/1/stock", "")
+		assert.JSONEq(t, `{"product_id":1,"on_hand":6,"reserved":0,"available":6}`, response.Body.String())
+
+		response = serve(server, http.MethodPost, "/reservations/"+reservation.ID+"/release", "")
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "reservation_closed", "reservation is not active: it is committed")
+	})
+
+	t.Run("should report unknown reservations", func(t *testing.T) {
+		server := createServerForTestInventoryHandler(t)
+
+		response := serve(server, http.MethodGet, "/reservations/unknown", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "reservation_not_found", "reservation not found")
+	})
+}
+
+func TestInventoryHandlers_Lots(t *testing.T) {
+	t.Run("should report the earliest expiration of the lots received", func(t *testing.T) {
+		server := createServerForTestInventoryHandler(t)
+
+		response := serve(server, http.MethodPost, "/products/1/lots", `{"code":"L2","qty":4,"expiration":"01/03/2022"}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.JSONEq(t, `{"product_id":1,"code":"L2","qty":4,"expiration":"01/03/2022","received_at":"2022-01-01T12:00:00Z"}`, response.Body.String())
+		response = serve(server, http.MethodPost, "/products/1/lots", `{"code":"L1","qty":3,"expiration":"2022-01-20"}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+
+		var product domain.Product
+		response = serve(server, http.MethodGet, "/products/1", "")
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &product))
+		assert.Equal(t, 17, product.Quantity)
+		assert.Equal(t, domain.NewDate(2022, time.January, 20), product.Expiration)
+
+		response = serve(server, http.MethodPost, "/products/1/movements", `{"kind":"sale","qty":12}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Contains(t, response.Body.String(), `"lots":[{"lot":"L1","qty":-2}]`)
+
+		var lots []domain.Lot
+		response = serve(server, http.MethodGet, "/lots/expiring?days=30", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &lots))
+		require.Len(t, lots, 1)
+		assert.Equal(t, "L1", lots[0].Code)
+		assert.Equal(t, 1, lots[0].Quantity)
+
+		response = serve(server, http.MethodGet, "/products/1/lots", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &lots))
+		assert.Len(t, lots, 2)
+	})
+
+	t.Run("should report invalid lots", func(t *testing.T) {
+		server := createServerForTestInventoryHandler(t)
+		serve(server, http.MethodPost, "/products/1/lots", `{"code":"L1","qty":3,"expiration":"2022-01-20"}`)
+
+		response := serve(server, http.MethodPost, "/products/1/lots", `{"code":"L1","qty":3,"expiration":"2022-02-20"}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "lot_already_exists", "the product has another lot with this code")
+
+		response = serve(server, http.MethodPost, "/products/1/lots", `{"code":"L2","qty":3}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"field":"expiration"`)
+
+		response = serve(server, http.MethodPost, "/products/1/movements", `{"kind":"sale","qty":1,"lot":"L9"}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "lot_not_found", "lot not found: L9")
+
+		response = serve(server, http.MethodGet, "/lots/expiring?days=-1", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"field":"days"`)
+	})
+}
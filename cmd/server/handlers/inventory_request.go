@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+type MovementRequest struct {
+	Kind     string `json:"kind" enums:"receipt,sale,adjustment,return" example:"receipt"`
+	Quantity int    `json:"qty" example:"20"`
+	Reason   string `json:"reason" example:"supplier delivery"`
+	// Lot is the code of the lot moved, none to take the units sold
+	// first-expired-first-out.
+	Lot string `json:"lot,omitempty" example:"L2022-014"`
+}
+
+func (request MovementRequest) ToDomain(productID int) domain.Movement {
+	return domain.Movement{
+		ProductID: productID,
+		Kind:      domain.MovementKind(request.Kind),
+		Quantity:  request.Quantity,
+		Reason:    request.Reason,
+		Lot:       request.Lot,
+	}
+}
+
+type LotRequest struct {
+	Code       string `json:"code" example:"L2022-014"`
+	Quantity   int    `json:"qty" example:"24"`
+	Expiration string `json:"expiration" example:"28/01/2022"`
+	Reason     string `json:"reason" example:"supplier delivery"`
+}
+
+// ToDomain fails with a violation of the expiration when it cannot be read;
+// the other fields are validated by the service.
+func (request LotRequest) ToDomain(productID int) (domain.Lot, error) {
+	lot := domain.Lot{ProductID: productID, Code: request.Code, Quantity: request.Quantity}
+	if request.Expiration != "" {
+		var err error
+		if lot.Expiration, err = domain.ParseDate(request.Expiration); err != nil {
+			return domain.Lot{}, dateError("expiration", err)
+		}
+	}
+	return lot, nil
+}
+
+type ReservationRequest struct {
+	Items []domain.QuoteItem `json:"items"`
+	// TTLSeconds is how long the reservation holds the stock, the default of
+	// the server when 0.
+	TTLSeconds int `json:"ttl_seconds" example:"900"`
+}
+
+func (request ReservationRequest) TTL() time.Duration {
+	return time.Duration(request.TTLSeconds) * time.Second
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/Andrea-Reyna/go-web/internal/alerts"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandlers struct {
+	Scheduler *scheduler.Scheduler
+}
+
+// @Summary List the background jobs
+// @Description Retrieves the status of every background job: its schedule, whether it is running, when it runs next and the result of its last run.
+// @Tags admin
+// @Produce json
+// @Param token header string true "Token"
+// @Success 200 {array} scheduler.Status "Successfully retrieved the jobs"
+// @Failure 401 {object} rest.Problem "invalid_token"
+// @Router /admin/jobs [get]
+func (handler JobHandlers) Jobs() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, handler.Scheduler.Jobs())
+	}
+}
+
+// @Summary Run a background job now
+// @Description Runs a job right away, out of its schedule, and returns the run once it finishes. A job does not run twice at the same time.
+// @Tags admin
+// @Produce json
+// @Param token header string true "Token"
+// @Param name path string true "Job name"
+// @Success 200 {object} scheduler.Run "Successfully ran the job"
+// @Failure 401 {object} rest.Problem "invalid_token"
+// @Failure 404 {object} rest.Problem "job_not_found"
+// @Failure 409 {object} rest.Problem "job_running"
+// @Failure 503 {object} rest.Problem "scheduler_not_running"
+// @Router /admin/jobs/{name}/run [post]
+func (handler JobHandlers) Run() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		run, err := handler.Scheduler.RunNow(ctx.Param("name"))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, run)
+	}
+}
+
+// UnpublishExpiredJob is the name of the job that unpublishes the expired
+// products.
+const UnpublishExpiredJob = "unpublish-expired"
+
+// unpublishExpired is the job that unpublishes the expired products, whose
+// last run lists the products it unpublished.
+func unpublishExpired(service products.Service) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		unpublished, err := service.UnpublishExpired()
+		if len(unpublished) > 0 {
+			log.Printf("unpublished %d expired products", len(unpublished))
+		}
+		return unpublished, err
+	}
+}
+
+// CheckAlertsJob is the name of the job that checks the alert rules on every
+// product.
+const CheckAlertsJob = "check-alerts"
+
+// checkAlerts is the job that checks the alert rules on every product, whose
+// last run lists the alerts it sent.
+func checkAlerts(alerter *alerts.Alerter, service products.Service) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		stored, err := service.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		return alerter.Check(ctx, stored), nil
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/alerts"
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createServerForTestJobHandler serves the jobs of a scheduler on a manual
+// clock, with a wine that expired the day before testNow and a cookie that
+// expires the day after.
+func createServerForTestJobHandler(t *testing.T) (*gin.Engine, products.Repository, *scheduler.ManualClock) {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	for _, product := range []domain.Product{
+		{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Expiration: domain.NewDate(2021, time.December, 31), Price: domain.NewMoney(17923, "ARS")},
+		{Name: "Cookie", Quantity: 5, CodeValue: "M7157", IsPublished: true, Expiration: domain.NewDate(2022, time.January, 2), Price: domain.NewMoney(27547, "ARS")},
+	} {
+		require.NoError(t, storage.Create(&product))
+	}
+
+	clock := scheduler.NewManualClock(testNow)
+	service := products.DefaultService{Storage: storage, Now: clock.Now}
+	jobs := &scheduler.Scheduler{Clock: clock}
+	schedule, err := scheduler.Parse("@daily")
+	require.NoError(t, err)
+	require.NoError(t, jobs.Add(scheduler.Job{Name: UnpublishExpiredJob, Schedule: schedule, Expression: "@daily", Run: unpublishExpired(service)}))
+	jobs.Start()
+	t.Cleanup(func() { jobs.Stop(context.Background()) })
+	handler := JobHandlers{Scheduler: jobs}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.GET("/admin/jobs", handler.Jobs())
+	server.POST("/admin/jobs/:name/run", handler.Run())
+	return server, storage, clock
+}
+
+func TestJobHandlers(t *testing.T) {
+	t.Run("should unpublish the expired products on schedule", func(t *testing.T) {
+		server, storage, clock := createServerForTestJobHandler(t)
+
+		require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+		clock.Advance(12 * time.Hour)
+		var statuses []scheduler.Status
+		require.Eventually(t, func() bool {
+			response := serve(server, http.MethodGet, "/admin/jobs", "")
+			return json.Unmarshal(response.Body.Bytes(), &statuses) == nil && statuses[0].Runs == 1
+		}, time.Second, time.Millisecond)
+
+		assert.Equal(t, UnpublishExpiredJob, statuses[0].Name)
+		assert.Equal(t, time.Date(2022, time.January, 3, 0, 0, 0, 0, time.UTC), statuses[0].NextRun)
+		require.NotNil(t, statuses[0].LastRun)
+		assert.Equal(t, []any{map[string]any{"id": 1.0, "name": "Wine", "code_value": "T65812", "expiration": "31/12/2021"}}, statuses[0].LastRun.Result)
+		wine, err := storage.FindById(1)
+		require.NoError(t, err)
+		assert.False(t, wine.IsPublished)
+		cookie, err := storage.FindById(2)
+		require.NoError(t, err)
+		assert.True(t, cookie.IsPublished)
+	})
+
+	t.Run("should run a job on demand", func(t *testing.T) {
+		server, _, _ := createServerForTestJobHandler(t)
+
+		response := serve(server, http.MethodPost, "/admin/jobs/unpublish-expired/run", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"result":[{"id":1,"name":"Wine"`)
+
+		response = serve(server, http.MethodPost, "/admin/jobs/unpublish-expired/run", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"result":[]`)
+
+		response = serve(server, http.MethodPost, "/admin/jobs/reindex/run", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "job_not_found", "job not found: reindex")
+	})
+}
+
+func TestCheckAlertsJob(t *testing.T) {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	rules, err := alerts.Parse([]byte(`[{name: low stock, kind: low_stock, threshold: 5}]`))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "alerts.log")
+	alerter := &alerts.Alerter{Rules: rules, Notifiers: []alerts.Notifier{&alerts.LogNotifier{Path: path}}, Now: func() time.Time { return testNow }}
+	service := products.DefaultService{Storage: storage, Now: func() time.Time { return testNow }, Changed: alerter.Changed}
+	logged := func() int {
+		alerter.Wait()
+		data, _ := os.ReadFile(path)
+		return strings.Count(string(data), "\n")
+	}
+
+	wine := domain.Product{Name: "Wine", Quantity: 10, CodeValue: "T65812", Expiration: domain.NewDate(2022, time.December, 31), Price: domain.NewMoney(17923, "ARS")}
+	require.NoError(t, service.Create(&wine))
+	assert.Equal(t, 0, logged())
+
+	t.Run("should alert on the writes of the service", func(t *testing.T) {
+		_, err := service.Patch(wine.ID, 0, func(product domain.Product) (domain.Product, error) {
+			product.Quantity = 4
+			return product, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, logged())
+	})
+
+	t.Run("should not alert again on schedule within the cooldown", func(t *testing.T) {
+		jobs := &scheduler.Scheduler{Clock: scheduler.NewManualClock(testNow)}
+		schedule, err := scheduler.Parse("@hourly")
+		require.NoError(t, err)
+		require.NoError(t, jobs.Add(scheduler.Job{Name: CheckAlertsJob, Schedule: schedule, Run: checkAlerts(alerter, service)}))
+		jobs.Start()
+		t.Cleanup(func() { jobs.Stop(context.Background()) })
+
+		run, err := jobs.RunNow(CheckAlertsJob)
+		require.NoError(t, err)
+		assert.Equal(t, []alerts.Alert{}, run.Result)
+		assert.Equal(t, 1, logged())
+	})
+}
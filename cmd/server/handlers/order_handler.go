@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/orders"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandlers struct {
+	Service orders.Service
+}
+
+// @Summary Create a cart
+// @Description Creates an open cart, empty or with some items. Items of the same product are added up.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param cart body CartRequest false "Items of the cart"
+// @Success 201 {object} domain.PricedCart "Successfully created the cart"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /carts [post]
+func (handler OrderHandlers) CreateCart() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request CartRequest
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.ShouldBindJSON(&request); err != nil {
+				problems.Abort(ctx, rest.BindingError(err))
+				return
+			}
+		}
+		cart, err := handler.Service.CreateCart(request.Items)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, cart)
+	}
+}
+
+// @Summary Get a cart
+// @Description Retrieves a cart priced at current prices with the consumer pricing rules. Items that could not be checked out right now are listed as unavailable in the quote.
+// @Tags orders
+// @Produce json
+// @Param id path string true "Cart ID"
+// @Success 200 {object} domain.PricedCart "Successfully retrieved the cart"
+// @Failure 404 {object} rest.Problem "cart_not_found"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /carts/{id} [get]
+func (handler OrderHandlers) FindCart() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		cart, err := handler.Service.FindCart(ctx.Param("id"))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, cart)
+	}
+}
+
+// @Summary Add an item to a cart
+// @Description Adds the quantity of a product to an open cart.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Cart ID"
+// @Param item body domain.QuoteItem true "Product and quantity"
+// @Success 200 {object} domain.PricedCart "Successfully added the item"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "cart_not_found or product_not_found"
+// @Failure 409 {object} rest.Problem "cart_checked_out"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /carts/{id}/items [post]
+func (handler OrderHandlers) AddItem() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var item domain.QuoteItem
+		if err := ctx.ShouldBindJSON(&item); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		cart, err := handler.Service.AddItem(ctx.Param("id"), item)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, cart)
+	}
+}
+
+// @Summary Remove an item from a cart
+// @Description Removes a product from an open cart, whatever its quantity.
+// @Tags orders
+// @Produce json
+// @Param id path string true "Cart ID"
+// @Param product_id path int true "Product ID"
+// @Success 200 {object} domain.PricedCart "Successfully removed the item"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid product_id"
+// @Failure 404 {object} rest.Problem "cart_not_found or cart_item_not_found"
+// @Failure 409 {object} rest.Problem "cart_checked_out"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /carts/{id}/items/{product_id} [delete]
+func (handler OrderHandlers) RemoveItem() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		productID, err := strconv.Atoi(ctx.Param("product_id"))
+		if err != nil {
+			problems.Abort(ctx, rest.InvalidField("product_id", "type", "must be a product ID"))
+			return
+		}
+		cart, err := handler.Service.RemoveItem(ctx.Param("id"), productID)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, cart)
+	}
+}
+
+// @Summary Check out a cart
+// @Description Turns an open cart into a pending order at current prices. The stock of every item is taken at once, or none when any item is unavailable. A cart is checked out only once.
+// @Tags orders
+// @Produce json
+// @Param id path string true "Cart ID"
+// @Success 201 {object} domain.Order "Successfully created the order"
+// @Failure 404 {object} rest.Problem "cart_not_found"
+// @Failure 409 {object} rest.Problem "cart_checked_out, empty_cart, items_unavailable or currency_mismatch"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /carts/{id}/checkout [post]
+func (handler OrderHandlers) Checkout() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		order, err := handler.Service.Checkout(ctx.Param("id"))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, order)
+	}
+}
+
+// @Summary List orders
+// @Description Retrieves the orders, newest first, optionally only those with a status or created between two days, both included.
+// @Tags orders
+// @Produce json
+// @Param token header string true "Token"
+// @Param status query string false "Status of the orders" Enums(pending, paid, shipped, cancelled)
+// @Param from query string false "First day, e.g. 2022-01-01"
+// @Param to query string false "Last day, e.g. 2022-01-31"
+// @Success 200 {array} domain.Order "Successfully retrieved the orders"
+// @Failure 400 {object} rest.Problem "validation_failed"
+// @Failure 401 {object} rest.Problem "invalid_token"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /orders [get]
+func (handler OrderHandlers) Orders() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		query := orders.OrderQuery{Status: domain.OrderStatus(ctx.Query("status"))}
+		for _, bound := range []struct {
+			name string
+			date *domain.Date
+		}{{"from", &query.From}, {"to", &query.To}} {
+			value := ctx.Query(bound.name)
+			if value == "" {
+				continue
+			}
+			date, err := domain.ParseDate(value)
+			if err != nil {
+				problems.Abort(ctx, dateError(bound.name, err))
+				return
+			}
+			*bound.date = date
+		}
+		found, err := handler.Service.Orders(query)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, found)
+	}
+}
+
+// @Summary Get an order
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} domain.Order "Successfully retrieved the order"
+// @Failure 404 {object} rest.Problem "order_not_found"
+// @Router /orders/{id} [get]
+func (handler OrderHandlers) FindOrder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		order, err := handler.Service.FindOrder(ctx.Param("id"))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, order)
+	}
+}
+
+// @Summary Change the status of an order
+// @Description Pending orders can be paid or cancelled and paid orders shipped or cancelled. The items of a cancelled order go back to the stock.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param status body OrderStatusRequest true "New status"
+// @Success 200 {object} domain.Order "Successfully changed the status"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "order_not_found"
+// @Failure 409 {object} rest.Problem "invalid_transition"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /orders/{id}/status [post]
+func (handler OrderHandlers) UpdateStatus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request OrderStatusRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		order, err := handler.Service.UpdateStatus(ctx.Param("id"), domain.OrderStatus(request.Status))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, order)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/orders"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createServerForTestOrderHandler serves a published product with 10 units
+// at 100.00, kept with the carts and orders apart from the other tests.
+func createServerForTestOrderHandler(t *testing.T) *gin.Engine {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	product := domain.Product{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(10000, "ARS")}
+	require.NoError(t, storage.Create(&product))
+
+	ledger, err := inventory.OpenLedger("")
+	require.NoError(t, err)
+	stock, err := inventory.NewRepository(storage, ledger)
+	require.NoError(t, err)
+	now := func() time.Time { return testNow }
+	stock.Now = now
+	inventoryService := inventory.DefaultService{Storage: stock}
+	orderStorage, err := orders.NewFileRepository("")
+	require.NoError(t, err)
+	handler := OrderHandlers{Service: orders.DefaultService{
+		Storage:   orderStorage,
+		Products:  products.DefaultService{Storage: stock, Now: now, Available: inventoryService.Available},
+		Inventory: inventoryService,
+		Now:       now,
+	}}
+	inventoryHandler := InventoryHandlers{Service: inventoryService}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.GET("/products/:id/stock", inventoryHandler.Stock())
+	carts := server.Group("carts")
+	carts.POST("", handler.CreateCart())
+	carts.GET("/:id", handler.FindCart())
+	carts.POST("/:id/items", handler.AddItem())
+	carts.DELETE("/:id/items/:product_id", handler.RemoveItem())
+	carts.POST("/:id/checkout", handler.Checkout())
+	group := server.Group("orders")
+	group.GET("", handler.Orders())
+	group.GET("/:id", handler.FindOrder())
+	group.POST("/:id/status", handler.UpdateStatus())
+	return server
+}
+
+func TestOrderHandlers_Checkout(t *testing.T) {
+	t.Run("should check out a cart into a pending order", func(t *testing.T) {
+		server := createServerForTestOrderHandler(t)
+
+		response := serve(server, http.MethodPost, "/carts", "")
+		assert.Equal(t, http.StatusCreated, response.Code)
+		var cart domain.PricedCart
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &cart))
+		assert.Equal(t, domain.CartOpen, cart.Status)
+
+		response = serve(server, http.MethodPost, "/carts/"+cart.ID+"/items", `{"id":1,"qty":3}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"items":[{"id":1,"qty":3}]`)
+		assert.Contains(t, response.Body.String(), `"total_price":363`)
+
+		response = serve(server, http.MethodPost, "/carts/"+cart.ID+"/checkout", "")
+		assert.Equal(t, http.StatusCreated, response.Code)
+		var order domain.Order
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &order))
+		assert.Equal(t, domain.OrderPending, order.Status)
+		assert.Equal(t, domain.NewMoney(36300, "ARS"), order.TotalPrice)
+
+		response = serve(server, http.MethodGet, "/products/1/stock", "")
+		assert.JSONEq(t, `{"product_id":1,"on_hand":7,"reserved":0,"available":7}`, response.Body.String())
+
+		response = serve(server, http.MethodPost, "/carts/"+cart.ID+"/checkout", "")
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "cart_checked_out", "cart already checked out")
+	})
+
+	t.Run("should not take any stock when an item is unavailable", func(t *testing.T) {
+		server := createServerForTestOrderHandler(t)
+
+		response := serve(server, http.MethodPost, "/carts", `{"items":[{"id":1,"qty":11}]}`)
+		var cart domain.PricedCart
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &cart))
+
+		response = serve(server, http.MethodPost, "/carts/"+cart.ID+"/checkout", "")
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "items_unavailable", "items unavailable: product 1 is short of stock")
+		response = serve(server, http.MethodGet, "/products/1/stock", "")
+		assert.JSONEq(t, `{"product_id":1,"on_hand":10,"reserved":0,"available":10}`, response.Body.String())
+	})
+
+	t.Run("should report unknown carts and products", func(t *testing.T) {
+		server := createServerForTestOrderHandler(t)
+
+		response := serve(server, http.MethodGet, "/carts/unknown", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "cart_not_found", "cart not found")
+
+		response = serve(server, http.MethodPost, "/carts", `{"items":[{"id":99,"qty":1}]}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestOrderHandlers_UpdateStatus(t *testing.T) {
+	t.Run("should follow the status transitions", func(t *testing.T) {
+		server := createServerForTestOrderHandler(t)
+		response := serve(server, http.MethodPost, "/carts", `{"items":[{"id":1,"qty":2}]}`)
+		var cart domain.PricedCart
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &cart))
+		response = serve(server, http.MethodPost, "/carts/"+cart.ID+"/checkout", "")
+		var order domain.Order
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &order))
+
+		response = serve(server, http.MethodPost, "/orders/"+order.ID+"/status", `{"status":"cancelled"}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		response = serve(server, http.MethodGet, "/products/1/stock", "")
+		assert.JSONEq(t, `{"product_id":1,"on_hand":10,"reserved":0,"available":10}`, response.Body.String())
+
+		response = serve(server, http.MethodPost, "/orders/"+order.ID+"/status", `{"status":"paid"}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "invalid_transition", "invalid order status transition: from cancelled to paid")
+
+		response = serve(server, http.MethodGet, "/orders?status=cancelled&from=2022-01-01", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		var found []domain.Order
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &found))
+		assert.Len(t, found, 1)
+	})
+
+	t.Run("should report unknown orders", func(t *testing.T) {
+		server := createServerForTestOrderHandler(t)
+
+		response := serve(server, http.MethodGet, "/orders/unknown", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "order_not_found", "order not found")
+	})
+}
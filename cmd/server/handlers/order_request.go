@@ -0,0 +1,11 @@
+package handlers
+
+import "github.com/Andrea-Reyna/go-web/internal/domain"
+
+type CartRequest struct {
+	Items []domain.QuoteItem `json:"items"`
+}
+
+type OrderStatusRequest struct {
+	Status string `json:"status" enums:"paid,shipped,cancelled" example:"paid"`
+}
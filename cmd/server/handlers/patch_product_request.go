@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/patch"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+// Media types accepted by PATCH /products/:id. Plain JSON bodies are read as
+// merge patches, which is what the endpoint accepted before.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+var (
+	errUnsupportedPatch = errors.New("unsupported patch media type")
+	errUnknownField     = errors.New("unknown field")
+	errImmutableField   = errors.New("field cannot be changed")
+)
+
+// immutableFields are assigned by the repository, or like the categories,
+// the variant fields and the deletion changed through their own endpoints.
+var immutableFields = []string{"id", "uid", "version", "categories", "parent_id", "attributes", "deleted_at", "deleted_by"}
+
+// productFields are the JSON names of the domain.Product fields, plus the
+// currency of its price.
+var productFields = func() map[string]bool {
+	fields := map[string]bool{"currency": true}
+	productType := reflect.TypeOf(domain.Product{})
+	for i := 0; i < productType.NumField(); i++ {
+		name := strings.Split(productType.Field(i).Tag.Get("json"), ",")[0]
+		fields[name] = true
+	}
+	return fields
+}()
+
+// patchProduct applies a merge patch or a JSON Patch body to the JSON form of
+// product. Fields removed by the patch, e.g. set to null in a merge patch, get
+// their zero value.
+func patchProduct(product domain.Product, mediaType string, body []byte) (domain.Product, error) {
+	document, err := json.Marshal(product)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	var patched []byte
+	switch mediaType {
+	case mergePatchType, "application/json":
+		// Removing a field drops it from the result, so unknown fields are
+		// looked for in the patch too.
+		var changes map[string]json.RawMessage
+		if json.Unmarshal(body, &changes) == nil {
+			for name := range changes {
+				if !productFields[name] {
+					return domain.Product{}, fmt.Errorf("%w %q", errUnknownField, name)
+				}
+			}
+		}
+		patched, err = patch.Merge(document, body)
+	case jsonPatchType:
+		patched, err = patch.Apply(document, body)
+	default:
+		return domain.Product{}, errUnsupportedPatch
+	}
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(document, &before); err != nil {
+		return domain.Product{}, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil || after == nil {
+		return domain.Product{}, fmt.Errorf("%w: the product must remain an object", patch.ErrInvalidPatch)
+	}
+	for name := range after {
+		if !productFields[name] {
+			return domain.Product{}, fmt.Errorf("%w %q", errUnknownField, name)
+		}
+	}
+	for _, name := range immutableFields {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			return domain.Product{}, fmt.Errorf("%w: %s", errImmutableField, name)
+		}
+	}
+
+	var result domain.Product
+	if err := json.Unmarshal(patched, &result); err != nil {
+		var violations validation.Errors
+		if errors.As(fieldError(err), &violations) {
+			return domain.Product{}, violations
+		}
+		return domain.Product{}, fmt.Errorf("%w: %s", patch.ErrInvalidPatch, err)
+	}
+	return result, nil
+}
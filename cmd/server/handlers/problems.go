@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Andrea-Reyna/go-web/cmd/server/middlewares"
+	"github.com/Andrea-Reyna/go-web/internal/categories"
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/orders"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/patch"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/Andrea-Reyna/go-web/pkg/scheduler"
+)
+
+// problems maps the errors of the products service to the problem+json
+// responses of the API. Codes are part of the API: clients match on them, so
+// they must not change once published.
+var problems = rest.NewProblems().
+	Register(products.ErrInvalidData, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_data"}).
+	Register(products.ErrProductInvalid, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_product"}).
+	Register(products.ErrInvalidPage, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_page"}).
+	Register(products.ErrInvalidSort, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_sort", Field: "sort"}).
+	Register(products.ErrInvalidCursor, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_cursor", Field: "cursor"}).
+	Register(products.ErrInvalidFilter, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_filter", Field: "filter"}).
+	Register(products.ErrInvalidRank, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_rank", Field: "rank"}).
+	Register(patch.ErrInvalidPatch, rest.ProblemType{Status: http.StatusBadRequest, Code: "invalid_patch"}).
+	Register(errUnknownField, rest.ProblemType{Status: http.StatusBadRequest, Code: "unknown_field"}).
+	Register(errImmutableField, rest.ProblemType{Status: http.StatusBadRequest, Code: "immutable_field"}).
+	Register(middlewares.ErrInvalidToken, rest.ProblemType{Status: http.StatusUnauthorized, Code: "invalid_token"}).
+	Register(products.ErrProductNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "product_not_found", Title: "Product Not Found"}).
+	Register(products.ErrVariantNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "variant_not_found"}).
+	Register(products.ErrProductNotInTrash, rest.ProblemType{Status: http.StatusNotFound, Code: "product_not_in_trash"}).
+	Register(products.ErrRevisionNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "revision_not_found"}).
+	Register(scheduler.ErrJobNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "job_not_found"}).
+	Register(categories.ErrCategoryNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "category_not_found"}).
+	Register(inventory.ErrLotNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "lot_not_found", Field: "lot"}).
+	Register(inventory.ErrReservationNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "reservation_not_found"}).
+	Register(orders.ErrCartNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "cart_not_found"}).
+	Register(orders.ErrCartLineNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "cart_item_not_found"}).
+	Register(orders.ErrOrderNotFound, rest.ProblemType{Status: http.StatusNotFound, Code: "order_not_found"}).
+	Register(products.ErrProductAlreadyExists, rest.ProblemType{Status: http.StatusConflict, Code: "product_already_exists", Title: "Product Already Exists", Field: "code_value", Detail: "another product has this code value"}).
+	Register(products.ErrNestedVariant, rest.ProblemType{Status: http.StatusConflict, Code: "nested_variant"}).
+	Register(products.ErrCodeValueInTrash, rest.ProblemType{Status: http.StatusConflict, Code: "code_value_in_trash", Field: "code_value", Detail: "a product in the trash has this code value, it must be restored or purged first"}).
+	Register(products.ErrParentInTrash, rest.ProblemType{Status: http.StatusConflict, Code: "parent_in_trash", Detail: "the parent of the variant must be restored first"}).
+	Register(products.ErrProductHasVariants, rest.ProblemType{Status: http.StatusConflict, Code: "product_has_variants", Detail: "the variants of the product must be deleted first"}).
+	Register(categories.ErrCategoryAlreadyExists, rest.ProblemType{Status: http.StatusConflict, Code: "category_already_exists", Field: "slug", Detail: "another category under the same parent has this slug"}).
+	Register(categories.ErrCategoryNotEmpty, rest.ProblemType{Status: http.StatusConflict, Code: "category_not_empty"}).
+	Register(categories.ErrInvalidParent, rest.ProblemType{Status: http.StatusConflict, Code: "invalid_parent", Field: "parent_id"}).
+	Register(domain.ErrCurrencyMismatch, rest.ProblemType{Status: http.StatusConflict, Code: "currency_mismatch", Detail: "the products are priced in different currencies"}).
+	Register(inventory.ErrLotAlreadyExists, rest.ProblemType{Status: http.StatusConflict, Code: "lot_already_exists", Field: "code", Detail: "the product has another lot with this code"}).
+	Register(inventory.ErrInsufficientStock, rest.ProblemType{Status: http.StatusConflict, Code: "insufficient_stock"}).
+	Register(inventory.ErrReservationClosed, rest.ProblemType{Status: http.StatusConflict, Code: "reservation_closed"}).
+	Register(orders.ErrCartCheckedOut, rest.ProblemType{Status: http.StatusConflict, Code: "cart_checked_out"}).
+	Register(orders.ErrEmptyCart, rest.ProblemType{Status: http.StatusConflict, Code: "empty_cart"}).
+	Register(orders.ErrItemsUnavailable, rest.ProblemType{Status: http.StatusConflict, Code: "items_unavailable"}).
+	Register(orders.ErrInvalidTransition, rest.ProblemType{Status: http.StatusConflict, Code: "invalid_transition"}).
+	Register(scheduler.ErrJobRunning, rest.ProblemType{Status: http.StatusConflict, Code: "job_running"}).
+	Register(patch.ErrTestFailed, rest.ProblemType{Status: http.StatusConflict, Code: "patch_test_failed"}).
+	Register(products.ErrVersionConflict, rest.ProblemType{Status: http.StatusPreconditionFailed, Code: "version_conflict", Detail: "the product was modified since it was read"}).
+	Register(categories.ErrVersionConflict, rest.ProblemType{Status: http.StatusPreconditionFailed, Code: "version_conflict", Detail: "the category was modified since it was read"}).
+	Register(errUnsupportedPatch, rest.ProblemType{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type"}).
+	Register(products.ErrSearchUnavailable, rest.ProblemType{Status: http.StatusServiceUnavailable, Code: "search_unavailable"}).
+	Register(scheduler.ErrNotRunning, rest.ProblemType{Status: http.StatusServiceUnavailable, Code: "scheduler_not_running"})
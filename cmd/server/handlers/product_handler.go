@@ -1,19 +1,96 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/Andrea-Reyna/go-web/cmd/server/middlewares"
+	"github.com/Andrea-Reyna/go-web/internal/categories"
+	"github.com/Andrea-Reyna/go-web/internal/domain"
 	"github.com/Andrea-Reyna/go-web/internal/products"
 	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
 type ProductHandlers struct {
 	Service products.Service
+	// Categories resolve the category filter of GetAll.
+	Categories categories.Service
+}
+
+func (handler ProductHandlers) productID(ctx *gin.Context) (int, error) {
+	return productID(ctx, handler.Service)
+}
+
+// by returns the service making its writes as the actor of the request, for
+// the history of the products.
+func (handler ProductHandlers) by(ctx *gin.Context) products.Service {
+	return handler.Service.By(products.Author{Actor: middlewares.Actor(ctx), RequestID: middlewares.RequestID(ctx)})
+}
+
+// productID parses the :id path parameter, which is either a numeric ID or
+// the UID of a product created under the uuid or ulid schemes. Unknown UIDs
+// fail with ErrProductNotFound.
+func productID(ctx *gin.Context, service products.Service) (int, error) {
+	param := ctx.Param("id")
+	if id, err := strconv.Atoi(param); err == nil {
+		return id, nil
+	}
+	if !products.IsUID(param) {
+		return 0, rest.InvalidField("id", "invalid", "must be a product ID or UID")
+	}
+	product, err := service.FindByUID(param)
+	if err != nil {
+		return 0, err
+	}
+	return product.ID, nil
+}
+
+// ifMatchVersion returns the version a write is conditioned on by If-Match,
+// or 0 for an unconditional write when the header is missing or "*", whose
+// writes of missing resources ifMatchFound fails. Only a single entity tag
+// can be checked atomically by the repository, so a list of several tags is
+// reported as not ok, like a tag that is not ours.
+func ifMatchVersion(ctx *gin.Context) (int, bool) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	return rest.ParseETag(header)
+}
+
+// ifMatchFound reports the failed write of a missing resource, err being
+// one of notFound, as conflict when it was conditioned on If-Match: *, which
+// only matches a resource that exists.
+func ifMatchFound(ctx *gin.Context, err error, conflict error, notFound ...error) error {
+	if strings.TrimSpace(ctx.GetHeader("If-Match")) != "*" {
+		return err
+	}
+	for _, target := range notFound {
+		if errors.Is(err, target) {
+			return conflict
+		}
+	}
+	return err
+}
+
+// intQuery parses the integer query parameter name, fallback when absent.
+func intQuery(ctx *gin.Context, name string, fallback int) (int, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, rest.InvalidField(name, "type", "must be an integer")
+	}
+	return number, nil
 }
 
 // @Summary Create a new product
@@ -23,51 +100,74 @@ type ProductHandlers struct {
 // @Produce json
 // @Param product body CreateProductRequest true "Product Information"
 // @Success 201 {object} CreateProductResponse "Successfully created product"
-// @Failure 400 {object} rest.ErrorResponse "BadRequest: invalid data"
-// @Failure 409 {object} rest.ErrorResponse "Conflict: cannot create the given product, it already exists or error date format"
-// @Failure 500 {object} rest.ErrorResponse "InternalServerError: an internal error has occurred"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 409 {object} rest.Problem "product_already_exists"
+// @Failure 500 {object} rest.Problem "internal_error"
 // @Router /products [post]
 func (handler ProductHandlers) Create() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var request CreateProductRequest
 
 		if err := ctx.ShouldBindJSON(&request); err != nil {
-			ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-				Status:  400,
-				Code:    "BadRequest",
-				Message: "invalid data",
-			})
+			problems.Abort(ctx, rest.BindingError(err))
 			return
 		}
-		productToCreate := request.ToDomain()
-		err := handler.Service.Create(&productToCreate)
+		productToCreate, err := request.ToDomain()
 		if err != nil {
-			switch err {
-			case products.ErrProductAlreadyExists:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "canot create the given product it already exist",
-				})
-			case products.ErrFormateDate:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "error date format",
-				})
-			default:
-				ctx.JSON(http.StatusInternalServerError, rest.ErrorResponse{
-					Status:  500,
-					Code:    "InternalServerError",
-					Message: "an internal error has ocurred",
-				})
-			}
+			problems.Abort(ctx, err)
 			return
 		}
+		err = handler.by(ctx).Create(&productToCreate)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.Header("ETag", rest.ETag(productToCreate.Version))
 		ctx.JSON(http.StatusCreated, productToCreate)
 	}
 }
 
+// @Summary Import products
+// @Description This method creates every product of a JSON list, or none of them when any is invalid. The violations of all the products are reported at once, with fields prefixed by the index of the product, e.g. [2].price; code values must be unique within the list too.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param products body []CreateProductRequest true "Products to create"
+// @Success 201 {array} CreateProductResponse "Successfully created products"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/import [post]
+func (handler ProductHandlers) Import() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var requests []CreateProductRequest
+
+		if err := ctx.ShouldBindJSON(&requests); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		productsToCreate := make([]domain.Product, len(requests))
+		var violations validation.Errors
+		for i, request := range requests {
+			product, err := request.ToDomain()
+			var productViolations validation.Errors
+			if errors.As(err, &productViolations) {
+				violations = append(violations, productViolations.Prefix(fmt.Sprintf("[%d].", i))...)
+			}
+			productsToCreate[i] = product
+		}
+		if len(violations) > 0 {
+			problems.Abort(ctx, violations)
+			return
+		}
+		created, err := handler.by(ctx).Import(productsToCreate)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, created)
+	}
+}
+
 // @Summary Update a product
 // @Description This method update a product entry in the system by taking a JSON input with the required product information and Id. It returns an error if there is an issue with the input data, if the product code already exists, or if there is an internal server error.
 // @Tags products
@@ -75,308 +175,392 @@ func (handler ProductHandlers) Create() gin.HandlerFunc {
 // @Produce json
 // @Param product body CreateProductRequest true "Product Information"
 // @Param id path int true "Product ID"
+// @Param If-Match header string false "ETag of the product read, the update fails if it was modified since"
 // @Success 201 {object} CreateProductResponse "Successfully updated product"
-// @Failure 400 {object} rest.ErrorResponse "BadRequest: invalid data"
-// @Failure 409 {object} rest.ErrorResponse "Conflict: cannot update product, code already exists or error date format"
-// @Failure 500 {object} rest.ErrorResponse "InternalServerError: an internal error has occurred"
+// @Header 201 {string} ETag "Version of the updated product"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 409 {object} rest.Problem "product_already_exists"
+// @Failure 412 {object} rest.Problem "version_conflict: the product was modified since it was read, or is missing with If-Match: *"
+// @Failure 500 {object} rest.Problem "internal_error"
 // @Router /products/:id [put]
 func (handler ProductHandlers) Update() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		id, err := strconv.Atoi(ctx.Param("id"))
+		id, err := handler.productID(ctx)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid data"})
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
 			return
 		}
 		var request CreateProductRequest
 
 		if err := ctx.ShouldBindJSON(&request); err != nil {
-			ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-				Status:  400,
-				Code:    "BadRequest",
-				Message: "bad request",
-			})
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		productToCreate, err := request.ToDomain()
+		if err != nil {
+			problems.Abort(ctx, err)
 			return
 		}
-		productToCreate := request.ToDomain()
 		productToCreate.ID = id
+		version, ok := ifMatchVersion(ctx)
+		if !ok {
+			problems.Abort(ctx, products.ErrVersionConflict)
+			return
+		}
+		productToCreate.Version = version
 
-		err = handler.Service.Update(&productToCreate)
+		err = handler.by(ctx).Update(&productToCreate)
 		if err != nil {
-			switch err {
-			case products.ErrProductAlreadyExists:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "code already exist",
-				})
-			case products.ErrFormateDate:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "error date format",
-				})
-			case products.ErrProductNotFound:
-				ctx.JSON(http.StatusNotFound, rest.ErrorResponse{
-					Status:  404,
-					Code:    "NotFound",
-					Message: "product not found",
-				})
-			default:
-				ctx.JSON(http.StatusInternalServerError, rest.ErrorResponse{
-					Status:  500,
-					Code:    "InternalServerError",
-					Message: "an internal error has ocurred",
-				})
-			}
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
 			return
 		}
+		ctx.Header("ETag", rest.ETag(productToCreate.Version))
 		ctx.JSON(http.StatusOK, productToCreate)
 	}
 }
 
 // @Summary Update partial a product
-// @Description This method update a product entry in the system by taking a JSON input with the required product information and Id. It returns an error if there is an issue with the input data, if the product code already exists, or if there is an internal server error.
+// @Description This method changes some fields of a product. The body is a JSON Merge Patch (RFC 7396, also accepted as application/json) where null resets a field, or a JSON Patch (RFC 6902) whose test operations must hold for the patch to apply. The patched product is validated like on update; id, uid, version, categories, parent_id and attributes cannot be changed and unknown fields are rejected.
 // @Tags products
-// @Accept json
+// @Accept json,application/merge-patch+json,application/json-patch+json
 // @Produce json
-// @Param product body CreateProductRequest true "Product Information"
+// @Param product body CreateProductRequest true "Merge patch with the fields to change, or list of JSON Patch operations"
 // @Param id path int true "Product ID"
-// @Success 201 {object} CreateProductResponse "Successfully updated product"
-// @Failure 400 {object} rest.ErrorResponse "BadRequest: invalid data"
-// @Failure 409 {object} rest.ErrorResponse "Conflict: cannot update product, code already exists or error date format"
-// @Failure 500 {object} rest.ErrorResponse "InternalServerError: an internal error has occurred"
+// @Param If-Match header string false "ETag of the product read, the update fails if it was modified since"
+// @Success 200 {object} CreateProductResponse "Successfully updated product"
+// @Header 200 {string} ETag "Version of the updated product"
+// @Failure 400 {object} rest.Problem "validation_failed, invalid_patch, unknown_field or immutable_field"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 409 {object} rest.Problem "patch_test_failed or product_already_exists"
+// @Failure 412 {object} rest.Problem "version_conflict: the product was modified since it was read, or is missing with If-Match: *"
+// @Failure 415 {object} rest.Problem "unsupported_media_type: the body is not a merge patch nor a JSON Patch"
+// @Failure 500 {object} rest.Problem "internal_error"
 // @Router /products/:id [patch]
 func (handler ProductHandlers) UpdatePartial() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		id, err := strconv.Atoi(ctx.Param("id"))
+		id, err := handler.productID(ctx)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-				Status:  400,
-				Code:    "BadRequest",
-				Message: "invalid data",
-			})
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
 			return
 		}
-
-		prod, err := handler.Service.FindById(id)
-		if err != nil {
-			ctx.JSON(http.StatusNotFound, rest.ErrorResponse{
-				Status:  404,
-				Code:    "NotFound",
-				Message: "product not found",
-			})
+		version, ok := ifMatchVersion(ctx)
+		if !ok {
+			problems.Abort(ctx, products.ErrVersionConflict)
+			return
 		}
 
-		if err := json.NewDecoder(ctx.Request.Body).Decode(&prod); err != nil {
-			ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-				Status:  400,
-				Code:    "BadRequest",
-				Message: "invalid data",
-			})
+		mediaType := "application/json"
+		if header := ctx.GetHeader("Content-Type"); header != "" {
+			if mediaType, _, err = mime.ParseMediaType(header); err != nil {
+				mediaType = header
+			}
+		}
+		body, err := ctx.GetRawData()
+		if err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
 			return
 		}
-		prod.ID = id
 
-
-		err = handler.Service.Update(&prod)
+		product, err := handler.by(ctx).Patch(id, version, func(product domain.Product) (domain.Product, error) {
+			return patchProduct(product, mediaType, body)
+		})
 		if err != nil {
-			switch err {
-			case products.ErrProductAlreadyExists:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "code already exist",
-				})
-			case products.ErrFormateDate:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "error date format",
-				})
-			case products.ErrProductNotFound:
-				ctx.JSON(http.StatusNotFound, rest.ErrorResponse{
-					Status:  404,
-					Code:    "NotFound",
-					Message: "product not found",
-				})
-			default:
-				ctx.JSON(http.StatusInternalServerError, rest.ErrorResponse{
-					Status:  500,
-					Code:    "InternalServerError",
-					Message: "an internal error has ocurred",
-				})
+			if err == errUnsupportedPatch {
+				ctx.Header("Accept-Patch", mergePatchType+", "+jsonPatchType)
 			}
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
 			return
 		}
-		ctx.JSON(http.StatusOK, prod)
+		ctx.Header("ETag", rest.ETag(product.Version))
+		ctx.JSON(http.StatusOK, product)
 	}
-
 }
 
 func (handler ProductHandlers) UpdateName() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		id, err := strconv.Atoi(ctx.Param("id"))
+		id, err := handler.productID(ctx)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-				Status:  400,
-				Code:    "BadRequest",
-				Message: "invalid data",
-			})
+			problems.Abort(ctx, err)
 			return
 		}
 		name := ctx.Query("name")
 
-		product, err := handler.Service.UpdateName(id, name)
+		product, err := handler.by(ctx).UpdateName(id, name)
 		if err != nil {
-			switch err {
-			case products.ErrProductAlreadyExists:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "code already exist",
-				})
-			case products.ErrFormateDate:
-				ctx.JSON(http.StatusConflict, rest.ErrorResponse{
-					Status:  409,
-					Code:    "Conflict",
-					Message: "error date format",
-				})
-			case products.ErrProductNotFound:
-				ctx.JSON(http.StatusNotFound, rest.ErrorResponse{
-					Status:  404,
-					Code:    "NotFound",
-					Message: "product not found",
-				})
-			default:
-				ctx.JSON(http.StatusInternalServerError, rest.ErrorResponse{
-					Status:  500,
-					Code:    "InternalServerError",
-					Message: "an internal error has ocurred",
-				})
-			}
+			problems.Abort(ctx, err)
 			return
 		}
+		ctx.Header("ETag", rest.ETag(product.Version))
 		ctx.JSON(http.StatusOK, product)
 	}
 }
 
 // @Summary Get All products
-// @Description This method get a list with all products.
+// @Description This method get a page of products. Without limit every product is returned. Pages continue either by offset or by the opaque next_cursor of the previous page, which is stable while products are created or deleted.
 // @Tags products
 // @Accept json
 // @Produce json
-// @Success 200 {array} domain.Product "Successfully list of products"
+// @Param limit query int false "Page size, up to 1000"
+// @Param offset query int false "Products to skip, not allowed with cursor"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "Comma-separated fields, prefixed with - for descending, e.g. price,-name"
+// @Param category query string false "Path of a category, only its products are listed, e.g. beverages/wine"
+// @Param include_descendants query bool false "With category, list the products of its subcategories too"
+// @Success 200 {object} rest.SuccessfulResponse "Successfully list of products"
+// @Header 200 {string} Link "first, prev and next pages"
+// @Failure 400 {object} rest.Problem "validation_failed, invalid_page, invalid_sort or invalid_cursor"
 // @Router /products [get]
 func (handler ProductHandlers) GetAll() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		products, err := handler.Service.GetAll()
+		query, err := parsePageQuery(ctx)
+		if err == nil {
+			query.Sort, err = products.ParseSort(ctx.Query("sort"))
+		}
+		if err == nil {
+			query.Categories, err = handler.categoryFilter(ctx)
+		}
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+
+		page, err := handler.Service.GetPage(query)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, rest.ErrorResponse{
-				Status:  500,
-				Code:    "InternalServerError",
-				Message: "an internal error has ocurred",
-			})
+			problems.Abort(ctx, err)
+			return
+		}
+
+		if link := pageLinks(ctx.Request.URL, query, page); link != "" {
+			ctx.Header("Link", link)
+		}
+		ctx.JSON(http.StatusOK, rest.SuccessfulResponse{
+			Data: page.Products,
+			Pagination: &rest.Pagination{
+				Total:      page.Total,
+				Limit:      query.Limit,
+				Offset:     query.Offset,
+				NextCursor: page.NextCursor,
+			},
+		})
+	}
+}
+
+func parsePageQuery(ctx *gin.Context) (products.PageQuery, error) {
+	var query products.PageQuery
+	var err error
+	if query.Limit, err = intQuery(ctx, "limit", 0); err != nil {
+		return query, err
+	}
+	if query.Offset, err = intQuery(ctx, "offset", 0); err != nil {
+		return query, err
+	}
+	query.Cursor = ctx.Query("cursor")
+	return query, nil
+}
+
+// categoryFilter resolves the category query parameter to the IDs of the
+// category and, with include_descendants, of its descendants.
+func (handler ProductHandlers) categoryFilter(ctx *gin.Context) ([]int, error) {
+	path := ctx.Query("category")
+	descendants := false
+	if value := ctx.Query("include_descendants"); value != "" {
+		var err error
+		if descendants, err = strconv.ParseBool(value); err != nil {
+			return nil, rest.InvalidField("include_descendants", "type", "must be a boolean")
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+	ids, err := handler.Categories.Resolve(path, descendants)
+	if errors.Is(err, categories.ErrCategoryNotFound) {
+		return nil, rest.InvalidField("category", "category", "must be the path of a category")
+	}
+	return ids, err
+}
+
+// pageLinks builds the Link header of a page. Offset pages link by offset and
+// cursor pages by cursor, so a client keeps the mode it started with.
+func pageLinks(base *url.URL, query products.PageQuery, page products.Page) string {
+	if query.Limit == 0 {
+		return ""
+	}
+	links := map[string]*url.URL{
+		"first": rest.WithQuery(base, map[string]string{"offset": "", "cursor": ""}),
+	}
+	if page.NextCursor != "" {
+		if query.Cursor != "" || query.Offset == 0 {
+			links["next"] = rest.WithQuery(base, map[string]string{"offset": "", "cursor": page.NextCursor})
+		} else {
+			links["next"] = rest.WithQuery(base, map[string]string{"offset": strconv.Itoa(query.Offset + query.Limit)})
 		}
-		ctx.JSON(http.StatusOK, products)
 	}
+	if query.Offset > 0 {
+		previous := query.Offset - query.Limit
+		if previous < 0 {
+			previous = 0
+		}
+		links["prev"] = rest.WithQuery(base, map[string]string{"offset": strconv.Itoa(previous)})
+	}
+	return rest.Link([]string{"first", "prev", "next"}, links)
 }
 
 // @Summary Get product by ID
-// @Description Retrieves a specific product by its ID
+// @Description Retrieves a specific product by its ID, or with as_of the product as its history had it at that time, without an ETag.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param id path int true "Product ID"
+// @Param as_of query string false "RFC 3339 time, e.g. 2022-01-01T12:00:00Z"
+// @Param token header string false "Token, required with as_of"
+// @Param If-None-Match header string false "ETag of a cached copy of the product"
 // @Success 200 {object} domain.Product "Successfully retrieved product"
-// @Failure 400 {object} map[string]string "Invalid data"
-// @Failure 404 {object} map[string]string "Product not found"
+// @Header 200 {string} ETag "Version of the product"
+// @Success 304 "The cached copy is still current"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id or as_of"
+// @Failure 401 {object} rest.Problem "invalid_token: as_of without the token"
+// @Failure 404 {object} rest.Problem "product_not_found, or revision_not_found when the history has no revision of the product by as_of"
 // @Router /products/{id} [get]
 func (handler ProductHandlers) FindById() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		id, err := strconv.Atoi(ctx.Param("id"))
+		id, err := handler.productID(ctx)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid data"})
+			problems.Abort(ctx, err)
+			return
+		}
+		if value, ok := ctx.GetQuery("as_of"); ok {
+			handler.findByIdAsOf(ctx, id, value)
 			return
 		}
 		product, err := handler.Service.FindById(id)
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			problems.Abort(ctx, err)
+			return
+		}
+		handler.Service.Viewed(product.ID)
+		etag := rest.ETag(product.Version)
+		ctx.Header("ETag", etag)
+		if rest.MatchETag(ctx.GetHeader("If-None-Match"), etag, true) {
+			ctx.Status(http.StatusNotModified)
 			return
 		}
 		ctx.JSON(http.StatusOK, product)
 	}
 }
 
-// @Summary Search products by price
-// @Description Retrieves a list of products with a price greater than the specified value
+// @Summary Search products
+// @Description Retrieves the products whose name matches q, ranked by a BM25 score. Matching ignores case and accents, accepts prefixes and tolerates typos in words of four or more letters.
+// @Description Without q, retrieves the products matching a filter expression, e.g. price>=100 AND is_published=true AND name~"wine". Fields: id, name, quantity, code_value, is_published, expiration (YYYY-MM-DD), price. Operators: = != < <= > >= ~ (contains), IN (...), BETWEEN ... AND ..., combined with AND, OR, NOT and parentheses. priceGt is kept as a shorthand for price>priceGt.
 // @Tags products
 // @Accept  json
 // @Produce  json
-// @Param   priceGt     query    float64     true    "Minimum product price"
-// @Success 200 {array} domain.Product "Successfully retrieved list of products"
-// @Failure 400 {object} rest.ErrorResponse "Invalid data"
-// @Failure 404 {object} map[string]string "Price must be greater than 0"
+// @Param   q           query    string      false   "Words of the product name"
+// @Param   limit       query    int         false   "Maximum results for q, 20 by default"
+// @Param   filter      query    string      false   "Filter expression"
+// @Param   priceGt     query    float64     false   "Minimum product price, greater than 0"
+// @Success 200 {array} products.ScoredProduct "Successfully retrieved list of products, with score when searching by q"
+// @Failure 400 {object} rest.Problem "validation_failed, invalid_data or invalid_filter with the reason and position"
 // @Router /products/search [get]
 func (handler ProductHandlers) Search() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		priceGt, err := strconv.ParseFloat(ctx.Query("priceGt"), 64)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-				Status:  400,
-				Code:    "BadRequest",
-				Message: "invalid data",
-			})
+		if query := ctx.Query("q"); query != "" {
+			handler.searchText(ctx, query)
 			return
 		}
-		filterProducts, err := handler.Service.Search(priceGt)
+
+		expression := ctx.Query("filter")
+		if expression == "" {
+			priceGt, err := strconv.ParseFloat(ctx.Query("priceGt"), 64)
+			if err != nil {
+				problems.Abort(ctx, rest.InvalidField("priceGt", "type", "must be a number"))
+				return
+			}
+			if priceGt <= 0 {
+				problems.Abort(ctx, rest.InvalidField("priceGt", "min", "must be greater than 0"))
+				return
+			}
+			expression = "price > " + strconv.FormatFloat(priceGt, 'f', -1, 64)
+		}
+
+		filterProducts, err := handler.Service.Search(expression)
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "el precio ingresado debe ser mayor a 0"})
+			problems.Abort(ctx, err)
 			return
 		}
 		ctx.JSON(http.StatusOK, filterProducts)
 	}
 }
 
+func (handler ProductHandlers) searchText(ctx *gin.Context, query string) {
+	limit, err := intQuery(ctx, "limit", 20)
+	if err != nil {
+		problems.Abort(ctx, err)
+		return
+	}
+
+	found, err := handler.Service.SearchText(query, limit)
+	if err != nil {
+		problems.Abort(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, found)
+}
+
+// @Summary Suggest products while typing
+// @Description Retrieves the products whose name, a word of their name or code value starts with prefix, ignoring case and accents. Ranked by popularity (reads since the server started) or by price, highest first.
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Param   prefix      query    string      true    "Beginning of a name, word or code value"
+// @Param   limit       query    int         false   "Maximum suggestions, 10 by default"
+// @Param   rank        query    string      false   "popularity (default) or price"
+// @Success 200 {array} products.Suggestion "Successfully retrieved suggestions"
+// @Failure 400 {object} rest.Problem "validation_failed, invalid_data or invalid_rank"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/suggest [get]
+func (handler ProductHandlers) Suggest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, err := intQuery(ctx, "limit", 10)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+
+		suggestions, err := handler.Service.Suggest(ctx.Query("prefix"), limit, ctx.DefaultQuery("rank", products.RankPopularity))
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, suggestions)
+	}
+}
+
 // @Summary Delete product by ID
-// @Description Deletes a specific product by its ID
+// @Description Moves a specific product to the trash, recording when and by whom it was deleted. It can be restored until it is purged, and its code value stays taken meanwhile. A product with variants cannot be deleted before its variants.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param id path int true "Product ID"
+// @Param X-Actor header string false "Who deletes the product, unknown by default"
 // @Success 204 "Successfully deleted product"
-// @Failure 400 {object} rest.ErrorResponse "Invalid data"
-// @Failure 404 {object} rest.ErrorResponse "Product not found"
-// @Failure 500 {object} rest.ErrorResponse "An internal error has occurred"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 409 {object} rest.Problem "product_has_variants"
+// @Failure 500 {object} rest.Problem "internal_error"
 // @Router /products/{id} [delete]
 func (handler ProductHandlers) Delete() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		id, err := strconv.Atoi(ctx.Param("id"))
+		id, err := handler.productID(ctx)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-				Status:  400,
-				Code:    "BadRequest",
-				Message: "invalid data",
-			})
+			problems.Abort(ctx, err)
 			return
 		}
 
-		err = handler.Service.Delete(id)
+		err = handler.by(ctx).Delete(id)
 		if err != nil {
-			switch err {
-			case products.ErrProductNotFound:
-				ctx.JSON(http.StatusNotFound, rest.ErrorResponse{
-					Status:  404,
-					Code:    "NotFound",
-					Message: "product not found",
-				})
-			default:
-				ctx.JSON(http.StatusInternalServerError, rest.ErrorResponse{
-					Status:  500,
-					Code:    "InternalServerError",
-					Message: "an internal error has ocurred",
-				})
-			}
+			problems.Abort(ctx, err)
 			return
 		}
 		ctx.JSON(http.StatusNoContent, nil)
@@ -384,15 +568,15 @@ func (handler ProductHandlers) Delete() gin.HandlerFunc {
 }
 
 // @Summary Get consumer prices for a list of product IDs
-// @Description Retrieves a list of products with prices for the specified product IDs
+// @Description Prices the published products with the specified IDs with the pricing rules, an ID listed several times counting several units. Every line lists the rules that applied to it.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param   list     query    string     true    "Comma-separated list of product IDs"
-// @Success 200 {array} domain.Product "Successfully retrieved list of consumer products"
-// @Failure 400 {object} rest.ErrorResponse "Invalid data"
-// @Failure 500 {object} rest.ErrorResponse "An internal error has occurred"
-// @Router /products/consumer-price [get]
+// @Success 200 {object} domain.ProductsConsumer "Successfully priced the products"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid list"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/consumer_price [get]
 func (handler ProductHandlers) ConsumerPrice() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		params := ctx.Query("list")
@@ -400,11 +584,7 @@ func (handler ProductHandlers) ConsumerPrice() gin.HandlerFunc {
 		for _, numStr := range strings.Split(params, ",") {
 			num, err := strconv.Atoi(numStr)
 			if err != nil {
-				ctx.JSON(http.StatusBadRequest, rest.ErrorResponse{
-					Status:  400,
-					Code:    "BadRequest",
-					Message: "invalid data",
-				})
+				problems.Abort(ctx, rest.InvalidField("list", "type", "must be a comma-separated list of product IDs"))
 				return
 			}
 			nums = append(nums, num)
@@ -412,13 +592,42 @@ func (handler ProductHandlers) ConsumerPrice() gin.HandlerFunc {
 
 		consumerProducts, err := handler.Service.ConsumerPrice(nums)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, rest.ErrorResponse{
-				Status:  500,
-				Code:    "InternalServerError",
-				Message: "an internal error has ocurred",
-			})
+			problems.Abort(ctx, err)
 			return
 		}
+		viewed := make([]int, 0, len(consumerProducts.Products))
+		for _, product := range consumerProducts.Products {
+			viewed = append(viewed, product.ID)
+		}
+		handler.Service.Viewed(viewed...)
 		ctx.JSON(http.StatusOK, consumerProducts)
 	}
 }
+
+// @Summary Quote products
+// @Description Prices the quantities of a list of products with the pricing rules, adding up the items of the same product. Products that are not found, not published or without enough stock are left out of the price and listed as unavailable, with the stock available.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param items body []domain.QuoteItem true "Products and quantities to quote"
+// @Success 200 {object} domain.Quote "Successfully quoted the products"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 409 {object} rest.Problem "currency_mismatch"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/quote [post]
+func (handler ProductHandlers) Quote() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var items []domain.QuoteItem
+
+		if err := ctx.ShouldBindJSON(&items); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		quote, err := handler.Service.Quote(items)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, quote)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProductsHandler_Concurrency hammers the handlers from many goroutines.
+// Run it with -race: it fails if the repository is mutated without locking.
+func TestProductsHandler_Concurrency(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "products.json"))
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, os.WriteFile(file, data, 0644))
+	t.Setenv("FILE", file)
+
+	server := createServerForTestPrductsHandler()
+
+	const workers = 8
+	const iterations = 25
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				product := newProduct
+				product.CodeValue = fmt.Sprintf("RACE-%d-%d", worker, i)
+				body, _ := json.Marshal(product)
+
+				requests := []*http.Request{
+					httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body)),
+					httptest.NewRequest(http.MethodPut, fmt.Sprintf("/products/%d", 10+i), bytes.NewReader(body)),
+					httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%d", 100+worker*iterations+i), nil),
+					httptest.NewRequest(http.MethodGet, "/products/search?priceGt=500", nil),
+					httptest.NewRequest(http.MethodGet, "/products", nil),
+				}
+				for _, request := range requests {
+					response := httptest.NewRecorder()
+					server.ServeHTTP(response, request)
+					assert.Less(t, response.Code, http.StatusInternalServerError, "%s %s", request.Method, request.URL)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/products", nil))
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var body struct {
+		Data []CreateProductResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	codes := map[string]bool{}
+	for _, product := range body.Data {
+		assert.False(t, codes[product.CodeValue], "duplicated code value %s", product.CodeValue)
+		codes[product.CodeValue] = true
+	}
+}
@@ -3,12 +3,18 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -49,7 +55,7 @@ var newProduct = CreateProductRequest{
 	CodeValue:   "M71599",
 	IsPublished: false,
 	Expiration:  "28/01/2022",
-	Price:       275.47,
+	Price:       "275.47",
 }
 var sameCode = CreateProductRequest{
 	Name:        "New product by test",
@@ -57,7 +63,7 @@ var sameCode = CreateProductRequest{
 	CodeValue:   "M4315",
 	IsPublished: false,
 	Expiration:  "28/01/2022",
-	Price:       275.47,
+	Price:       "275.47",
 }
 
 var updateProduct = CreateProductRequest{
@@ -66,19 +72,59 @@ var updateProduct = CreateProductRequest{
 	CodeValue:   "S82254D",
 	IsPublished: false,
 	Expiration:  "01/01/2022",
-	Price:       555.47,
+	Price:       "555.47",
 }
 
-func createServerForTestPrductsHandler() *gin.Engine {
-	os.Setenv("FILE", "/Users/areyna/Documents/Bootcamp/Modulo 4 - Go Web/Activities/products.json")
+// TestMain works on a copy of testdata/products.json so the tests, which
+// persist changes between servers, never modify the fixture itself.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "products")
+	if err != nil {
+		panic(err)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "products.json"))
+	if err != nil {
+		panic(err)
+	}
+	file := filepath.Join(dir, "products.json")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		panic(err)
+	}
+	os.Setenv("FILE", file)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// assertProblem checks the code and detail of a problem+json response.
+func assertProblem(t *testing.T, response *httptest.ResponseRecorder, code, detail string) {
+	t.Helper()
+	var problem rest.Problem
+	assert.Equal(t, "application/problem+json", response.Header().Get("Content-Type"))
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+	assert.Equal(t, code, problem.Code)
+	assert.Equal(t, detail, problem.Detail)
+	assert.Equal(t, response.Code, problem.Status)
+}
 
-	repository, err := products.NewSliceBasedRepository()
+// testNow is the date the test server runs on, so that fixtures expiring in
+// 2022 can still be published.
+var testNow = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func createServerForTestPrductsHandler() *gin.Engine {
+	storage, err := products.NewSliceBasedRepository()
+	if err != nil {
+		panic(err)
+	}
+	repository, err := products.NewIndexedRepository(storage)
 	if err != nil {
 		panic(err)
 	}
 
 	service := &products.DefaultService{
 		Storage: repository,
+		Now:     func() time.Time { return testNow },
 	}
 
 	handler := &ProductHandlers{
@@ -91,10 +137,16 @@ func createServerForTestPrductsHandler() *gin.Engine {
 
 	group := server.Group("products")
 	group.POST("", handler.Create())
+	group.POST("/import", handler.Import())
 	group.GET("", handler.GetAll())
 	group.GET("/:id", handler.FindById())
+	group.GET("/search", handler.Search())
+	group.GET("/suggest", handler.Suggest())
 	group.PUT("/:id", handler.Update())
+	group.PATCH("/:id", handler.UpdatePartial())
 	group.DELETE("/:id", handler.Delete())
+	group.GET("/consumer_price", handler.ConsumerPrice())
+	group.POST("/quote", handler.Quote())
 	return server
 }
 
@@ -131,6 +183,7 @@ func TestProductsHandler_GetProductByID(t *testing.T) {
 				"Content-Type": []string{
 					"application/json; charset=utf-8",
 				},
+				"Etag": []string{`"1"`},
 			}
 
 			expectedResponse = `{
@@ -140,7 +193,9 @@ func TestProductsHandler_GetProductByID(t *testing.T) {
 					"code_value": "M7157",
 					"is_published": false,
 					"expiration": "28/01/2022",
-					"price": 275.47
+					"price": 275.47,
+					"currency": "ARS",
+					"version": 1
 			}`
 		)
 
@@ -162,10 +217,17 @@ func TestProductsHandler_GetProductByID(t *testing.T) {
 			expectedStatusCode = http.StatusNotFound
 			expectedHeaders    = http.Header{
 				"Content-Type": []string{
-					"application/json; charset=utf-8",
+					"application/problem+json",
 				},
 			}
-			expectedResponse = `{"error":"product not found"}`
+			expectedResponse = `{
+				"type": "/problems/product_not_found",
+				"title": "Product Not Found",
+				"status": 404,
+				"detail": "product not found",
+				"instance": "/products/777",
+				"code": "product_not_found"
+			}`
 		)
 
 		request := httptest.NewRequest(http.MethodGet, "/products/"+productIdToSearch, nil)
@@ -186,10 +248,18 @@ func TestProductsHandler_GetProductByID(t *testing.T) {
 			expectedStatusCode = http.StatusBadRequest
 			expectedHeaders    = http.Header{
 				"Content-Type": []string{
-					"application/json; charset=utf-8",
+					"application/problem+json",
 				},
 			}
-			expectedResponse = `{"error":"invalid data"}`
+			expectedResponse = `{
+				"type": "/problems/validation_failed",
+				"title": "Validation Failed",
+				"status": 400,
+				"detail": "invalid data: id must be a product ID or UID",
+				"instance": "/products/qwer",
+				"code": "validation_failed",
+				"errors": [{"field": "id", "code": "invalid", "message": "must be a product ID or UID"}]
+			}`
 		)
 
 		request := httptest.NewRequest(http.MethodGet, "/products/"+productIdToSearch, nil)
@@ -218,6 +288,7 @@ func TestProductsHandler_Create(t *testing.T) {
 				"Content-Type": []string{
 					"application/json; charset=utf-8",
 				},
+				"Etag": []string{`"1"`},
 			}
 			expectedResponse = `{
 				"id":501,
@@ -226,7 +297,9 @@ func TestProductsHandler_Create(t *testing.T) {
 				"code_value": "M71599",
 				"is_published": false,
 				"expiration": "28/01/2022",
-				"price": 275.47
+				"price": 275.47,
+				"currency": "ARS",
+				"version": 1
 			}`
 		)
 		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer([]byte(string(newProductJson))))
@@ -251,10 +324,18 @@ func TestProductsHandler_Create(t *testing.T) {
 			expectedStatusCode = http.StatusConflict
 			expectedHeaders    = http.Header{
 				"Content-Type": []string{
-					"application/json; charset=utf-8",
+					"application/problem+json",
 				},
 			}
-			expectedResponse = `{"code":"Conflict", "message":"canot create the given product it already exist", "status":409}`
+			expectedResponse = `{
+				"type": "/problems/product_already_exists",
+				"title": "Product Already Exists",
+				"status": 409,
+				"detail": "another product has this code value",
+				"instance": "/products",
+				"code": "product_already_exists",
+				"errors": [{"field": "code_value", "code": "product_already_exists", "message": "another product has this code value"}]
+			}`
 		)
 		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer([]byte(string(newProductJson))))
 		response := httptest.NewRecorder()
@@ -269,6 +350,299 @@ func TestProductsHandler_Create(t *testing.T) {
 	})
 }
 
+func TestProductsHandler_CreateValidation(t *testing.T) {
+	t.Run("should list the invalid fields", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{"quantity": 1, "code_value": "V1", "expiration": "01/01/2022", "price": 1}`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var problem rest.Problem
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, "validation_failed", problem.Code)
+		assert.Equal(t, []rest.Violation{{Field: "name", Code: "required", Message: "is required"}}, problem.Errors)
+	})
+
+	t.Run("should accept zero quantities", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{"name": "Empty", "quantity": 0, "code_value": "ZERO1", "expiration": "01/01/2022", "price": 1}`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusCreated, response.Code)
+	})
+
+	t.Run("should report every violation at once", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{
+			"name": "Expired",
+			"quantity": -3,
+			"code_value": "bad code",
+			"is_published": true,
+			"expiration": "31/12/2021",
+			"price": 0
+		}`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var problem rest.Problem
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, []rest.Violation{
+			{Field: "quantity", Code: "min", Message: "must be at least 0"},
+			{Field: "code_value", Code: "pattern", Message: "must be uppercase letters and digits, optionally separated by hyphens"},
+			{Field: "expiration", Code: "past", Message: "must not be in the past"},
+			{Field: "price", Code: "positive", Message: "must be greater than 0"},
+		}, problem.Errors)
+	})
+
+	t.Run("should reject ambiguous dates", func(t *testing.T) {
+		cases := map[string]string{
+			"03/04/22":            "ambiguous_date",
+			"04-03-2022":          "ambiguous_date",
+			"2022-04-03T10:00:00": "ambiguous_date",
+			"31/02/2022":          "date",
+			"tomorrow":            "date",
+		}
+		server := createServerForTestPrductsHandler()
+		for expiration, code := range cases {
+			body, _ := json.Marshal(CreateProductRequest{Name: "Dated", Quantity: 1, CodeValue: "DATE1", Expiration: expiration, Price: "1"})
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body)))
+
+			var problem rest.Problem
+			assert.Equal(t, http.StatusBadRequest, response.Code, expiration)
+			assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+			if assert.Len(t, problem.Errors, 1, expiration) {
+				assert.Equal(t, "expiration", problem.Errors[0].Field, expiration)
+				assert.Equal(t, code, problem.Errors[0].Code, expiration)
+			}
+		}
+	})
+
+	t.Run("should accept ISO-8601 and RFC 3339 dates", func(t *testing.T) {
+		server := createServerForTestPrductsHandler()
+		for i, expiration := range []string{"2022-01-28", "2022-01-28T23:30:00-03:00"} {
+			body, _ := json.Marshal(CreateProductRequest{Name: "Dated", Quantity: 1, CodeValue: fmt.Sprintf("ISO%d", i), Expiration: expiration, Price: "1"})
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body)))
+
+			var created CreateProductResponse
+			assert.Equal(t, http.StatusCreated, response.Code, expiration)
+			assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+			assert.Equal(t, "28/01/2022", created.Expiration, expiration)
+		}
+	})
+
+	t.Run("should reject prices finer than their currency", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{"name": "Yen", "code_value": "JPY1", "expiration": "01/01/2022", "price": "10.5", "currency": "JPY"}`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var problem rest.Problem
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, []rest.Violation{{Field: "price", Code: "amount", Message: "must be a decimal number with at most the decimal places of its currency"}}, problem.Errors)
+	})
+
+	t.Run("should report fields of the wrong type", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{"name": "x", "quantity": "many"}`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var problem rest.Problem
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, []rest.Violation{{Field: "quantity", Code: "type", Message: "must be an integer"}}, problem.Errors)
+	})
+
+	t.Run("should reject a body that is not JSON", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{"name":`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assertProblem(t, response, "malformed_body", "malformed request body: unexpected EOF")
+	})
+}
+
+func TestProductsHandler_Import(t *testing.T) {
+	t.Run("should create every product", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products/import", bytes.NewBufferString(`[
+			{"name": "First import", "quantity": 1, "code_value": "IMP1", "expiration": "01/01/2022", "price": 1},
+			{"name": "Second import", "quantity": 0, "code_value": "IMP2", "expiration": "01/01/2022", "price": 2}
+		]`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var created []CreateProductResponse
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+		assert.Len(t, created, 2)
+		assert.NotZero(t, created[0].ID)
+		assert.Equal(t, "IMP2", created[1].CodeValue)
+	})
+
+	t.Run("should create none when a product is invalid", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/products/import", bytes.NewBufferString(`[
+			{"name": "Valid import", "quantity": 1, "code_value": "IMP3", "expiration": "01/01/2022", "price": 1},
+			{"name": "", "quantity": 1, "code_value": "IMP3", "expiration": "01/01/2022", "price": 1},
+			{"name": "Existing", "quantity": 1, "code_value": "S82254D", "expiration": "2022-01-01", "price": 1}
+		]`))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var problem rest.Problem
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, []rest.Violation{
+			{Field: "[1].name", Code: "required", Message: "is required"},
+			{Field: "[1].code_value", Code: "unique", Message: "must be unique"},
+			{Field: "[2].code_value", Code: "unique", Message: "must be unique"},
+		}, problem.Errors)
+
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/products/search?filter="+url.QueryEscape(`code_value="IMP3"`), nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NotContains(t, response.Body.String(), "IMP3")
+	})
+}
+
+func TestProductsHandler_ConsumerPrice(t *testing.T) {
+	t.Run("should round the total price with taxes to cents", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products/consumer_price?list=2", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var consumer struct {
+			TotalPrice json.RawMessage `json:"total_price"`
+			Currency   string          `json:"currency"`
+		}
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &consumer))
+		assert.Equal(t, "426.88", string(consumer.TotalPrice))
+		assert.Equal(t, "ARS", consumer.Currency)
+	})
+
+	t.Run("should explain the rules applied to every line", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products/consumer_price?list=2,2", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var consumer struct {
+			Lines []struct {
+				ProductID   int             `json:"product_id"`
+				Quantity    int             `json:"quantity"`
+				Subtotal    json.RawMessage `json:"subtotal"`
+				Adjustments []struct {
+					Rule    string          `json:"rule"`
+					Kind    string          `json:"kind"`
+					Percent string          `json:"percent"`
+					Amount  json.RawMessage `json:"amount"`
+				} `json:"adjustments"`
+			} `json:"lines"`
+			TotalPrice json.RawMessage `json:"total_price"`
+		}
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &consumer))
+		if !assert.Len(t, consumer.Lines, 1) || !assert.Len(t, consumer.Lines[0].Adjustments, 1) {
+			return
+		}
+		assert.Equal(t, 2, consumer.Lines[0].Quantity)
+		assert.Equal(t, "705.58", string(consumer.Lines[0].Subtotal))
+		assert.Equal(t, "markup", consumer.Lines[0].Adjustments[0].Kind)
+		assert.Equal(t, "21", consumer.Lines[0].Adjustments[0].Percent)
+		assert.Equal(t, "148.17", string(consumer.Lines[0].Adjustments[0].Amount))
+		assert.Equal(t, "853.75", string(consumer.TotalPrice))
+	})
+}
+
+func TestProductsHandler_Quote(t *testing.T) {
+	t.Run("should price the available items and report the rest", func(t *testing.T) {
+		body := `[{"id":1,"qty":2},{"id":2,"qty":1},{"id":1,"qty":1},{"id":3,"qty":1},{"id":999,"qty":1},{"id":7,"qty":500}]`
+		request := httptest.NewRequest(http.MethodPost, "/products/quote", bytes.NewBufferString(body))
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		expectedResponse := `{
+			"lines": [
+				{"product_id":1,"name":"Oil - Margarine","quantity":3,"unit_price":71.42,"subtotal":214.26,
+				 "adjustments":[{"rule":"up to 10 items","kind":"markup","percent":"21","amount":44.99}],"total":259.25},
+				{"product_id":2,"name":"Pineapple - Canned, Rings MOD","quantity":1,"unit_price":352.79,"subtotal":352.79,
+				 "adjustments":[{"rule":"up to 10 items","kind":"markup","percent":"21","amount":74.09}],"total":426.88}
+			],
+			"unavailable": [
+				{"id":3,"qty":1,"available":0,"reason":"unpublished"},
+				{"id":999,"qty":1,"available":0,"reason":"not_found"},
+				{"id":7,"qty":500,"available":165,"reason":"insufficient_stock"}
+			],
+			"subtotal": 567.05,
+			"surcharges": 119.08,
+			"total_price": 686.13,
+			"currency": "ARS"
+		}`
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, expectedResponse, response.Body.String())
+	})
+
+	t.Run("should validate the items", func(t *testing.T) {
+		cases := map[string][]rest.Violation{
+			`[]`: {{Field: "items", Code: "required", Message: "must list at least one item"}},
+			`[{"id":1,"qty":1},{"id":0,"qty":-2}]`: {
+				{Field: "[1].id", Code: "positive", Message: "must be greater than 0"},
+				{Field: "[1].qty", Code: "positive", Message: "must be greater than 0"},
+			},
+		}
+		for body, violations := range cases {
+			request := httptest.NewRequest(http.MethodPost, "/products/quote", bytes.NewBufferString(body))
+			response := httptest.NewRecorder()
+
+			server := createServerForTestPrductsHandler()
+
+			server.ServeHTTP(response, request)
+
+			var problem rest.Problem
+			assert.Equal(t, http.StatusBadRequest, response.Code, body)
+			assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+			assert.Equal(t, "validation_failed", problem.Code, body)
+			assert.Equal(t, violations, problem.Errors, body)
+		}
+	})
+}
+
 func TestProductsHandler_Update(t *testing.T) {
 	t.Run("should update product", func(t *testing.T) {
 		newProductJson, err := json.Marshal(updateProduct)
@@ -283,6 +657,7 @@ func TestProductsHandler_Update(t *testing.T) {
 				"Content-Type": []string{
 					"application/json; charset=utf-8",
 				},
+				"Etag": []string{`"2"`},
 			}
 			expectedResponse = `{
 				"id":1,
@@ -291,7 +666,9 @@ func TestProductsHandler_Update(t *testing.T) {
 				"code_value": "S82254D",
 				"is_published": false,
 				"expiration": "01/01/2022",
-				"price": 555.47
+				"price": 555.47,
+				"currency": "ARS",
+				"version": 2
 			}`
 		)
 		request := httptest.NewRequest(http.MethodPut, "/products/"+productIdToUpdate, bytes.NewBuffer([]byte(string(newProductJson))))
@@ -317,10 +694,18 @@ func TestProductsHandler_Update(t *testing.T) {
 			expectedStatusCode = http.StatusConflict
 			expectedHeaders    = http.Header{
 				"Content-Type": []string{
-					"application/json; charset=utf-8",
+					"application/problem+json",
 				},
 			}
-			expectedResponse = `{"code":"Conflict", "message":"code already exist", "status":409}`
+			expectedResponse = `{
+				"type": "/problems/product_already_exists",
+				"title": "Product Already Exists",
+				"status": 409,
+				"detail": "another product has this code value",
+				"instance": "/products/1",
+				"code": "product_already_exists",
+				"errors": [{"field": "code_value", "code": "product_already_exists", "message": "another product has this code value"}]
+			}`
 		)
 		request := httptest.NewRequest(http.MethodPut, "/products/"+productIdToUpdate, bytes.NewBuffer([]byte(string(newProductJson))))
 		response := httptest.NewRecorder()
@@ -363,10 +748,17 @@ func TestProductsHandler_Delete(t *testing.T) {
 			expectedStatusCode = http.StatusNotFound
 			expectedHeaders    = http.Header{
 				"Content-Type": []string{
-					"application/json; charset=utf-8",
+					"application/problem+json",
 				},
 			}
-			expectedResponse = `{"code":"NotFound", "message":"product not found", "status":404}`
+			expectedResponse = `{
+				"type": "/problems/product_not_found",
+				"title": "Product Not Found",
+				"status": 404,
+				"detail": "product not found",
+				"instance": "/products/1",
+				"code": "product_not_found"
+			}`
 		)
 		request := httptest.NewRequest(http.MethodDelete, "/products/"+productIdToDelete, nil)
 		response := httptest.NewRecorder()
@@ -380,3 +772,407 @@ func TestProductsHandler_Delete(t *testing.T) {
 		assert.JSONEq(t, expectedResponse, response.Body.String())
 	})
 }
+
+func TestProductsHandler_GetAllPaged(t *testing.T) {
+	type pageBody struct {
+		Data       []CreateProductResponse `json:"data"`
+		Total      int                     `json:"total"`
+		NextCursor string                  `json:"next_cursor"`
+	}
+
+	t.Run("should walk every product by cursor in sort order", func(t *testing.T) {
+		server := createServerForTestPrductsHandler()
+
+		var seen []CreateProductResponse
+		var total int
+		url := "/products?limit=100&sort=-price,name"
+		for url != "" {
+			request := httptest.NewRequest(http.MethodGet, url, nil)
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+			assert.Equal(t, http.StatusOK, response.Code)
+
+			var body pageBody
+			assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+			assert.LessOrEqual(t, len(body.Data), 100)
+			seen = append(seen, body.Data...)
+			total = body.Total
+
+			url = ""
+			if body.NextCursor != "" {
+				assert.Contains(t, response.Header().Get("Link"), `rel="next"`)
+				url = "/products?limit=100&sort=-price,name&cursor=" + body.NextCursor
+			}
+		}
+
+		assert.Len(t, seen, total)
+		for i := 1; i < len(seen); i++ {
+			assert.GreaterOrEqual(t, seen[i-1].Price, seen[i].Price)
+		}
+	})
+
+	t.Run("should page by offset", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products?limit=2&offset=2", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var body pageBody
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		assert.Len(t, body.Data, 2)
+		assert.Equal(t, 4, body.Data[0].ID)
+		assert.Contains(t, response.Header().Get("Link"), `offset=4>; rel="next"`)
+		assert.Contains(t, response.Header().Get("Link"), `offset=0>; rel="prev"`)
+	})
+
+	t.Run("should return an error for an unknown sort field", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products?sort=color", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.JSONEq(t, `{
+			"type": "/problems/invalid_sort",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid sort",
+			"instance": "/products?sort=color",
+			"code": "invalid_sort",
+			"errors": [{"field": "sort", "code": "invalid_sort", "message": "invalid sort"}]
+		}`, response.Body.String())
+	})
+}
+
+func TestProductsHandler_Search(t *testing.T) {
+	t.Run("should return the products matching the filter", func(t *testing.T) {
+		query := url.Values{"filter": {`price>=100 AND name~"oakridge merlot"`}}
+		request := httptest.NewRequest(http.MethodGet, "/products/search?"+query.Encode(), nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var found []CreateProductResponse
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &found))
+		assert.Len(t, found, 1)
+		assert.Equal(t, "T65812", found[0].CodeValue)
+	})
+
+	t.Run("should return an error for a price that is not positive", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products/search?priceGt=0", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var problem rest.Problem
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, []rest.Violation{{Field: "priceGt", Code: "min", Message: "must be greater than 0"}}, problem.Errors)
+	})
+
+	t.Run("should return an error for an invalid filter", func(t *testing.T) {
+		query := url.Values{"filter": {`price >= "cheap"`}}
+		request := httptest.NewRequest(http.MethodGet, "/products/search?"+query.Encode(), nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		var problem rest.Problem
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, "invalid_filter", problem.Code)
+		assert.Equal(t, []rest.Violation{{
+			Field:   "filter",
+			Code:    "invalid_filter",
+			Message: `invalid filter: at position 9: field "price" expects a number value, got "cheap"`,
+		}}, problem.Errors)
+	})
+}
+
+func TestProductsHandler_SearchText(t *testing.T) {
+	t.Run("should rank products by name", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products/search?q=oatmel+cookie&limit=3", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var found []struct {
+			ID    int     `json:"id"`
+			Name  string  `json:"name"`
+			Score float64 `json:"score"`
+		}
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &found))
+		assert.Len(t, found, 3)
+		assert.Equal(t, "Cookie - Oatmeal", found[0].Name)
+		assert.Greater(t, found[0].Score, found[1].Score)
+	})
+}
+
+func TestProductsHandler_Suggest(t *testing.T) {
+	t.Run("should suggest products by name prefix ranked by price", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products/suggest?prefix=wine&rank=price&limit=5", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		var suggestions []products.Suggestion
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &suggestions))
+		assert.Len(t, suggestions, 5)
+		for i, suggestion := range suggestions {
+			assert.Contains(t, suggestion.Name, "Wine")
+			if i > 0 {
+				assert.GreaterOrEqual(t, suggestions[i-1].Price.Amount, suggestion.Price.Amount)
+			}
+		}
+	})
+
+	t.Run("should suggest products by code value", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products/suggest?prefix=M4637", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"id":2, "name":"Pineapple - Canned, Rings MOD", "code_value":"M4637", "price":352.79, "currency":"ARS", "popularity":0}]`, response.Body.String())
+	})
+
+	t.Run("should rank by the reads of the clients only", func(t *testing.T) {
+		server := createServerForTestPrductsHandler()
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products/2", nil))
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/products/quote", bytes.NewBufferString(`[{"id":2,"qty":1}]`)))
+
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/products/suggest?prefix=M4637", nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"popularity":1`)
+	})
+
+	t.Run("should return an error for an unknown rank", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/products/suggest?prefix=wine&rank=color", nil)
+		response := httptest.NewRecorder()
+
+		server := createServerForTestPrductsHandler()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.JSONEq(t, `{
+			"type": "/problems/invalid_rank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid rank",
+			"instance": "/products/suggest?prefix=wine&rank=color",
+			"code": "invalid_rank",
+			"errors": [{"field": "rank", "code": "invalid_rank", "message": "invalid rank"}]
+		}`, response.Body.String())
+	})
+}
+
+func TestProductsHandler_ConditionalRequests(t *testing.T) {
+	server := createServerForTestPrductsHandler()
+	body, err := json.Marshal(CreateProductRequest{
+		Name:       "Conditional by test",
+		Quantity:   10,
+		CodeValue:  "ETAG1",
+		Expiration: "01/01/2022",
+		Price:      "10",
+	})
+	assert.NoError(t, err)
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, response.Code)
+	var created CreateProductResponse
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+	path := "/products/" + strconv.Itoa(created.ID)
+
+	t.Run("should return 304 while the cached copy is current", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set("If-None-Match", `"7", W/"1"`)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusNotModified, response.Code)
+		assert.Equal(t, `"1"`, response.Header().Get("ETag"))
+		assert.Empty(t, response.Body.String())
+	})
+
+	t.Run("should update when If-Match is the current version", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPut, path, bytes.NewReader(body))
+		request.Header.Set("If-Match", `"1"`)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"2"`, response.Header().Get("ETag"))
+	})
+
+	t.Run("should return 412 when the product was modified", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPut, path, bytes.NewReader(body))
+		request.Header.Set("If-Match", `"1"`)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+		assert.JSONEq(t, `{
+			"type": "/problems/version_conflict",
+			"title": "Precondition Failed",
+			"status": 412,
+			"detail": "the product was modified since it was read",
+			"instance": "`+path+`",
+			"code": "version_conflict"
+		}`, response.Body.String())
+	})
+
+	t.Run("should return 412 when If-Match is * and the product is missing", func(t *testing.T) {
+		missing := []byte(`{"name":"Missing","quantity":1,"code_value":"ETAG404","expiration":"01/01/2030","price":10}`)
+		for _, method := range []string{http.MethodPut, http.MethodPatch} {
+			request := httptest.NewRequest(method, "/products/999999", bytes.NewReader(missing))
+			request.Header.Set("If-Match", "*")
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			assert.Equal(t, http.StatusPreconditionFailed, response.Code, method)
+		}
+	})
+
+	t.Run("should return the product when the cached copy is stale", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set("If-None-Match", `"1"`)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"2"`, response.Header().Get("ETag"))
+	})
+}
+
+func TestProductsHandler_UpdatePartial(t *testing.T) {
+	server := createServerForTestPrductsHandler()
+	body, err := json.Marshal(CreateProductRequest{
+		Name:        "Patched by test",
+		Quantity:    10,
+		CodeValue:   "PATCH1",
+		IsPublished: true,
+		Expiration:  "01/01/2022",
+		Price:       "10",
+	})
+	assert.NoError(t, err)
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, response.Code)
+	var created CreateProductResponse
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+	path := "/products/" + strconv.Itoa(created.ID)
+
+	patchProduct := func(path, contentType, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+		request.Header.Set("Content-Type", contentType)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response
+	}
+
+	t.Run("should apply a merge patch", func(t *testing.T) {
+		response := patchProduct(path, "application/merge-patch+json", `{"price": 12.5, "is_published": null}`)
+
+		var patched CreateProductResponse
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"2"`, response.Header().Get("ETag"))
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &patched))
+		assert.Equal(t, 12.5, patched.Price)
+		assert.False(t, patched.IsPublished)
+		assert.Equal(t, "Patched by test", patched.Name)
+	})
+
+	t.Run("should apply a JSON patch when its tests hold", func(t *testing.T) {
+		response := patchProduct(path, "application/json-patch+json", `[
+			{"op": "test", "path": "/price", "value": 12.5},
+			{"op": "replace", "path": "/quantity", "value": 3}
+		]`)
+
+		var patched CreateProductResponse
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &patched))
+		assert.Equal(t, 3, patched.Quantity)
+	})
+
+	t.Run("should not apply a JSON patch when a test fails", func(t *testing.T) {
+		response := patchProduct(path, "application/json-patch+json", `[
+			{"op": "replace", "path": "/quantity", "value": 4},
+			{"op": "test", "path": "/price", "value": 10}
+		]`)
+
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "patch_test_failed", "patch test failed: operation 1")
+	})
+
+	t.Run("should reject unknown and immutable fields", func(t *testing.T) {
+		response := patchProduct(path, "application/merge-patch+json", `{"color": "red"}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assertProblem(t, response, "unknown_field", `unknown field "color"`)
+		response = patchProduct(path, "application/merge-patch+json", `{"color": null}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assertProblem(t, response, "unknown_field", `unknown field "color"`)
+
+		response = patchProduct(path, "application/json-patch+json", `[{"op": "replace", "path": "/id", "value": 1}]`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assertProblem(t, response, "immutable_field", "field cannot be changed: id")
+
+		response = patchProduct(path, "application/merge-patch+json", `{"categories": [1]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assertProblem(t, response, "immutable_field", "field cannot be changed: categories")
+	})
+
+	t.Run("should validate the patched product", func(t *testing.T) {
+		response := patchProduct(path, "application/merge-patch+json", `{"expiration": null, "quantity": -1}`)
+
+		var problem rest.Problem
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, []rest.Violation{
+			{Field: "quantity", Code: "min", Message: "must be at least 0"},
+			{Field: "expiration", Code: "required", Message: "is required"},
+		}, problem.Errors)
+	})
+
+	t.Run("should return an error if the product is not found", func(t *testing.T) {
+		response := patchProduct("/products/777777", "application/merge-patch+json", `{"price": 1}`)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "product_not_found", "product not found")
+	})
+
+	t.Run("should return an error for other media types", func(t *testing.T) {
+		response := patchProduct(path, "text/plain", `price=1`)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, response.Code)
+		assert.Equal(t, "application/merge-patch+json, application/json-patch+json", response.Header().Get("Accept-Patch"))
+	})
+}
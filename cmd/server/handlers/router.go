@@ -1,47 +1,444 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/Andrea-Reyna/go-web/cmd/server/middlewares"
+	"github.com/Andrea-Reyna/go-web/internal/alerts"
+	"github.com/Andrea-Reyna/go-web/internal/categories"
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/orders"
+	"github.com/Andrea-Reyna/go-web/internal/pricing"
 	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/scheduler"
+	"github.com/Andrea-Reyna/go-web/pkg/store"
 	"github.com/gin-gonic/gin"
 )
 
 type Router struct {
 	Engine *gin.Engine
+	// Scheduler runs the background jobs, started by Setup.
+	Scheduler *scheduler.Scheduler
 }
 
+// shutdownTimeout bounds how long the server waits for the requests and the
+// jobs in progress when it is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+// defaultTrashRetention is how long deleted products stay in the trash
+// unless TRASH_RETENTION says otherwise.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
 func (router *Router) Setup() {
 	router.Engine.Use(gin.Recovery())
 	router.Engine.Use(gin.Logger())
+	router.Engine.Use(middlewares.AssignRequestID)
 	//router.Engine.Use(middlewares.Logger)
 
 	router.SetProductsRoutes()
-	router.Engine.Run()
+	router.Scheduler.Start()
+
+	// The server listens on PORT, 8080 by default, until it receives an
+	// interrupt or a SIGTERM, and then stops once the requests and the jobs
+	// in progress finish.
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	server := &http.Server{Addr: ":" + port, Handler: router.Engine}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("error serving: %v", err)
+		}
+	}()
+	<-ctx.Done()
+
+	shutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdown); err != nil {
+		log.Printf("error stopping the server: %v", err)
+	}
+	if err := router.Scheduler.Stop(shutdown); err != nil {
+		log.Printf("error stopping the jobs: %v", err)
+	}
 }
 
 func (router *Router) SetProductsRoutes() {
+	// DATE_FORMAT is the format dates are written in: dmy (DD/MM/YYYY,
+	// default), iso (YYYY-MM-DD) or rfc3339. Every format is read on input.
+	layout, err := domain.ParseDateLayout(os.Getenv("DATE_FORMAT"))
+	if err != nil {
+		panic("error configuring dates: " + err.Error())
+	}
+	if err := domain.SetDateLayout(layout); err != nil {
+		panic("error configuring dates: " + err.Error())
+	}
+	// CURRENCY is the currency of prices given without one (ARS by default),
+	// MONEY_FORMAT writes amounts as a number (default) or a string and
+	// ROUNDING rounds computed amounts half_up (default) or half_even.
+	if currency := os.Getenv("CURRENCY"); currency != "" {
+		if err := domain.SetDefaultCurrency(currency); err != nil {
+			panic("error configuring prices: " + err.Error())
+		}
+	}
+	encoding, err := domain.ParseMoneyEncoding(os.Getenv("MONEY_FORMAT"))
+	if err != nil {
+		panic("error configuring prices: " + err.Error())
+	}
+	domain.SetMoneyEncoding(encoding)
+	rounding, err := domain.ParseRoundingMode(os.Getenv("ROUNDING"))
+	if err != nil {
+		panic("error configuring prices: " + err.Error())
+	}
+	// PRICING_RULES is a YAML or JSON file with the consumer price rules,
+	// the default markups by number of items when unset.
+	var rules *pricing.Rules
+	if path := os.Getenv("PRICING_RULES"); path != "" {
+		if rules, err = pricing.Load(path); err != nil {
+			panic("error configuring prices: " + err.Error())
+		}
+	}
 
-	repository, err := products.NewSliceBasedRepository()
+	alerter, err := newAlerter()
 	if err != nil {
-		panic("error loading repository")
+		panic("error configuring alerts: " + err.Error())
+	}
+
+	storage, err := newRepository()
+	if err != nil {
+		panic("error loading repository: " + err.Error())
+	}
+	// LEDGER_FILE keeps the inventory movements and reservations, in memory
+	// only when unset, and RESERVATION_TTL is how long reservations hold
+	// stock by default, e.g. 15m.
+	ledger, err := inventory.OpenLedger(os.Getenv("LEDGER_FILE"))
+	if err != nil {
+		panic("error loading inventory: " + err.Error())
+	}
+	stock, err := inventory.NewRepository(storage, ledger)
+	if err != nil {
+		panic("error loading inventory: " + err.Error())
+	}
+	var ttl time.Duration
+	if value := os.Getenv("RESERVATION_TTL"); value != "" {
+		if ttl, err = time.ParseDuration(value); err != nil {
+			panic("error configuring reservations: " + err.Error())
+		}
+	}
+	inventoryService := inventory.DefaultService{
+		Storage: stock,
+		TTL:     ttl,
+	}
+
+	repository, err := products.NewIndexedRepository(stock)
+	if err != nil {
+		panic("error indexing products: " + err.Error())
+	}
+
+	// CATEGORIES_FILE keeps the category tree, in memory only when unset.
+	categoryStorage, err := categories.NewFileRepository(os.Getenv("CATEGORIES_FILE"))
+	if err != nil {
+		panic("error loading categories: " + err.Error())
+	}
+	categoryService := categories.DefaultService{
+		Storage: categoryStorage,
 	}
 
+	// HISTORY_FILE keeps the revisions of the products, in memory only when
+	// unset.
+	history, err := products.OpenHistory(os.Getenv("HISTORY_FILE"))
+	if err != nil {
+		panic("error loading product history: " + err.Error())
+	}
 	service := products.DefaultService{
-		Storage: repository,
+		Storage:       repository,
+		Rounding:      rounding,
+		Pricing:       rules,
+		Available:     inventoryService.Available,
+		Expiration:    inventoryService.Expiration,
+		CategoryPaths: categoryService.Paths,
+		History:       history,
+	}
+	if alerter != nil {
+		service.Changed = alerter.Changed
+	}
+	// The stock moved by the inventory is recorded in the history and
+	// alerted about like the other writes of the products.
+	stock.Changed = func(previous *domain.Product, current domain.Product) {
+		service.Record(products.OperationStock, previous, current)
 	}
+	// The categories are assigned through the service, to be recorded like
+	// the other writes of the products.
+	categoryService.Products = service
 
 	handler := ProductHandlers{
-		Service: service,
+		Service:    service,
+		Categories: categoryService,
 	}
 
 	group := router.Engine.Group("products")
 
 	group.POST("", middlewares.ValidateToken, handler.Create())
+	group.POST("/import", middlewares.ValidateToken, handler.Import())
 	group.GET("", handler.GetAll())
 	group.GET("/:id", handler.FindById())
 	group.GET("/search", handler.Search())
+	group.GET("/suggest", handler.Suggest())
 	group.PUT("/:id", middlewares.ValidateToken, handler.Update())
 	group.PATCH("/:id", middlewares.ValidateToken, handler.UpdatePartial())
 	group.DELETE("/:id", middlewares.ValidateToken, handler.Delete())
+	group.GET("/trash", middlewares.ValidateToken, handler.Trash())
+	group.POST("/:id/restore", middlewares.ValidateToken, handler.Restore())
+	group.GET("/:id/history", middlewares.ValidateToken, handler.History())
+	group.POST("/:id/history/:revision/revert", middlewares.ValidateToken, handler.Revert())
 	group.GET("/consumer_price", handler.ConsumerPrice())
+	group.POST("/quote", handler.Quote())
+	group.GET("/:id/variants", handler.Variants())
+	group.POST("/:id/variants", middlewares.ValidateToken, handler.CreateVariant())
+	group.PUT("/:id/variants/:variant_id", middlewares.ValidateToken, handler.UpdateVariant())
+
+	categoryHandler := CategoryHandlers{
+		Service:  categoryService,
+		Products: service,
+	}
+	group.PUT("/:id/categories", middlewares.ValidateToken, categoryHandler.Assign())
+
+	categoryGroup := router.Engine.Group("categories")
+	categoryGroup.POST("", middlewares.ValidateToken, categoryHandler.Create())
+	categoryGroup.GET("", categoryHandler.GetAll())
+	categoryGroup.GET("/:id", categoryHandler.FindById())
+	categoryGroup.PUT("/:id", middlewares.ValidateToken, categoryHandler.Update())
+	categoryGroup.DELETE("/:id", middlewares.ValidateToken, categoryHandler.Delete())
+
+	inventoryHandler := InventoryHandlers{
+		Service:  inventoryService,
+		Products: service,
+	}
+	group.GET("/:id/stock", inventoryHandler.Stock())
+	group.GET("/:id/movements", inventoryHandler.Movements())
+	group.POST("/:id/movements", middlewares.ValidateToken, inventoryHandler.Record())
+	group.GET("/:id/lots", inventoryHandler.Lots())
+	group.POST("/:id/lots", middlewares.ValidateToken, inventoryHandler.Receive())
+	router.Engine.GET("/lots/expiring", inventoryHandler.Expiring())
+
+	reservations := router.Engine.Group("reservations")
+	reservations.POST("", middlewares.ValidateToken, inventoryHandler.Reserve())
+	reservations.GET("/:id", inventoryHandler.FindReservation())
+	reservations.POST("/:id/commit", middlewares.ValidateToken, inventoryHandler.Commit())
+	reservations.POST("/:id/release", middlewares.ValidateToken, inventoryHandler.Release())
+
+	// ORDERS_FILE keeps the carts and orders, in memory only when unset.
+	orderStorage, err := orders.NewFileRepository(os.Getenv("ORDERS_FILE"))
+	if err != nil {
+		panic("error loading orders: " + err.Error())
+	}
+	orderHandler := OrderHandlers{
+		Service: orders.DefaultService{
+			Storage:   orderStorage,
+			Products:  service,
+			Inventory: inventoryService,
+		},
+	}
+	carts := router.Engine.Group("carts")
+	carts.POST("", orderHandler.CreateCart())
+	carts.GET("/:id", orderHandler.FindCart())
+	carts.POST("/:id/items", orderHandler.AddItem())
+	carts.DELETE("/:id/items/:product_id", orderHandler.RemoveItem())
+	carts.POST("/:id/checkout", orderHandler.Checkout())
+
+	orderGroup := router.Engine.Group("orders")
+	orderGroup.GET("", middlewares.ValidateToken, orderHandler.Orders())
+	orderGroup.GET("/:id", orderHandler.FindOrder())
+	orderGroup.POST("/:id/status", middlewares.ValidateToken, orderHandler.UpdateStatus())
+
+	// UNPUBLISH_SCHEDULE is the cron expression of the job that unpublishes
+	// the expired products, hourly by default and disabled by "off", and
+	// JOB_JITTER the random delay of the runs of jobs, e.g. 1m.
+	router.Scheduler = &scheduler.Scheduler{}
+	var jitter time.Duration
+	if value := os.Getenv("JOB_JITTER"); value != "" {
+		if jitter, err = time.ParseDuration(value); err != nil {
+			panic("error configuring jobs: " + err.Error())
+		}
+	}
+	if expression := os.Getenv("UNPUBLISH_SCHEDULE"); expression != "off" {
+		if expression == "" {
+			expression = "@hourly"
+		}
+		schedule, err := scheduler.Parse(expression)
+		if err != nil {
+			panic("error configuring jobs: " + err.Error())
+		}
+		err = router.Scheduler.Add(scheduler.Job{
+			Name:       UnpublishExpiredJob,
+			Schedule:   schedule,
+			Expression: expression,
+			Jitter:     jitter,
+			Run:        unpublishExpired(service.By(products.Author{Actor: UnpublishExpiredJob})),
+		})
+		if err != nil {
+			panic("error configuring jobs: " + err.Error())
+		}
+	}
+	// TRASH_RETENTION is how long deleted products stay in the trash, e.g.
+	// 168h, 720h (30 days) by default, and PURGE_SCHEDULE the cron expression
+	// of the job that purges them after it, daily by default and disabled by
+	// "off".
+	if expression := os.Getenv("PURGE_SCHEDULE"); expression != "off" {
+		if expression == "" {
+			expression = "@daily"
+		}
+		retention := defaultTrashRetention
+		if value := os.Getenv("TRASH_RETENTION"); value != "" {
+			if retention, err = time.ParseDuration(value); err != nil {
+				panic("error configuring the trash: " + err.Error())
+			}
+			if retention < 0 {
+				panic("error configuring the trash: negative retention " + value)
+			}
+		}
+		schedule, err := scheduler.Parse(expression)
+		if err != nil {
+			panic("error configuring jobs: " + err.Error())
+		}
+		err = router.Scheduler.Add(scheduler.Job{
+			Name:       PurgeTrashJob,
+			Schedule:   schedule,
+			Expression: expression,
+			Jitter:     jitter,
+			Run:        purgeTrash(service.By(products.Author{Actor: PurgeTrashJob}), retention, time.Now),
+		})
+		if err != nil {
+			panic("error configuring jobs: " + err.Error())
+		}
+	}
+	// ALERT_SCHEDULE is the cron expression of the job that checks the alert
+	// rules on every product, every 15 minutes by default.
+	if alerter != nil {
+		expression := os.Getenv("ALERT_SCHEDULE")
+		if expression == "" {
+			expression = "*/15 * * * *"
+		}
+		schedule, err := scheduler.Parse(expression)
+		if err != nil {
+			panic("error configuring jobs: " + err.Error())
+		}
+		err = router.Scheduler.Add(scheduler.Job{
+			Name:       CheckAlertsJob,
+			Schedule:   schedule,
+			Expression: expression,
+			Jitter:     jitter,
+			Run:        checkAlerts(alerter, service),
+		})
+		if err != nil {
+			panic("error configuring jobs: " + err.Error())
+		}
+	}
+	jobHandler := JobHandlers{Scheduler: router.Scheduler}
+	admin := router.Engine.Group("admin", middlewares.ValidateToken)
+	admin.GET("/jobs", jobHandler.Jobs())
+	admin.POST("/jobs/:name/run", jobHandler.Run())
+}
+
+// newAlerter configures the alerts from the environment, none unless
+// ALERT_RULES is a YAML or JSON file with alert rules. The alerts are sent to
+// every notifier configured:
+//   - ALERT_WEBHOOK_URL posts them as JSON.
+//   - ALERT_SMTP_ADDR emails them through the SMTP server at host:port, from
+//     ALERT_SMTP_FROM to the comma-separated ALERT_SMTP_TO, authenticating
+//     with ALERT_SMTP_USER and ALERT_SMTP_PASSWORD when set.
+//   - ALERT_LOG_FILE appends them to a file as lines of JSON.
+//
+// ALERT_COOLDOWN is how long an alert that still holds waits before it is
+// sent again, e.g. 12h, 24h by default.
+func newAlerter() (*alerts.Alerter, error) {
+	path := os.Getenv("ALERT_RULES")
+	if path == "" {
+		return nil, nil
+	}
+	rules, err := alerts.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	alerter := &alerts.Alerter{Rules: rules}
+	if value := os.Getenv("ALERT_COOLDOWN"); value != "" {
+		if alerter.Cooldown, err = time.ParseDuration(value); err != nil {
+			return nil, err
+		}
+		if alerter.Cooldown <= 0 {
+			return nil, fmt.Errorf("cooldown must be positive, got %s", value)
+		}
+	}
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		alerter.Notifiers = append(alerter.Notifiers, alerts.WebhookNotifier{URL: url})
+	}
+	if addr := os.Getenv("ALERT_SMTP_ADDR"); addr != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		notifier := alerts.SMTPNotifier{Addr: addr, From: os.Getenv("ALERT_SMTP_FROM")}
+		for _, to := range strings.Split(os.Getenv("ALERT_SMTP_TO"), ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				notifier.To = append(notifier.To, to)
+			}
+		}
+		if notifier.From == "" || len(notifier.To) == 0 {
+			return nil, errors.New("ALERT_SMTP_FROM and ALERT_SMTP_TO are required to email alerts")
+		}
+		if user := os.Getenv("ALERT_SMTP_USER"); user != "" {
+			notifier.Auth = smtp.PlainAuth("", user, os.Getenv("ALERT_SMTP_PASSWORD"), host)
+		}
+		alerter.Notifiers = append(alerter.Notifiers, notifier)
+	}
+	if file := os.Getenv("ALERT_LOG_FILE"); file != "" {
+		alerter.Notifiers = append(alerter.Notifiers, &alerts.LogNotifier{Path: file})
+	}
+	return alerter, nil
+}
+
+// newRepository selects the products storage from the REPOSITORY environment
+// variable: "json" (default) keeps products in FILE, "sqlite" uses the
+// database at DATABASE, seeded from FILE the first time. ID_SCHEME chooses
+// the public identifiers of new products (sequential, uuid or ulid).
+func newRepository() (products.Repository, error) {
+	switch os.Getenv("REPOSITORY") {
+	case "", "json":
+		return products.NewSliceBasedRepository()
+	case "sqlite":
+		scheme, err := products.ParseIDScheme(os.Getenv("ID_SCHEME"))
+		if err != nil {
+			return nil, err
+		}
+		repository, err := products.NewSQLiteRepository(os.Getenv("DATABASE"), scheme)
+		if err != nil {
+			return nil, err
+		}
+		if os.Getenv("FILE") != "" {
+			seed, err := store.LoadFile()
+			if err != nil {
+				return nil, err
+			}
+			if err := repository.Seed(seed); err != nil {
+				return nil, err
+			}
+		}
+		return repository, nil
+	default:
+		return nil, fmt.Errorf("unknown repository %q", os.Getenv("REPOSITORY"))
+	}
 }
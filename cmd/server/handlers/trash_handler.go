@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List the products in the trash
+// @Description Retrieves the deleted products, oldest deletion first, with when and by whom they were deleted. They are purged after the retention window of the trash.
+// @Tags products
+// @Produce json
+// @Success 200 {array} domain.Product "Successfully retrieved the trash"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/trash [get]
+func (handler ProductHandlers) Trash() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		trashed, err := handler.Service.Trash()
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, trashed)
+	}
+}
+
+// @Summary Restore a deleted product
+// @Description Moves a product out of the trash, with the data it had when it was deleted. A variant is only restored once its parent is.
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID or UID"
+// @Success 200 {object} domain.Product "Successfully restored the product"
+// @Header 200 {string} ETag "Version of the restored product"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id, or attributes of a variant taken by another variant"
+// @Failure 404 {object} rest.Problem "product_not_in_trash"
+// @Failure 409 {object} rest.Problem "parent_in_trash"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/restore [post]
+func (handler ProductHandlers) Restore() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := handler.trashedID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		product, err := handler.by(ctx).Restore(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.Header("ETag", rest.ETag(product.Version))
+		ctx.JSON(http.StatusOK, product)
+	}
+}
+
+// trashedID parses the :id path parameter like productID, looking up UIDs in
+// the trash, where FindByUID does not look. UIDs not in the trash fail with
+// ErrProductNotInTrash.
+func (handler ProductHandlers) trashedID(ctx *gin.Context) (int, error) {
+	param := ctx.Param("id")
+	if id, err := strconv.Atoi(param); err == nil {
+		return id, nil
+	}
+	if !products.IsUID(param) {
+		return 0, rest.InvalidField("id", "invalid", "must be a product ID or UID")
+	}
+	trashed, err := handler.Service.Trash()
+	if err != nil {
+		return 0, err
+	}
+	for _, product := range trashed {
+		if strings.EqualFold(product.UID, param) {
+			return product.ID, nil
+		}
+	}
+	return 0, products.ErrProductNotInTrash
+}
+
+// PurgeTrashJob is the name of the job that purges the products deleted
+// before the retention window of the trash.
+const PurgeTrashJob = "purge-trash"
+
+// purgeTrash is the job that purges the products deleted for longer than
+// retention, whose last run lists the products it purged.
+func purgeTrash(service products.Service, retention time.Duration, now func() time.Time) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		purged, err := service.Purge(now().Add(-retention))
+		if len(purged) > 0 {
+			log.Printf("purged %d deleted products", len(purged))
+		}
+		return purged, err
+	}
+}
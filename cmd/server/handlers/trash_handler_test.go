@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/cmd/server/middlewares"
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createServerForTestTrashHandler serves a wine with a variant and a cookie,
+// on a clock that only moves when now is changed.
+func createServerForTestTrashHandler(t *testing.T) (*gin.Engine, products.Service, *time.Time) {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	for _, product := range []domain.Product{
+		{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Expiration: domain.NewDate(2030, time.June, 1), Price: domain.NewMoney(17923, "ARS")},
+		{Name: "Wine 750ml", Quantity: 3, CodeValue: "T65812-750", IsPublished: true, Expiration: domain.NewDate(2030, time.June, 1), Price: domain.NewMoney(17923, "ARS"), ParentID: 1, Attributes: map[string]string{"size": "750ml"}},
+		{Name: "Cookie", Quantity: 5, CodeValue: "M7157", IsPublished: true, Expiration: domain.NewDate(2030, time.June, 1), Price: domain.NewMoney(27547, "ARS")},
+	} {
+		require.NoError(t, storage.Create(&product))
+	}
+	now := testNow
+	service := products.DefaultService{Storage: storage, Now: func() time.Time { return now }}
+	handler := ProductHandlers{Service: service}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.POST("/products", handler.Create())
+	server.GET("/products", handler.GetAll())
+	server.GET("/products/:id", handler.FindById())
+	server.DELETE("/products/:id", handler.Delete())
+	server.GET("/products/consumer_price", handler.ConsumerPrice())
+	server.GET("/products/trash", handler.Trash())
+	server.POST("/products/:id/restore", handler.Restore())
+	return server, service, &now
+}
+
+func deleteAs(server *gin.Engine, target, actor string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodDelete, target, nil)
+	request.Header.Set(middlewares.ActorHeader, actor)
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+func TestTrashHandlers(t *testing.T) {
+	t.Run("should move a deleted product to the trash", func(t *testing.T) {
+		server, _, _ := createServerForTestTrashHandler(t)
+
+		response := deleteAs(server, "/products/3", "ana")
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		response = serve(server, http.MethodGet, "/products/3", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		response = serve(server, http.MethodGet, "/products/consumer_price?list=3", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"products":null`)
+		response = deleteAs(server, "/products/3", "ana")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serve(server, http.MethodGet, "/products/trash", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		var trashed []domain.Product
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &trashed))
+		require.Len(t, trashed, 1)
+		assert.Equal(t, "Cookie", trashed[0].Name)
+		assert.Equal(t, testNow, *trashed[0].DeletedAt)
+		assert.Equal(t, "ana", trashed[0].DeletedBy)
+	})
+
+	t.Run("should keep the code value of a product in the trash", func(t *testing.T) {
+		server, _, _ := createServerForTestTrashHandler(t)
+		require.Equal(t, http.StatusNoContent, deleteAs(server, "/products/3", "ana").Code)
+
+		response := serve(server, http.MethodPost, "/products", `{"name":"Cookie","quantity":5,"code_value":"M7157","is_published":true,"expiration":"01/06/2030","price":275.47}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "code_value_in_trash", "a product in the trash has this code value, it must be restored or purged first")
+	})
+
+	t.Run("should restore a product with the data it had", func(t *testing.T) {
+		server, _, _ := createServerForTestTrashHandler(t)
+		require.Equal(t, http.StatusNoContent, deleteAs(server, "/products/3", "ana").Code)
+
+		response := serve(server, http.MethodPost, "/products/3/restore", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+		assert.NotContains(t, response.Body.String(), "deleted_at")
+		response = serve(server, http.MethodGet, "/products/3", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"quantity":5`)
+
+		response = serve(server, http.MethodPost, "/products/3/restore", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "product_not_in_trash", "product not in trash")
+	})
+
+	t.Run("should not find unknown UIDs", func(t *testing.T) {
+		server, _, _ := createServerForTestTrashHandler(t)
+		uid := "01890a5d-ac96-774b-bcce-b302099a8057"
+
+		response := serve(server, http.MethodPost, "/products/"+uid+"/restore", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "product_not_in_trash", "product not in trash")
+		response = deleteAs(server, "/products/"+uid, "ana")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "product_not_found", "product not found")
+	})
+
+	t.Run("should restore a variant after its parent", func(t *testing.T) {
+		server, _, _ := createServerForTestTrashHandler(t)
+
+		response := deleteAs(server, "/products/1", "ana")
+		assert.Equal(t, http.StatusConflict, response.Code)
+		require.Equal(t, http.StatusNoContent, deleteAs(server, "/products/2", "ana").Code)
+		require.Equal(t, http.StatusNoContent, deleteAs(server, "/products/1", "ana").Code)
+
+		response = serve(server, http.MethodPost, "/products/2/restore", "")
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "parent_in_trash", "the parent of the variant must be restored first")
+		assert.Equal(t, http.StatusOK, serve(server, http.MethodPost, "/products/1/restore", "").Code)
+		assert.Equal(t, http.StatusOK, serve(server, http.MethodPost, "/products/2/restore", "").Code)
+	})
+
+	t.Run("should purge the products deleted before the retention window", func(t *testing.T) {
+		server, service, now := createServerForTestTrashHandler(t)
+		require.Equal(t, http.StatusNoContent, deleteAs(server, "/products/2", "ana").Code)
+		*now = now.Add(48 * time.Hour)
+		require.Equal(t, http.StatusNoContent, deleteAs(server, "/products/3", "ana").Code)
+
+		run := purgeTrash(service, 24*time.Hour, func() time.Time { return *now })
+		purged, err := run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		assert.Equal(t, 2, purged.([]domain.Product)[0].ID)
+
+		var trashed []domain.Product
+		response := serve(server, http.MethodGet, "/products/trash", "")
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &trashed))
+		require.Len(t, trashed, 1)
+		assert.Equal(t, 3, trashed[0].ID)
+		response = serve(server, http.MethodPost, "/products", `{"name":"Wine 750ml","quantity":3,"code_value":"T65812-750","is_published":true,"expiration":"01/06/2030","price":179.23}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+	})
+}
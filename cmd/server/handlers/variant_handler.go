@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func variantID(ctx *gin.Context) (int, error) {
+	id, err := strconv.Atoi(ctx.Param("variant_id"))
+	if err != nil {
+		return 0, rest.InvalidField("variant_id", "type", "must be a product ID")
+	}
+	return id, nil
+}
+
+// @Summary List the variants of a product
+// @Description Retrieves the variants, or SKUs, of a product sorted by ID. Each variant is a product with its own code value, price, stock and expiration.
+// @Tags variants
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} domain.Product "Successfully retrieved the variants"
+// @Failure 400 {object} rest.Problem "validation_failed: invalid id"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/variants [get]
+func (handler ProductHandlers) Variants() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := handler.productID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		variants, err := handler.Service.Variants(id)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, variants)
+	}
+}
+
+// @Summary Create a variant of a product
+// @Description Adds a variant to a product, told apart from the other variants by its size, color or flavour. Its code value is unique among all products and variants. Without a name, the variant is named after the product and its attributes. Variants cannot have variants.
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param variant body VariantRequest true "Variant"
+// @Success 201 {object} domain.Product "Successfully created the variant"
+// @Header 201 {string} ETag "Version of the variant"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "product_not_found"
+// @Failure 409 {object} rest.Problem "product_already_exists or nested_variant"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/variants [post]
+func (handler ProductHandlers) CreateVariant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := handler.productID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		var request VariantRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		variant, err := request.ToDomain()
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		if err := handler.by(ctx).CreateVariant(id, &variant); err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		ctx.Header("ETag", rest.ETag(variant.Version))
+		ctx.JSON(http.StatusCreated, variant)
+	}
+}
+
+// @Summary Update a variant of a product
+// @Description Replaces a variant of a product. A product that is not a variant and has no variants becomes a variant of the product.
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param variant_id path int true "Variant ID"
+// @Param variant body VariantRequest true "Variant"
+// @Param If-Match header string false "ETag of the variant read, the update fails if it was modified since"
+// @Success 200 {object} domain.Product "Successfully updated the variant"
+// @Header 200 {string} ETag "Version of the updated variant"
+// @Failure 400 {object} rest.Problem "validation_failed or malformed_body"
+// @Failure 404 {object} rest.Problem "product_not_found or variant_not_found"
+// @Failure 409 {object} rest.Problem "product_already_exists or nested_variant"
+// @Failure 412 {object} rest.Problem "version_conflict: the variant was modified since it was read, or is missing with If-Match: *"
+// @Failure 500 {object} rest.Problem "internal_error"
+// @Router /products/{id}/variants/{variant_id} [put]
+func (handler ProductHandlers) UpdateVariant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := handler.productID(ctx)
+		if err != nil {
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound))
+			return
+		}
+		variantID, err := variantID(ctx)
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		var request VariantRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problems.Abort(ctx, rest.BindingError(err))
+			return
+		}
+		variant, err := request.ToDomain()
+		if err != nil {
+			problems.Abort(ctx, err)
+			return
+		}
+		version, ok := ifMatchVersion(ctx)
+		if !ok {
+			problems.Abort(ctx, products.ErrVersionConflict)
+			return
+		}
+		variant.ID = variantID
+		variant.Version = version
+		if err := handler.by(ctx).UpdateVariant(id, &variant); err != nil {
+			problems.Abort(ctx, ifMatchFound(ctx, err, products.ErrVersionConflict, products.ErrProductNotFound, products.ErrVariantNotFound))
+			return
+		}
+		ctx.Header("ETag", rest.ETag(variant.Version))
+		ctx.JSON(http.StatusOK, variant)
+	}
+}
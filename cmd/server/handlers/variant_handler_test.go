@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createServerForTestVariantHandler serves a wine and a cookie, kept with
+// their variants apart from the other tests.
+func createServerForTestVariantHandler(t *testing.T) *gin.Engine {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	for _, product := range []domain.Product{
+		{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(17923, "ARS")},
+		{Name: "Cookie", Quantity: 5, CodeValue: "M7157", IsPublished: true, Price: domain.NewMoney(27547, "ARS")},
+	} {
+		require.NoError(t, storage.Create(&product))
+	}
+	handler := ProductHandlers{Service: products.DefaultService{Storage: storage, Now: func() time.Time { return testNow }}}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.PATCH("/products/:id", handler.UpdatePartial())
+	server.DELETE("/products/:id", handler.Delete())
+	server.GET("/products/:id/variants", handler.Variants())
+	server.POST("/products/:id/variants", handler.CreateVariant())
+	server.PUT("/products/:id/variants/:variant_id", handler.UpdateVariant())
+	return server
+}
+
+func TestVariantHandlers(t *testing.T) {
+	t.Run("should create, list and update the variants of a product", func(t *testing.T) {
+		server := createServerForTestVariantHandler(t)
+
+		response := serve(server, http.MethodPost, "/products/1/variants", `{"quantity":3,"code_value":"T65812-750","is_published":true,"expiration":"01/06/2030","price":179.23,"attributes":{"size":"750ml","color":"red"}}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Equal(t, `"1"`, response.Header().Get("ETag"))
+		var variant domain.Product
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &variant))
+		assert.Equal(t, "Wine 750ml red", variant.Name)
+		assert.Equal(t, 1, variant.ParentID)
+
+		response = serve(server, http.MethodPut, "/products/1/variants/2", `{"name":"Cookie","quantity":5,"code_value":"M7157","is_published":true,"expiration":"01/06/2030","price":275.47,"attributes":{"size":"1.5l"}}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"parent_id":1`)
+
+		var variants []domain.Product
+		response = serve(server, http.MethodGet, "/products/1/variants", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &variants))
+		require.Len(t, variants, 2)
+		assert.Equal(t, 2, variants[0].ID)
+		assert.Equal(t, variant.ID, variants[1].ID)
+
+		response = serve(server, http.MethodDelete, "/products/1", "")
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "product_has_variants", "the variants of the product must be deleted first")
+	})
+
+	t.Run("should reject invalid variants", func(t *testing.T) {
+		server := createServerForTestVariantHandler(t)
+		serve(server, http.MethodPost, "/products/1/variants", `{"quantity":3,"code_value":"T65812-750","expiration":"01/06/2030","price":179.23,"attributes":{"size":"750ml"}}`)
+
+		response := serve(server, http.MethodPost, "/products/1/variants", `{"quantity":3,"code_value":"T65812-75","expiration":"01/06/2030","price":179.23,"attributes":{"size":"750ml"}}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"unique"`)
+
+		response = serve(server, http.MethodPost, "/products/1/variants", `{"quantity":3,"code_value":"M7157","expiration":"01/06/2030","price":179.23,"attributes":{"size":"1.5l"}}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "product_already_exists", "another product has this code value")
+
+		response = serve(server, http.MethodPost, "/products/1/variants", `{"quantity":3,"code_value":"T65812-1","expiration":"01/06/2030","price":179.23,"attributes":{"vintage":"2019"}}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"field":"attributes.vintage"`)
+
+		response = serve(server, http.MethodPost, "/products/3/variants", `{"quantity":3,"code_value":"T65812-2","expiration":"01/06/2030","price":179.23,"attributes":{"size":"2l"}}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assertProblem(t, response, "nested_variant", "variants cannot have variants")
+
+		response = serve(server, http.MethodPut, "/products/2/variants/3", `{"quantity":3,"code_value":"T65812-750","expiration":"01/06/2030","price":179.23,"attributes":{"size":"750ml"}}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assertProblem(t, response, "variant_not_found", "variant not found")
+
+		response = serve(server, http.MethodPatch, "/products/3", `{"attributes":{"size":"1l"}}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assertProblem(t, response, "immutable_field", "field cannot be changed: attributes")
+	})
+}
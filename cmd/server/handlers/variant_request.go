@@ -0,0 +1,20 @@
+package handlers
+
+import "github.com/Andrea-Reyna/go-web/internal/domain"
+
+// VariantRequest is a product with the attributes that tell it apart from
+// the other variants of its parent. Without a name, the variant is named
+// after its parent and its attributes.
+type VariantRequest struct {
+	CreateProductRequest
+	Attributes map[string]string `json:"attributes" example:"size:750ml"`
+}
+
+func (request VariantRequest) ToDomain() (domain.Product, error) {
+	product, err := request.CreateProductRequest.ToDomain()
+	if err != nil {
+		return domain.Product{}, err
+	}
+	product.Attributes = request.Attributes
+	return product, nil
+}
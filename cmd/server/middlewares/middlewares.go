@@ -1,23 +1,90 @@
 package middlewares
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/Andrea-Reyna/go-web/pkg/rest"
 	"github.com/gin-gonic/gin"
 )
 
+var ErrInvalidToken = errors.New("invalid token")
+
+var problems = rest.NewProblems().
+	Register(ErrInvalidToken, rest.ProblemType{Status: http.StatusUnauthorized, Code: "invalid_token"})
+
 func ValidateToken(ctx *gin.Context) {
-	header := ctx.GetHeader("token")
-	if header != os.Getenv("TOKEN") {
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+	if !ValidToken(ctx) {
+		problems.Abort(ctx, ErrInvalidToken)
 		return
 	}
 	ctx.Next()
 }
 
+// ValidToken tells whether the request has the token, for the handlers that
+// only need it for some requests.
+func ValidToken(ctx *gin.Context) bool {
+	return ctx.GetHeader("token") == os.Getenv("TOKEN")
+}
+
+// ActorHeader names who makes a request, e.g. the user of a back office, for
+// the writes that record who made them. Requests without it are made by
+// UnknownActor.
+const (
+	ActorHeader  = "X-Actor"
+	UnknownActor = "unknown"
+)
+
+// Actor returns who makes the request.
+func Actor(ctx *gin.Context) string {
+	if actor := strings.TrimSpace(ctx.GetHeader(ActorHeader)); actor != "" {
+		return actor
+	}
+	return UnknownActor
+}
+
+// RequestIDHeader identifies a request, e.g. in the revisions of the products
+// it writes. Requests without it are given one, and it is sent back in the
+// response.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey keeps the ID of a request in its context.
+const requestIDKey = "request_id"
+
+// maxRequestIDLength bounds the IDs taken from the requests.
+const maxRequestIDLength = 128
+
+func AssignRequestID(ctx *gin.Context) {
+	id := strings.TrimSpace(ctx.GetHeader(RequestIDHeader))
+	if id == "" || len(id) > maxRequestIDLength {
+		id = newRequestID()
+	}
+	ctx.Set(requestIDKey, id)
+	ctx.Header(RequestIDHeader, id)
+	ctx.Next()
+}
+
+// RequestID returns the ID of the request, the one in its header when it is
+// not served behind AssignRequestID.
+func RequestID(ctx *gin.Context) string {
+	if id := ctx.GetString(requestIDKey); id != "" {
+		return id
+	}
+	return strings.TrimSpace(ctx.GetHeader(RequestIDHeader))
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
 func Logger(ctx *gin.Context) {
 	startTime := time.Now()
 
@@ -0,0 +1,131 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// DefaultCooldown is how long an alert that still holds waits before it is
+// sent again.
+const DefaultCooldown = 24 * time.Hour
+
+// notifyTimeout bounds the delivery of an alert by a notifier.
+const notifyTimeout = 30 * time.Second
+
+// Alerter evaluates the rules on the writes of products and on every product
+// periodically, and delivers the alerts through every notifier. An alert is
+// only sent again once Cooldown has passed, unless its rule stopped holding
+// in between: a product restocked and running out again alerts again. Price
+// changes alert once per new price.
+type Alerter struct {
+	Rules     *Rules
+	Notifiers []Notifier
+	// Cooldown is DefaultCooldown when zero.
+	Cooldown time.Duration
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+
+	mu sync.Mutex
+	// sent is when every alert that still holds was last sent, by key.
+	sent map[string]time.Time
+	// deliveries are the alerts of Changed being delivered.
+	deliveries sync.WaitGroup
+}
+
+func (alerter *Alerter) now() time.Time {
+	if alerter.Now == nil {
+		return time.Now()
+	}
+	return alerter.Now()
+}
+
+// Changed evaluates the rules on a written product, previous being the
+// product it replaced, if any, and delivers the new alerts in the
+// background, so that writes do not wait for the notifiers.
+func (alerter *Alerter) Changed(previous *domain.Product, current domain.Product) {
+	alerts := alerter.due(previous, []domain.Product{current}, false)
+	if len(alerts) == 0 {
+		return
+	}
+	alerter.deliveries.Add(1)
+	go func() {
+		defer alerter.deliveries.Done()
+		alerter.deliver(context.Background(), alerts)
+	}()
+}
+
+// Check evaluates the rules on products and delivers the new alerts, which
+// it returns. It also forgets the alerts sent before the cooldown, which
+// would be sent again anyway.
+func (alerter *Alerter) Check(ctx context.Context, products []domain.Product) []Alert {
+	alerts := alerter.due(nil, products, true)
+	alerter.deliver(ctx, alerts)
+	return alerts
+}
+
+// Wait waits for the alerts of Changed to be delivered.
+func (alerter *Alerter) Wait() {
+	alerter.deliveries.Wait()
+}
+
+// due returns the alerts of products that were not sent within the cooldown,
+// and marks them as sent.
+func (alerter *Alerter) due(previous *domain.Product, products []domain.Product, prune bool) []Alert {
+	if alerter.Rules == nil {
+		return nil
+	}
+	now := alerter.now()
+	today := domain.DateOf(now)
+	cooldown := alerter.Cooldown
+	if cooldown == 0 {
+		cooldown = DefaultCooldown
+	}
+
+	alerter.mu.Lock()
+	defer alerter.mu.Unlock()
+	if alerter.sent == nil {
+		alerter.sent = map[string]time.Time{}
+	}
+	if prune {
+		for key, sent := range alerter.sent {
+			if now.Sub(sent) >= cooldown {
+				delete(alerter.sent, key)
+			}
+		}
+	}
+	due := []Alert{}
+	for _, product := range products {
+		alerts, cleared := alerter.Rules.Evaluate(previous, product, today)
+		for _, key := range cleared {
+			delete(alerter.sent, key)
+		}
+		for _, alert := range alerts {
+			if sent, ok := alerter.sent[alert.key]; ok && now.Sub(sent) < cooldown {
+				continue
+			}
+			alerter.sent[alert.key] = now
+			alert.CreatedAt = now
+			due = append(due, alert)
+		}
+	}
+	return due
+}
+
+// deliver sends alerts through every notifier. Notifiers that fail are
+// logged and not retried, so that a notifier down does not make the others
+// send the alert again and again.
+func (alerter *Alerter) deliver(ctx context.Context, alerts []Alert) {
+	for _, alert := range alerts {
+		for _, notifier := range alerter.Notifiers {
+			notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+			if err := notifier.Notify(notifyCtx, alert); err != nil {
+				log.Printf("error delivering alert %q of product %d: %v", alert.Rule, alert.ProductID, err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func product(id int, quantity int, expiration domain.Date, price int64) domain.Product {
+	return domain.Product{ID: id, Name: "Wine", Quantity: quantity, Expiration: expiration, Price: domain.NewMoney(price, "ARS")}
+}
+
+func loadRules(t *testing.T) *Rules {
+	rules, err := Load("testdata/rules.yaml")
+	require.NoError(t, err)
+	return rules
+}
+
+func TestRules_Evaluate(t *testing.T) {
+	rules := loadRules(t)
+	today := domain.DateOf(testNow)
+	later := domain.NewDate(2023, time.January, 1)
+
+	t.Run("should alert on low stock", func(t *testing.T) {
+		alerts, _ := rules.Evaluate(nil, product(3, 4, later, 1000), today)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "low stock", alerts[0].Rule)
+		assert.Equal(t, KindLowStock, alerts[0].Kind)
+		assert.Equal(t, "Wine has 4 units left, below 5", alerts[0].Message)
+
+		alerts, cleared := rules.Evaluate(nil, product(3, 5, later, 1000), today)
+		assert.Empty(t, alerts)
+		assert.Equal(t, []string{"low stock/3"}, cleared)
+	})
+
+	t.Run("should alert on the expiration of the products of the rule", func(t *testing.T) {
+		soon := domain.NewDate(2022, time.January, 8)
+		alerts, _ := rules.Evaluate(nil, product(1, 10, soon, 1000), today)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "Wine expires on 08/01/2022, within 7 days", alerts[0].Message)
+
+		alerts, _ = rules.Evaluate(nil, product(2, 10, domain.NewDate(2021, time.December, 31), 1000), today)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "Wine expired on 31/12/2021", alerts[0].Message)
+
+		alerts, _ = rules.Evaluate(nil, product(1, 10, domain.NewDate(2022, time.January, 9), 1000), today)
+		assert.Empty(t, alerts)
+		alerts, _ = rules.Evaluate(nil, product(3, 10, soon, 1000), today)
+		assert.Empty(t, alerts)
+	})
+
+	t.Run("should alert on price changes above the percentage", func(t *testing.T) {
+		previous := product(3, 10, later, 1000)
+		alerts, _ := rules.Evaluate(&previous, product(3, 10, later, 1201), today)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "the price of Wine changed from 10.00 to 12.01, more than 20%", alerts[0].Message)
+
+		alerts, _ = rules.Evaluate(&previous, product(3, 10, later, 799), today)
+		assert.Len(t, alerts, 1)
+		alerts, _ = rules.Evaluate(&previous, product(3, 10, later, 1200), today)
+		assert.Empty(t, alerts)
+		alerts, _ = rules.Evaluate(nil, product(3, 10, later, 5000), today)
+		assert.Empty(t, alerts)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("should read JSON", func(t *testing.T) {
+		rules, err := Parse([]byte(`[{"name": "empty", "kind": "low_stock", "threshold": 1}]`))
+		require.NoError(t, err)
+		alerts, _ := rules.Evaluate(nil, product(1, 0, domain.Date{}, 1000), domain.DateOf(testNow))
+		assert.Len(t, alerts, 1)
+	})
+
+	t.Run("should reject invalid rules", func(t *testing.T) {
+		files := []string{
+			`- {name: a, kind: out_of_stock, threshold: 1}`,
+			`- {kind: low_stock, threshold: 1}`,
+			`- {name: a, kind: low_stock}`,
+			`- {name: a, kind: expiring, days: -1}`,
+			`- {name: a, kind: price_change}`,
+			`- {name: a, kind: price_change, percent: abc}`,
+			`[{name: a, kind: low_stock, threshold: 1}, {name: a, kind: expiring, days: 1}]`,
+			`{name: a}`,
+		}
+		for _, file := range files {
+			_, err := Parse([]byte(file))
+			assert.ErrorIs(t, err, ErrInvalidRules, file)
+		}
+	})
+}
+
+// recorder is a notifier that keeps the alerts it is sent.
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (recorder *recorder) Notify(ctx context.Context, alert Alert) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.alerts = append(recorder.alerts, alert)
+	return nil
+}
+
+func (recorder *recorder) rules() []string {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	rules := []string{}
+	for _, alert := range recorder.alerts {
+		rules = append(rules, alert.Rule)
+	}
+	return rules
+}
+
+func TestAlerter(t *testing.T) {
+	later := domain.NewDate(2023, time.January, 1)
+	setup := func() (*Alerter, *recorder, *time.Time) {
+		now := testNow
+		notifier := &recorder{}
+		alerter := &Alerter{Rules: loadRules(t), Notifiers: []Notifier{notifier}, Cooldown: time.Hour, Now: func() time.Time { return now }}
+		return alerter, notifier, &now
+	}
+
+	t.Run("should not send an alert again within the cooldown", func(t *testing.T) {
+		alerter, notifier, now := setup()
+		alerter.Changed(nil, product(3, 4, later, 1000))
+		alerter.Changed(nil, product(3, 3, later, 1000))
+		assert.Empty(t, alerter.Check(context.Background(), []domain.Product{product(3, 2, later, 1000)}))
+		alerter.Wait()
+		assert.Equal(t, []string{"low stock"}, notifier.rules())
+
+		*now = now.Add(time.Hour)
+		alerts := alerter.Check(context.Background(), []domain.Product{product(3, 2, later, 1000)})
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, []string{"low stock", "low stock"}, notifier.rules())
+	})
+
+	t.Run("should send an alert again once its rule held again", func(t *testing.T) {
+		alerter, notifier, _ := setup()
+		alerter.Changed(nil, product(3, 4, later, 1000))
+		alerter.Changed(nil, product(3, 10, later, 1000))
+		alerter.Changed(nil, product(3, 4, later, 1000))
+		alerter.Wait()
+		assert.Equal(t, []string{"low stock", "low stock"}, notifier.rules())
+	})
+
+	t.Run("should send a price change once per new price", func(t *testing.T) {
+		alerter, notifier, _ := setup()
+		previous := product(3, 10, later, 1000)
+		alerter.Changed(&previous, product(3, 10, later, 2000))
+		alerter.Changed(&previous, product(3, 10, later, 2000))
+		alerter.Changed(&previous, product(3, 10, later, 3000))
+		alerter.Wait()
+		assert.Equal(t, []string{"price jump", "price jump"}, notifier.rules())
+	})
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	alert := Alert{Rule: "low stock", Kind: KindLowStock, ProductID: 3, Product: "Wine", Message: "Wine has 4 units left, below 5", CreatedAt: testNow}
+
+	t.Run("should post the alert as JSON", func(t *testing.T) {
+		received := make(chan Alert, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var posted Alert
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+			received <- posted
+		}))
+		defer server.Close()
+
+		require.NoError(t, WebhookNotifier{URL: server.URL}.Notify(context.Background(), alert))
+		assert.Equal(t, alert, <-received)
+	})
+
+	t.Run("should fail when the webhook does not answer 2xx", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		err := WebhookNotifier{URL: server.URL}.Notify(context.Background(), alert)
+		assert.ErrorContains(t, err, "502 Bad Gateway")
+	})
+}
+
+// fakeSMTPServer accepts one SMTP session and sends the message it receives
+// on the channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		var message strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				reply("354 end with .")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					message.WriteString(line)
+				}
+				messages <- message.String()
+				reply("250 queued")
+			case strings.HasPrefix(command, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+	notifier := SMTPNotifier{Addr: addr, From: "alerts@example.com", To: []string{"stock@example.com", "buyers@example.com"}}
+	alert := Alert{Rule: "low stock", Kind: KindLowStock, ProductID: 3, Product: "Wine", Message: "Wine has 4 units left, below 5", CreatedAt: testNow}
+
+	require.NoError(t, notifier.Notify(context.Background(), alert))
+	message := <-messages
+	assert.Contains(t, message, "To: stock@example.com, buyers@example.com\r\n")
+	assert.Contains(t, message, "Subject: [low stock] Wine\r\n")
+	assert.Contains(t, message, "\r\n\r\nWine has 4 units left, below 5\r\n")
+}
+
+func TestSMTPNotifier_HeaderInjection(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+	notifier := SMTPNotifier{Addr: addr, From: "alerts@example.com", To: []string{"stock@example.com"}}
+	alert := Alert{Rule: "low stock", Kind: KindLowStock, ProductID: 3, Product: "Wine\r\nBcc: thief@example.com", Message: "Wine has 4 units left, below 5", CreatedAt: testNow}
+
+	require.NoError(t, notifier.Notify(context.Background(), alert))
+	message := <-messages
+	assert.NotContains(t, message, "\r\nBcc:")
+	assert.Contains(t, message, "Subject: =?utf-8?q?")
+}
+
+func TestSMTPNotifier_Timeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	// The server accepts the connection and never greets.
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	notifier := SMTPNotifier{Addr: listener.Addr().String(), From: "alerts@example.com", To: []string{"stock@example.com"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = notifier.Notify(ctx, Alert{Rule: "low stock", ProductID: 3, Product: "Wine", CreatedAt: testNow})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestLogNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.log")
+	notifier := &LogNotifier{Path: path}
+	for _, rule := range []string{"low stock", "price jump"} {
+		require.NoError(t, notifier.Notify(context.Background(), Alert{Rule: rule, ProductID: 3, CreatedAt: testNow}))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var logged Alert
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &logged))
+	assert.Equal(t, "price jump", logged.Rule)
+}
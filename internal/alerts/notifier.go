@@ -0,0 +1,177 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier delivers alerts.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// WebhookNotifier posts every alert as JSON to URL, which must answer with a
+// 2xx status.
+type WebhookNotifier struct {
+	URL string
+	// Client sends the requests, a client with a 10 seconds timeout when
+	// nil.
+	Client *http.Client
+}
+
+func (notifier WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("error encoding alert: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, notifier.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting alert: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	client := notifier.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error posting alert: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("error posting alert: webhook answered %s", response.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier emails every alert through the SMTP server at Addr, e.g.
+// smtp.example.com:587. Auth, when not nil, requires a server with TLS,
+// unless it runs on localhost.
+type SMTPNotifier struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+func (notifier SMTPNotifier) Notify(ctx context.Context, alert Alert) error {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", notifier.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(notifier.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", encodeHeader(fmt.Sprintf("[%s] %s", alert.Rule, alert.Product)))
+	fmt.Fprintf(&message, "Date: %s\r\n", alert.CreatedAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&message, "%s\r\n", alert.Message)
+	if err := notifier.send(ctx, message.Bytes()); err != nil {
+		return fmt.Errorf("error emailing alert: %w", err)
+	}
+	return nil
+}
+
+// send sends message like smtp.SendMail, but stops when ctx is done: the
+// connection is dialed with ctx, has its deadline and is closed when ctx is
+// cancelled, so a stalled server does not hold the caller.
+func (notifier SMTPNotifier) send(ctx context.Context, message []byte) error {
+	host, _, err := net.SplitHostPort(notifier.Addr)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", notifier.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if notifier.Auth != nil {
+		if err := client.Auth(notifier.Auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(notifier.From); err != nil {
+		return err
+	}
+	for _, to := range notifier.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// encodeHeader encodes a header value that is not printable ASCII as a MIME
+// encoded-word, so that the names of products, which may have line breaks,
+// cannot add headers.
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", value)
+}
+
+// LogNotifier appends every alert to the file at Path as a line of JSON.
+type LogNotifier struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (notifier *LogNotifier) Notify(ctx context.Context, alert Alert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("error encoding alert: %w", err)
+	}
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	file, err := os.OpenFile(notifier.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error logging alert: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error logging alert: %w", err)
+	}
+	return file.Close()
+}
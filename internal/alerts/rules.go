@@ -0,0 +1,198 @@
+// Package alerts warns about products running out, expiring or changing
+// price much, as told by rules loaded from a YAML or JSON file, through
+// notifiers such as webhooks, email or a log file.
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidRules = errors.New("invalid alert rules")
+
+// Kind is the condition a rule alerts on.
+type Kind string
+
+const (
+	// KindLowStock alerts when the quantity is below the threshold.
+	KindLowStock Kind = "low_stock"
+	// KindExpiring alerts when the product expires within some days, or
+	// has expired.
+	KindExpiring Kind = "expiring"
+	// KindPriceChange alerts when a write changes the price by more than a
+	// percentage of the price it replaces.
+	KindPriceChange Kind = "price_change"
+)
+
+// Rule is a condition on products. Products, when not empty, limits the rule
+// to some products.
+type Rule struct {
+	Name      string
+	Kind      Kind
+	Threshold int
+	Days      int
+	// Percent is the percentage as written in the rules file, e.g. 10.5.
+	Percent  string
+	Products []int
+
+	rate domain.Rate
+}
+
+// ruleFile is a rule as written in a rules file.
+type ruleFile struct {
+	Name      string `yaml:"name"`
+	Kind      Kind   `yaml:"kind"`
+	Threshold int    `yaml:"threshold"`
+	Days      int    `yaml:"days"`
+	Percent   string `yaml:"percent"`
+	Products  []int  `yaml:"products"`
+}
+
+// Rules are the alert rules, in the order of the rules file.
+type Rules struct {
+	rules []Rule
+}
+
+// Load reads the rules in the YAML or JSON file at path.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading alert rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads a YAML or JSON list of rules, JSON being valid YAML. Errors
+// wrap ErrInvalidRules and name the rule at fault. Rule names are unique,
+// since alerts are told apart by them.
+func Parse(data []byte) (*Rules, error) {
+	var files []ruleFile
+	if err := yaml.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRules, err)
+	}
+	rules := make([]Rule, len(files))
+	names := map[string]bool{}
+	for i, file := range files {
+		rule, err := file.compile()
+		if err == nil && names[rule.Name] {
+			err = errors.New("duplicated name")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d (%s): %s", ErrInvalidRules, i, file.Name, err)
+		}
+		names[rule.Name] = true
+		rules[i] = rule
+	}
+	return &Rules{rules: rules}, nil
+}
+
+func (file ruleFile) compile() (Rule, error) {
+	rule := Rule{
+		Name:      file.Name,
+		Kind:      file.Kind,
+		Threshold: file.Threshold,
+		Days:      file.Days,
+		Percent:   file.Percent,
+		Products:  file.Products,
+	}
+	if rule.Name == "" {
+		return Rule{}, errors.New("missing name")
+	}
+	switch rule.Kind {
+	case KindLowStock:
+		if rule.Threshold <= 0 {
+			return Rule{}, fmt.Errorf("threshold must be positive, got %d", rule.Threshold)
+		}
+	case KindExpiring:
+		if rule.Days < 0 {
+			return Rule{}, fmt.Errorf("days must not be negative, got %d", rule.Days)
+		}
+	case KindPriceChange:
+		rate, err := domain.ParsePercent(file.Percent)
+		if err != nil || rate.Sign() <= 0 {
+			return Rule{}, fmt.Errorf("percent must be a positive number, got %q", file.Percent)
+		}
+		rule.rate = rate
+	default:
+		return Rule{}, fmt.Errorf("unknown kind %q", rule.Kind)
+	}
+	return rule, nil
+}
+
+// Alert is a rule that holds for a product.
+type Alert struct {
+	Rule      string    `json:"rule" example:"low stock"`
+	Kind      Kind      `json:"kind" enums:"low_stock,expiring,price_change" example:"low_stock"`
+	ProductID int       `json:"product_id" example:"2"`
+	Product   string    `json:"product" example:"Wine"`
+	Message   string    `json:"message" example:"Wine has 3 units left, below 5"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// key tells the same alert apart from the others: the rule and the
+	// product, and for price changes the new price too.
+	key string
+}
+
+// Evaluate returns the alerts of current on today, previous being the
+// product it replaced, if any. Price changes are only told by writes, so
+// they need previous. It also returns the keys of the alerts of the rules
+// that no longer hold.
+func (rules *Rules) Evaluate(previous *domain.Product, current domain.Product, today domain.Date) (alerts []Alert, cleared []string) {
+	for _, rule := range rules.rules {
+		if !rule.appliesTo(current.ID) {
+			continue
+		}
+		alert := Alert{Rule: rule.Name, Kind: rule.Kind, ProductID: current.ID, Product: current.Name, key: rule.Name + "/" + strconv.Itoa(current.ID)}
+		holds := false
+		switch rule.Kind {
+		case KindLowStock:
+			holds = current.Quantity < rule.Threshold
+			alert.Message = fmt.Sprintf("%s has %d units left, below %d", current.Name, current.Quantity, rule.Threshold)
+		case KindExpiring:
+			until := domain.DateOf(today.Time().AddDate(0, 0, rule.Days))
+			holds = !current.Expiration.IsZero() && !current.Expiration.After(until)
+			if current.Expiration.Before(today) {
+				alert.Message = fmt.Sprintf("%s expired on %s", current.Name, current.Expiration)
+			} else {
+				alert.Message = fmt.Sprintf("%s expires on %s, within %d days", current.Name, current.Expiration, rule.Days)
+			}
+		case KindPriceChange:
+			if previous == nil || previous.Price.Currency != current.Price.Currency || previous.Price.Amount == current.Price.Amount {
+				continue
+			}
+			change := current.Price.Amount - previous.Price.Amount
+			if change < 0 {
+				change = -change
+			}
+			// A threshold out of range is above any change.
+			threshold, err := previous.Price.Mul(rule.rate, domain.RoundHalfUp)
+			holds = err == nil && change > threshold.Amount
+			alert.Message = fmt.Sprintf("the price of %s changed from %s to %s, more than %s%%", current.Name, previous.Price, current.Price, rule.Percent)
+			alert.key += "/" + strconv.FormatInt(current.Price.Amount, 10)
+		}
+		if holds {
+			alerts = append(alerts, alert)
+		} else if rule.Kind != KindPriceChange {
+			cleared = append(cleared, alert.key)
+		}
+	}
+	return alerts, cleared
+}
+
+func (rule Rule) appliesTo(productID int) bool {
+	if len(rule.Products) == 0 {
+		return true
+	}
+	for _, id := range rule.Products {
+		if id == productID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package categories
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService returns a service with a wine product and the categories
+// beverages (1), beverages/wine (2) and beverages/wine/red (3).
+func newTestService(t *testing.T) DefaultService {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	product := domain.Product{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(10000, "ARS")}
+	require.NoError(t, storage.Create(&product))
+
+	history, err := products.OpenHistory("")
+	require.NoError(t, err)
+	repository, err := NewFileRepository("")
+	require.NoError(t, err)
+	service := DefaultService{Storage: repository, Products: products.DefaultService{Storage: storage, History: history}}
+	for _, category := range []domain.Category{
+		{Name: "Beverages"},
+		{Name: "Wine", ParentID: 1},
+		{Name: "Red", ParentID: 2},
+	} {
+		require.NoError(t, service.Create(&category))
+	}
+	return service
+}
+
+func TestDefaultService_Create(t *testing.T) {
+	t.Run("should make the slug and the path of a category", func(t *testing.T) {
+		service := newTestService(t)
+
+		category := domain.Category{Name: "Sparkling Wine ", ParentID: 1}
+		require.NoError(t, service.Create(&category))
+		assert.Equal(t, domain.Category{ID: 4, Name: "Sparkling Wine ", Slug: "sparkling-wine", ParentID: 1, Path: "beverages/sparkling-wine", Version: 1}, category)
+
+		all, err := service.GetAll()
+		require.NoError(t, err)
+		var paths []string
+		for _, category := range all {
+			paths = append(paths, category.Path)
+		}
+		assert.Equal(t, []string{"beverages", "beverages/sparkling-wine", "beverages/wine", "beverages/wine/red"}, paths)
+	})
+
+	t.Run("should reject invalid categories", func(t *testing.T) {
+		service := newTestService(t)
+
+		err := service.Create(&domain.Category{Name: "Año", Slug: "Año", ParentID: 99})
+		var violations validation.Errors
+		require.ErrorAs(t, err, &violations)
+		assert.Equal(t, []string{"slug", "parent_id"}, []string{violations[0].Field, violations[1].Field})
+
+		err = service.Create(&domain.Category{Name: "Wine", ParentID: 1})
+		assert.ErrorIs(t, err, ErrCategoryAlreadyExists)
+	})
+}
+
+func TestDefaultService_Update(t *testing.T) {
+	t.Run("should move a category with its descendants", func(t *testing.T) {
+		service := newTestService(t)
+
+		wine := domain.Category{ID: 2, Name: "Wines", Slug: "wines"}
+		require.NoError(t, service.Update(&wine))
+		assert.Equal(t, "wines", wine.Path)
+		red, err := service.FindById(3)
+		require.NoError(t, err)
+		assert.Equal(t, "wines/red", red.Path)
+	})
+
+	t.Run("should not move a category under its descendants", func(t *testing.T) {
+		service := newTestService(t)
+
+		err := service.Update(&domain.Category{ID: 1, Name: "Beverages", ParentID: 3})
+		var violations validation.Errors
+		require.ErrorAs(t, err, &violations)
+		assert.Equal(t, "cycle", violations[0].Code)
+	})
+
+	t.Run("should only update the expected version", func(t *testing.T) {
+		service := newTestService(t)
+
+		err := service.Update(&domain.Category{ID: 1, Name: "Drinks", Version: 2})
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+}
+
+func TestDefaultService_Assign(t *testing.T) {
+	t.Run("should filter the products of a category and its descendants", func(t *testing.T) {
+		service := newTestService(t)
+
+		product, err := service.Assign(1, 0, []int{3, 3})
+		require.NoError(t, err)
+		assert.Equal(t, []int{3}, product.Categories)
+		assert.Equal(t, []string{"beverages/wine/red"}, service.Paths(product.Categories))
+
+		ids, err := service.Resolve("beverages", false)
+		require.NoError(t, err)
+		page, err := service.Products.GetPage(products.PageQuery{Categories: ids})
+		require.NoError(t, err)
+		assert.Equal(t, 0, page.Total)
+
+		ids, err = service.Resolve("/Beverages/", true)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, ids)
+		page, err = service.Products.GetPage(products.PageQuery{Categories: ids})
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.Total)
+
+		_, err = service.Resolve("beverages/beer", true)
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+	})
+
+	t.Run("should reject unknown categories and products", func(t *testing.T) {
+		service := newTestService(t)
+
+		_, err := service.Assign(1, 0, []int{1, 99})
+		var violations validation.Errors
+		require.ErrorAs(t, err, &violations)
+		assert.Equal(t, "categories[1]", violations[0].Field)
+
+		_, err = service.Assign(99, 0, []int{1})
+		assert.ErrorIs(t, err, products.ErrProductNotFound)
+	})
+
+	t.Run("should record the categories of the version read", func(t *testing.T) {
+		service := newTestService(t)
+
+		_, err := service.Assign(1, 2, []int{3})
+		assert.ErrorIs(t, err, products.ErrVersionConflict)
+		product, err := service.By(products.Author{Actor: "ana"}).Assign(1, 1, []int{3})
+		require.NoError(t, err)
+		assert.Equal(t, 2, product.Version)
+
+		revisions, err := service.Products.Revisions(1)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, products.OperationCategorize, revisions[0].Operation)
+		assert.Equal(t, "ana", revisions[0].Actor)
+		assert.Equal(t, []int{3}, revisions[0].Snapshot.Categories)
+	})
+}
+
+func TestDefaultService_Delete(t *testing.T) {
+	t.Run("should only delete empty categories", func(t *testing.T) {
+		service := newTestService(t)
+		_, err := service.Assign(1, 0, []int{3})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, service.Delete(2), ErrCategoryNotEmpty)
+		assert.ErrorIs(t, service.Delete(3), ErrCategoryNotEmpty)
+
+		_, err = service.Assign(1, 0, nil)
+		require.NoError(t, err)
+		require.NoError(t, service.Delete(3))
+		_, err = service.FindById(3)
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+	})
+
+	t.Run("should not delete the categories of products in the trash", func(t *testing.T) {
+		service := newTestService(t)
+		_, err := service.Assign(1, 0, []int{3})
+		require.NoError(t, err)
+		require.NoError(t, service.Products.Delete(1))
+
+		assert.ErrorIs(t, service.Delete(3), ErrCategoryNotEmpty)
+	})
+}
+
+func TestFileRepository(t *testing.T) {
+	t.Run("should keep the categories and never reuse an ID", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "categories.json")
+		repository, err := NewFileRepository(path)
+		require.NoError(t, err)
+		for _, category := range []domain.Category{{Name: "Food", Slug: "food"}, {Name: "Fruit", Slug: "fruit", ParentID: 1}} {
+			require.NoError(t, repository.Create(&category))
+		}
+		require.NoError(t, repository.Delete(2))
+
+		reopened, err := NewFileRepository(path)
+		require.NoError(t, err)
+		all, err := reopened.GetAll()
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Category{{ID: 1, Name: "Food", Slug: "food", Version: 1}}, all)
+
+		category := domain.Category{Name: "Fruits", Slug: "fruits", ParentID: 1}
+		require.NoError(t, reopened.Create(&category))
+		assert.Equal(t, 3, category.ID)
+	})
+}
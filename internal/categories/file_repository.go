@@ -0,0 +1,200 @@
+package categories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// FileRepository keeps categories in memory and, with a path, saves them all
+// to a JSON file after every write. IDs are never reused, even after the
+// category with the last one is deleted.
+type FileRepository struct {
+	mu         sync.RWMutex
+	path       string
+	nextID     int
+	categories map[int]domain.Category
+}
+
+// snapshot is the content of the file of a FileRepository.
+type snapshot struct {
+	NextID     int               `json:"next_id"`
+	Categories []domain.Category `json:"categories"`
+}
+
+// NewFileRepository loads the categories saved at path, if any. An empty
+// path keeps them in memory only.
+func NewFileRepository(path string) (*FileRepository, error) {
+	repository := &FileRepository{
+		path:       path,
+		nextID:     1,
+		categories: map[int]domain.Category{},
+	}
+	if path == "" {
+		return repository, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return repository, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening categories: %w", err)
+	}
+	var saved snapshot
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("error decoding categories: %w", err)
+	}
+	for _, category := range saved.Categories {
+		repository.categories[category.ID] = category
+		if category.ID >= saved.NextID {
+			saved.NextID = category.ID + 1
+		}
+	}
+	if saved.NextID > repository.nextID {
+		repository.nextID = saved.NextID
+	}
+	return repository, nil
+}
+
+// save writes every category to a temporary file, synced and renamed over
+// the file, so that a crash leaves either the old or the new content.
+func (repository *FileRepository) save() error {
+	if repository.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(snapshot{NextID: repository.nextID, Categories: repository.sorted()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding categories: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(repository.path), filepath.Base(repository.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error writting categories: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("error writting categories: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("error syncing categories: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error writting categories: %w", err)
+	}
+	if err := os.Rename(temp.Name(), repository.path); err != nil {
+		return fmt.Errorf("error writting categories: %w", err)
+	}
+	return nil
+}
+
+func (repository *FileRepository) sorted() []domain.Category {
+	categories := make([]domain.Category, 0, len(repository.categories))
+	for _, category := range repository.categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories
+}
+
+// check verifies the parent and the slug of category against the other
+// categories. It runs under the write lock, so two concurrent writes cannot
+// both pass the service validation and break the tree.
+func (repository *FileRepository) check(category domain.Category) error {
+	for parent := category.ParentID; parent != 0; parent = repository.categories[parent].ParentID {
+		if _, ok := repository.categories[parent]; !ok || parent == category.ID {
+			return ErrInvalidParent
+		}
+	}
+	for _, sibling := range repository.categories {
+		if sibling.ID != category.ID && sibling.ParentID == category.ParentID && sibling.Slug == category.Slug {
+			return ErrCategoryAlreadyExists
+		}
+	}
+	return nil
+}
+
+func (repository *FileRepository) Create(category *domain.Category) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	category.ID = 0
+	if err := repository.check(*category); err != nil {
+		return err
+	}
+	category.ID = repository.nextID
+	category.Path = ""
+	category.Version = 1
+	repository.categories[category.ID] = *category
+	repository.nextID++
+	if err := repository.save(); err != nil {
+		delete(repository.categories, category.ID)
+		repository.nextID--
+		return err
+	}
+	return nil
+}
+
+func (repository *FileRepository) GetAll() ([]domain.Category, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+	return repository.sorted(), nil
+}
+
+func (repository *FileRepository) FindById(id int) (domain.Category, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+	category, ok := repository.categories[id]
+	if !ok {
+		return domain.Category{}, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+func (repository *FileRepository) Update(category *domain.Category) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	stored, ok := repository.categories[category.ID]
+	if !ok {
+		return ErrCategoryNotFound
+	}
+	if category.Version != 0 && category.Version != stored.Version {
+		return ErrVersionConflict
+	}
+	if err := repository.check(*category); err != nil {
+		return err
+	}
+	category.Path = ""
+	category.Version = stored.Version + 1
+	repository.categories[category.ID] = *category
+	if err := repository.save(); err != nil {
+		repository.categories[category.ID] = stored
+		return err
+	}
+	return nil
+}
+
+func (repository *FileRepository) Delete(id int) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	stored, ok := repository.categories[id]
+	if !ok {
+		return ErrCategoryNotFound
+	}
+	for _, category := range repository.categories {
+		if category.ParentID == id {
+			return ErrCategoryNotEmpty
+		}
+	}
+	delete(repository.categories, id)
+	if err := repository.save(); err != nil {
+		repository.categories[id] = stored
+		return err
+	}
+	return nil
+}
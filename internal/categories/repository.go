@@ -0,0 +1,35 @@
+// Package categories keeps the category tree of the products and the
+// assignment of products to categories.
+package categories
+
+import (
+	"errors"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+var (
+	ErrCategoryNotFound      = errors.New("category not found")
+	ErrCategoryAlreadyExists = errors.New("category already exists")
+	ErrCategoryNotEmpty      = errors.New("category has subcategories or products")
+	ErrInvalidParent         = errors.New("invalid parent category")
+	ErrVersionConflict       = errors.New("category was modified")
+)
+
+// Repository stores categories without their Path, which depends on the
+// other categories. Like products.Repository, every write increments the
+// Version, starting at 1 on Create, and updates with a non-zero Version are
+// compare-and-swaps that fail with ErrVersionConflict.
+//
+// Writes keep the tree whole: the parent of a category must exist and must
+// not be the category or one of its descendants (ErrInvalidParent), siblings
+// have different slugs (ErrCategoryAlreadyExists) and categories with
+// subcategories are not deleted (ErrCategoryNotEmpty).
+type Repository interface {
+	Create(category *domain.Category) error
+	// GetAll returns every category, sorted by ID.
+	GetAll() ([]domain.Category, error)
+	FindById(id int) (domain.Category, error)
+	Update(category *domain.Category) error
+	Delete(id int) error
+}
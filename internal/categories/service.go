@@ -0,0 +1,257 @@
+package categories
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+const (
+	MaxNameLength = 50
+	MaxSlugLength = 50
+)
+
+// slugPattern matches lowercase letters and digits, optionally split in
+// groups by hyphens, e.g. wine or sparkling-wine.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type Service interface {
+	Create(category *domain.Category) error
+	GetAll() ([]domain.Category, error)
+	FindById(id int) (domain.Category, error)
+	Update(category *domain.Category) error
+	Delete(id int) error
+	Resolve(path string, descendants bool) ([]int, error)
+	Assign(productID int, version int, categories []int) (domain.Product, error)
+	Paths(categories []int) []string
+	By(author products.Author) Service
+}
+
+type DefaultService struct {
+	Storage Repository
+	// Products are the products assigned to the categories, which record
+	// the assignments in their history.
+	Products products.Service
+}
+
+// By returns the service assigning categories as author.
+func (service DefaultService) By(author products.Author) Service {
+	service.Products = service.Products.By(author)
+	return service
+}
+
+func (service DefaultService) tree() (Tree, error) {
+	categories, err := service.Storage.GetAll()
+	if err != nil {
+		return Tree{}, err
+	}
+	return NewTree(categories), nil
+}
+
+// Create adds a category to the tree. Without a slug, the slug is made from
+// the name.
+func (service DefaultService) Create(category *domain.Category) error {
+	if err := service.validate(category); err != nil {
+		return err
+	}
+	if err := service.Storage.Create(category); err != nil {
+		return err
+	}
+	return service.setPath(category)
+}
+
+// Update renames or moves a category, which changes the paths of all its
+// descendants.
+func (service DefaultService) Update(category *domain.Category) error {
+	if _, err := service.Storage.FindById(category.ID); err != nil {
+		return err
+	}
+	if err := service.validate(category); err != nil {
+		return err
+	}
+	if err := service.Storage.Update(category); err != nil {
+		return err
+	}
+	return service.setPath(category)
+}
+
+func (service DefaultService) setPath(category *domain.Category) error {
+	tree, err := service.tree()
+	if err != nil {
+		return err
+	}
+	if stored, ok := tree.Category(category.ID); ok {
+		category.Path = stored.Path
+	}
+	return nil
+}
+
+// validate checks category against the tree, after making its slug from
+// the name when it has none.
+func (service DefaultService) validate(category *domain.Category) error {
+	tree, err := service.tree()
+	if err != nil {
+		return err
+	}
+	if category.Slug == "" {
+		category.Slug = Slugify(category.Name)
+	}
+	return validation.Validate(
+		validation.Field("name", category.Name, validation.Required[string](), validation.MaxLength(MaxNameLength)),
+		validation.Field("slug", category.Slug,
+			validation.Required[string](),
+			validation.MaxLength(MaxSlugLength),
+			validation.Pattern(slugPattern, "lowercase letters and digits, optionally separated by hyphens")),
+		validation.Field("parent_id", category.ParentID, validation.Min(0), validParent(tree, category.ID)),
+	)
+}
+
+// validParent rejects parents that are not categories of tree, and the
+// category id itself or its descendants, which would make a cycle.
+func validParent(tree Tree, id int) validation.Rule[int] {
+	return func(parent int) *validation.Violation {
+		if parent <= 0 {
+			return nil
+		}
+		if _, ok := tree.Category(parent); !ok {
+			return &validation.Violation{Code: "category", Message: "must be the ID of a category"}
+		}
+		if id != 0 && tree.IsDescendant(parent, id) {
+			return &validation.Violation{Code: "cycle", Message: "must not be the category or one of its subcategories"}
+		}
+		return nil
+	}
+}
+
+// slugReplacer folds the accented letters of Spanish names.
+var slugReplacer = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// Slugify makes a slug from a name, e.g. sparkling-wine from Sparkling Wine.
+func Slugify(name string) string {
+	var slug strings.Builder
+	separate := false
+	for _, r := range slugReplacer.Replace(strings.ToLower(name)) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if separate && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			separate = false
+			continue
+		}
+		separate = true
+	}
+	return slug.String()
+}
+
+// GetAll returns every category sorted by path.
+func (service DefaultService) GetAll() ([]domain.Category, error) {
+	tree, err := service.tree()
+	if err != nil {
+		return nil, err
+	}
+	return tree.Categories(), nil
+}
+
+func (service DefaultService) FindById(id int) (domain.Category, error) {
+	tree, err := service.tree()
+	if err != nil {
+		return domain.Category{}, err
+	}
+	category, ok := tree.Category(id)
+	if !ok {
+		return domain.Category{}, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// Delete removes a category without subcategories nor products, those in the
+// trash included, since they keep their categories when restored.
+func (service DefaultService) Delete(id int) error {
+	if _, err := service.Storage.FindById(id); err != nil {
+		return err
+	}
+	page, err := service.Products.GetPage(products.PageQuery{Limit: 1, Categories: []int{id}})
+	if err != nil {
+		return err
+	}
+	if page.Total > 0 {
+		return ErrCategoryNotEmpty
+	}
+	trashed, err := service.Products.Trash()
+	if err != nil {
+		return err
+	}
+	for _, product := range trashed {
+		for _, category := range product.Categories {
+			if category == id {
+				return ErrCategoryNotEmpty
+			}
+		}
+	}
+	return service.Storage.Delete(id)
+}
+
+// Resolve returns the ID of the category at path and, with descendants, the
+// IDs of all its descendants after it.
+func (service DefaultService) Resolve(path string, descendants bool) ([]int, error) {
+	tree, err := service.tree()
+	if err != nil {
+		return nil, err
+	}
+	category, ok := tree.Find(path)
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	if descendants {
+		return tree.Descendants(category.ID), nil
+	}
+	return []int{category.ID}, nil
+}
+
+// Assign sets the categories of a product, replacing the ones it had. With a
+// version, the product must still have it.
+func (service DefaultService) Assign(productID int, version int, categories []int) (domain.Product, error) {
+	tree, err := service.tree()
+	if err != nil {
+		return domain.Product{}, err
+	}
+	var violations validation.Errors
+	seen := map[int]bool{}
+	ids := []int{}
+	for i, id := range categories {
+		if _, ok := tree.Category(id); !ok {
+			violations = append(violations, validation.Violation{Field: fmt.Sprintf("categories[%d]", i), Code: "category", Message: "must be the ID of a category"})
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(violations) > 0 {
+		return domain.Product{}, violations
+	}
+	sort.Ints(ids)
+	return service.Products.SetCategories(productID, version, ids)
+}
+
+// Paths returns the paths of the categories, skipping the unknown ones.
+func (service DefaultService) Paths(categories []int) []string {
+	tree, err := service.tree()
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, id := range categories {
+		if category, ok := tree.Category(id); ok {
+			paths = append(paths, category.Path)
+		}
+	}
+	return paths
+}
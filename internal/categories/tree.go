@@ -0,0 +1,106 @@
+package categories
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// Tree indexes categories by ID and by path, with the Path of every category
+// set from the slugs of its ancestors.
+type Tree struct {
+	categories map[int]domain.Category
+	children   map[int][]int
+	paths      map[string]int
+}
+
+func NewTree(categories []domain.Category) Tree {
+	tree := Tree{
+		categories: make(map[int]domain.Category, len(categories)),
+		children:   map[int][]int{},
+		paths:      make(map[string]int, len(categories)),
+	}
+	for _, category := range categories {
+		tree.categories[category.ID] = category
+		tree.children[category.ParentID] = append(tree.children[category.ParentID], category.ID)
+	}
+	tree.setPaths(0, "")
+	return tree
+}
+
+// setPaths sets the path of the descendants of the category id at path.
+// Categories that cannot be reached from the root keep an empty path.
+func (tree Tree) setPaths(id int, path string) {
+	for _, child := range tree.children[id] {
+		category := tree.categories[child]
+		category.Path = category.Slug
+		if path != "" {
+			category.Path = path + "/" + category.Slug
+		}
+		tree.categories[child] = category
+		tree.paths[category.Path] = child
+		tree.setPaths(child, category.Path)
+	}
+}
+
+func (tree Tree) Category(id int) (domain.Category, bool) {
+	category, ok := tree.categories[id]
+	return category, ok
+}
+
+// Find returns the category at path, e.g. beverages/wine.
+func (tree Tree) Find(path string) (domain.Category, bool) {
+	id, ok := tree.paths[strings.ToLower(strings.Trim(path, "/"))]
+	if !ok {
+		return domain.Category{}, false
+	}
+	return tree.categories[id], true
+}
+
+// Categories returns every category sorted by path, so that each one comes
+// right after its parent and its siblings.
+func (tree Tree) Categories() []domain.Category {
+	categories := make([]domain.Category, 0, len(tree.categories))
+	for _, category := range tree.categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return lessPath(categories[i].Path, categories[j].Path)
+	})
+	return categories
+}
+
+// lessPath orders paths segment by segment, so that beverages/wine comes
+// before beverages-alcohol-free.
+func lessPath(a, b string) bool {
+	aSegments, bSegments := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		if aSegments[i] != bSegments[i] {
+			return aSegments[i] < bSegments[i]
+		}
+	}
+	return len(aSegments) < len(bSegments)
+}
+
+// Descendants returns the IDs of the category id and of all its
+// descendants, id first.
+func (tree Tree) Descendants(id int) []int {
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, tree.children[ids[i]]...)
+	}
+	return ids
+}
+
+// IsDescendant reports whether the category id is ancestor or one of its
+// descendants.
+func (tree Tree) IsDescendant(id, ancestor int) bool {
+	for seen := 0; id != 0 && seen <= len(tree.categories); seen++ {
+		if id == ancestor {
+			return true
+		}
+		id = tree.categories[id].ParentID
+	}
+	return false
+}
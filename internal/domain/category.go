@@ -0,0 +1,14 @@
+package domain
+
+// Category is a node of the category tree. Its Path is made of the slugs of
+// its ancestors and its own, e.g. beverages/wine, and changes when the
+// category or an ancestor is renamed or moved.
+type Category struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" example:"Wine"`
+	Slug string `json:"slug" example:"wine"`
+	// ParentID is 0 for the categories at the root of the tree.
+	ParentID int    `json:"parent_id,omitempty"`
+	Path     string `json:"path" example:"beverages/wine"`
+	Version  int    `json:"version"`
+}
@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Date layouts accepted on input. Day first is the historical format of the
+// API, so two-digit days and months separated by slashes are DD/MM.
+const (
+	DateLayoutDMY     = "02/01/2006"
+	DateLayoutISO     = "2006-01-02"
+	DateLayoutRFC3339 = time.RFC3339
+)
+
+var (
+	ErrInvalidDate   = errors.New("invalid date")
+	ErrAmbiguousDate = errors.New("ambiguous date")
+)
+
+// dateLayout is the canonical layout dates are formatted with.
+var dateLayout atomic.Value
+
+func init() {
+	dateLayout.Store(DateLayoutDMY)
+}
+
+// SetDateLayout changes the layout Date.String and JSON use, one of the
+// DateLayout constants. It is meant to be called once at start up.
+func SetDateLayout(layout string) error {
+	switch layout {
+	case DateLayoutDMY, DateLayoutISO, DateLayoutRFC3339:
+		dateLayout.Store(layout)
+		return nil
+	default:
+		return fmt.Errorf("unknown date layout %q", layout)
+	}
+}
+
+// ParseDateLayout maps the names of the canonical formats, as configured, to
+// their layout: "dmy" (DD/MM/YYYY), "iso" (YYYY-MM-DD) or "rfc3339".
+func ParseDateLayout(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", "dmy":
+		return DateLayoutDMY, nil
+	case "iso":
+		return DateLayoutISO, nil
+	case "rfc3339":
+		return DateLayoutRFC3339, nil
+	default:
+		return "", fmt.Errorf("unknown date format %q", name)
+	}
+}
+
+// Date is a calendar day without a time of day nor a time zone. The zero
+// Date is no date, and Dates compare with ==.
+type Date struct {
+	t time.Time
+}
+
+// NewDate returns the date of year, month and day, normalizing values out of
+// range the way time.Date does.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{t: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the calendar day of t in its own location.
+func DateOf(t time.Time) Date {
+	return NewDate(t.Year(), t.Month(), t.Day())
+}
+
+var (
+	// acceptedDate matches the accepted numeric layouts, so that inputs in
+	// them that do not parse are out of range rather than ambiguous.
+	acceptedDate = regexp.MustCompile(`^(\d{2}/\d{2}/\d{4}|\d{4}-\d{2}-\d{2})$`)
+	// ambiguousDate matches inputs that look like dates in an order or
+	// precision that could be read several ways, e.g. 3/4/22 or 04-03-2022.
+	ambiguousDate = regexp.MustCompile(`^\d{1,4}[-/.]\d{1,2}[-/.]\d{1,4}$`)
+)
+
+// ParseDate reads DD/MM/YYYY, YYYY-MM-DD and RFC 3339 timestamps, whose day
+// is the one in their own offset. Timestamps without an offset and dates in
+// any other numeric order or with two-digit years are rejected with
+// ErrAmbiguousDate rather than guessed.
+func ParseDate(value string) (Date, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{DateLayoutDMY, DateLayoutISO, DateLayoutRFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return DateOf(t), nil
+		}
+	}
+	if _, err := time.Parse("2006-01-02T15:04:05", value); err == nil {
+		return Date{}, fmt.Errorf("%w: %q has no time zone offset", ErrAmbiguousDate, value)
+	}
+	if ambiguousDate.MatchString(value) && !acceptedDate.MatchString(value) {
+		return Date{}, fmt.Errorf("%w: %q, use DD/MM/YYYY or YYYY-MM-DD", ErrAmbiguousDate, value)
+	}
+	return Date{}, fmt.Errorf("%w: %q, use DD/MM/YYYY, YYYY-MM-DD or RFC 3339", ErrInvalidDate, value)
+}
+
+func (date Date) IsZero() bool {
+	return date.t.IsZero()
+}
+
+// Time returns the start of the day in UTC.
+func (date Date) Time() time.Time {
+	return date.t
+}
+
+func (date Date) Before(other Date) bool {
+	return date.t.Before(other.t)
+}
+
+func (date Date) After(other Date) bool {
+	return date.t.After(other.t)
+}
+
+// Format formats the date with a time layout; the zero Date is "".
+func (date Date) Format(layout string) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.t.Format(layout)
+}
+
+// ISO formats the date as YYYY-MM-DD, which sorts in date order.
+func (date Date) ISO() string {
+	return date.Format(DateLayoutISO)
+}
+
+// String formats the date in the canonical layout.
+func (date Date) String() string {
+	return date.Format(dateLayout.Load().(string))
+}
+
+func (date Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(date.String())
+}
+
+// UnmarshalJSON accepts every format ParseDate does. Empty strings and null
+// are the zero Date.
+func (date *Date) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: %s is not a string", ErrInvalidDate, data)
+	}
+	if value == nil || *value == "" {
+		*date = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(*value)
+	if err != nil {
+		return err
+	}
+	*date = parsed
+	return nil
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDate(t *testing.T) {
+	t.Run("should read every accepted format", func(t *testing.T) {
+		cases := map[string]Date{
+			"28/01/2022":                NewDate(2022, time.January, 28),
+			"2022-01-28":                NewDate(2022, time.January, 28),
+			"2022-01-28T23:30:00-03:00": NewDate(2022, time.January, 28),
+			"2022-01-28T01:30:00+09:00": NewDate(2022, time.January, 28),
+		}
+		for value, expected := range cases {
+			date, err := ParseDate(value)
+			require.NoError(t, err, value)
+			assert.Equal(t, expected, date, value)
+		}
+	})
+
+	t.Run("should reject ambiguous and invalid dates", func(t *testing.T) {
+		cases := map[string]error{
+			"03/04/22":            ErrAmbiguousDate,
+			"3/4/2022":            ErrAmbiguousDate,
+			"04-03-2022":          ErrAmbiguousDate,
+			"2022/04/03":          ErrAmbiguousDate,
+			"2022-04-03T10:00:00": ErrAmbiguousDate,
+			"31/02/2022":          ErrInvalidDate,
+			"2022-13-01":          ErrInvalidDate,
+			"next week":           ErrInvalidDate,
+		}
+		for value, expected := range cases {
+			_, err := ParseDate(value)
+			assert.ErrorIs(t, err, expected, value)
+		}
+	})
+}
+
+func TestDate_JSON(t *testing.T) {
+	t.Cleanup(func() { SetDateLayout(DateLayoutDMY) })
+	date := NewDate(2022, time.January, 28)
+
+	for layout, expected := range map[string]string{
+		DateLayoutDMY:     `"28/01/2022"`,
+		DateLayoutISO:     `"2022-01-28"`,
+		DateLayoutRFC3339: `"2022-01-28T00:00:00Z"`,
+	} {
+		require.NoError(t, SetDateLayout(layout))
+		data, err := json.Marshal(date)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+
+		var decoded Date
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, date, decoded, "round trip in %s", layout)
+	}
+
+	var empty Date
+	require.NoError(t, json.Unmarshal([]byte(`null`), &empty))
+	assert.True(t, empty.IsZero())
+	data, _ := json.Marshal(empty)
+	assert.Equal(t, `""`, string(data))
+	assert.ErrorIs(t, json.Unmarshal([]byte(`20220128`), &empty), ErrInvalidDate)
+	assert.Error(t, SetDateLayout("Jan 2"))
+}
@@ -0,0 +1,89 @@
+package domain
+
+import "time"
+
+// MovementKind is why the stock of a product changed.
+type MovementKind string
+
+const (
+	MovementReceipt    MovementKind = "receipt"
+	MovementSale       MovementKind = "sale"
+	MovementAdjustment MovementKind = "adjustment"
+	MovementReturn     MovementKind = "return"
+)
+
+// Movement is an entry of the inventory ledger. Quantity is the change of
+// the stock on hand, negative for sales, and Balance the stock on hand after
+// the movement.
+type Movement struct {
+	ID            int          `json:"id" example:"12"`
+	ProductID     int          `json:"product_id" example:"2"`
+	Kind          MovementKind `json:"kind" enums:"receipt,sale,adjustment,return" example:"sale"`
+	Quantity      int          `json:"qty" example:"-3"`
+	Balance       int          `json:"balance" example:"342"`
+	Reason        string       `json:"reason,omitempty" example:"damaged in transit"`
+	ReservationID string       `json:"reservation_id,omitempty" example:"01H8XGJWBWBAQ4Z4J1TYXKRRFD"`
+	// Lot is the lot the movement is asked to change. Without one, receipts
+	// and returns add stock outside lots and the units removed are taken
+	// first-expired-first-out.
+	Lot string `json:"lot,omitempty" example:"L2022-014"`
+	// Lots are the units the movement changed in each lot, none when it only
+	// changed stock outside lots.
+	Lots      []LotQuantity `json:"lots,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// Lot is stock of a product received together, e.g. a delivery of a
+// supplier, that expires on its own date. Quantity is what is left of it.
+type Lot struct {
+	ProductID  int       `json:"product_id" example:"2"`
+	Code       string    `json:"code" example:"L2022-014"`
+	Quantity   int       `json:"qty" example:"24"`
+	Expiration Date      `json:"expiration" swaggertype:"string" example:"28/01/2022"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// LotQuantity is the change of the stock of a lot by a movement.
+type LotQuantity struct {
+	Lot      string `json:"lot" example:"L2022-014"`
+	Quantity int    `json:"qty" example:"-3"`
+}
+
+// ReservationStatus is the state of a Reservation. Only active reservations
+// hold stock, and they stop holding it once they expire.
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds stock of some products until it expires, is committed as
+// sales or is released.
+type Reservation struct {
+	ID        string            `json:"id" example:"01H8XGJWBWBAQ4Z4J1TYXKRRFD"`
+	Items     []QuoteItem       `json:"items"`
+	Status    ReservationStatus `json:"status" enums:"active,committed,released,expired" example:"active"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// StatusAt is the status of the reservation at now, expired for an active
+// reservation past its expiration.
+func (reservation Reservation) StatusAt(now time.Time) ReservationStatus {
+	if reservation.Status == ReservationActive && !now.Before(reservation.ExpiresAt) {
+		return ReservationExpired
+	}
+	return reservation.Status
+}
+
+// Stock is the stock of a product: OnHand is its Quantity, Reserved the units
+// held by active reservations and Available what can still be reserved.
+type Stock struct {
+	ProductID int `json:"product_id" example:"2"`
+	OnHand    int `json:"on_hand" example:"345"`
+	Reserved  int `json:"reserved" example:"3"`
+	Available int `json:"available" example:"342"`
+}
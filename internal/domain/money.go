@@ -0,0 +1,333 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+// minorUnits are the decimal places of the supported currencies.
+var minorUnits = map[Currency]int{
+	"ARS": 2, "BRL": 2, "CLP": 0, "COP": 2, "EUR": 2, "GBP": 2,
+	"JPY": 0, "KWD": 3, "MXN": 2, "PEN": 2, "USD": 2, "UYU": 2,
+}
+
+// ParseCurrency checks code is a supported currency, ignoring case.
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := minorUnits[currency]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return currency, nil
+}
+
+// Exponent is the number of decimal places of the currency.
+func (currency Currency) Exponent() int {
+	exponent, ok := minorUnits[currency]
+	if !ok {
+		return 2
+	}
+	return exponent
+}
+
+var defaultCurrency atomic.Value
+
+// MoneyEncoding tells how amounts are written to JSON.
+type MoneyEncoding int32
+
+const (
+	// MoneyAsNumber writes amounts as numbers with the decimal places of
+	// their currency, e.g. 275.40.
+	MoneyAsNumber MoneyEncoding = iota
+	// MoneyAsString writes amounts as strings, e.g. "275.40", for clients
+	// that would read numbers as floats.
+	MoneyAsString
+)
+
+var moneyEncoding atomic.Int32
+
+func init() {
+	defaultCurrency.Store(Currency("ARS"))
+}
+
+// DefaultCurrency is the currency of amounts given without one.
+func DefaultCurrency() Currency {
+	return defaultCurrency.Load().(Currency)
+}
+
+// SetDefaultCurrency changes DefaultCurrency. Like SetMoneyEncoding, it is
+// meant to be called once at start up.
+func SetDefaultCurrency(code string) error {
+	currency, err := ParseCurrency(code)
+	if err != nil {
+		return err
+	}
+	defaultCurrency.Store(currency)
+	return nil
+}
+
+func SetMoneyEncoding(encoding MoneyEncoding) {
+	moneyEncoding.Store(int32(encoding))
+}
+
+// ParseMoneyEncoding maps "number" (default) or "string" to an encoding.
+func ParseMoneyEncoding(name string) (MoneyEncoding, error) {
+	switch strings.ToLower(name) {
+	case "", "number":
+		return MoneyAsNumber, nil
+	case "string":
+		return MoneyAsString, nil
+	default:
+		return 0, fmt.Errorf("unknown money encoding %q", name)
+	}
+}
+
+// RoundingMode decides which way amounts halfway between two minor units go.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero: 0.125 is 0.13.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven, or banker's rounding, rounds halves to the even minor
+	// unit: 0.125 is 0.12 and 0.135 is 0.14.
+	RoundHalfEven
+)
+
+// ParseRoundingMode maps "half_up" (default) or "half_even" to a mode.
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	switch strings.ToLower(name) {
+	case "", "half_up":
+		return RoundHalfUp, nil
+	case "half_even", "bankers":
+		return RoundHalfEven, nil
+	default:
+		return 0, fmt.Errorf("unknown rounding mode %q", name)
+	}
+}
+
+// Money is an exact amount of a currency, counted in its minor units, e.g.
+// cents. The zero Money has no currency.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney reads a decimal amount, e.g. 275.4 or -3, of currency. It
+// fails with ErrInvalidAmount when the amount has more decimal places than
+// the currency, instead of rounding it.
+func ParseMoney(value string, currency Currency) (Money, error) {
+	value = strings.TrimSpace(value)
+	exponent := currency.Exponent()
+	negative := strings.HasPrefix(value, "-")
+	digits := strings.TrimPrefix(value, "-")
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) || strings.HasSuffix(digits, ".") {
+		return Money{}, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, value)
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, value, exponent)
+	}
+	amount, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (money Money) IsZero() bool {
+	return money.Amount == 0
+}
+
+// Add returns the sum of two amounts of the same currency. A zero Money
+// takes the currency of the other amount. It fails with ErrInvalidAmount when
+// the sum is out of range.
+func (money Money) Add(other Money) (Money, error) {
+	switch {
+	case money.Currency == "" && money.Amount == 0:
+		return other, nil
+	case other.Currency == "" && other.Amount == 0:
+		return money, nil
+	case money.Currency != other.Currency:
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, money.Currency, other.Currency)
+	}
+	sum := money.Amount + other.Amount
+	if other.Amount > 0 && sum < money.Amount || other.Amount < 0 && sum > money.Amount {
+		return Money{}, fmt.Errorf("%w: %s plus %s is out of range", ErrInvalidAmount, money, other)
+	}
+	return Money{Amount: sum, Currency: money.Currency}, nil
+}
+
+// Mul returns the amount times rate, rounded to a minor unit with mode. It
+// fails with ErrInvalidAmount when the result is out of range.
+func (money Money) Mul(rate Rate, mode RoundingMode) (Money, error) {
+	numerator := new(big.Int).Mul(big.NewInt(money.Amount), big.NewInt(rate.numerator))
+	quotient := divide(numerator, big.NewInt(rate.denominator), mode)
+	if !quotient.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s times %d/%d is out of range", ErrInvalidAmount, money, rate.numerator, rate.denominator)
+	}
+	return Money{Amount: quotient.Int64(), Currency: money.Currency}, nil
+}
+
+// divide rounds numerator / denominator, with a positive denominator.
+func divide(numerator, denominator *big.Int, mode RoundingMode) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	// Compare twice the remainder with the denominator to find halves.
+	half := new(big.Int).Abs(remainder)
+	half.Mul(half, big.NewInt(2))
+	comparison := half.Cmp(denominator)
+	if comparison > 0 || comparison == 0 && (mode == RoundHalfUp || quotient.Bit(0) == 1) {
+		if numerator.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient
+}
+
+// Float is the amount in major units, only meant to compare and rank
+// amounts, never to compute with them.
+func (money Money) Float() float64 {
+	value, _ := strconv.ParseFloat(money.String(), 64)
+	return value
+}
+
+// String formats the amount with the decimal places of its currency, e.g.
+// 275.40, without the currency.
+func (money Money) String() string {
+	exponent := money.Currency.Exponent()
+	amount := money.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// MarshalJSON writes the amount only; types holding Money write its
+// currency next to it.
+func (money Money) MarshalJSON() ([]byte, error) {
+	if MoneyEncoding(moneyEncoding.Load()) == MoneyAsString {
+		return json.Marshal(money.String())
+	}
+	return []byte(money.String()), nil
+}
+
+// UnmarshalJSON reads a number or a string amount of the DefaultCurrency.
+func (money *Money) UnmarshalJSON(data []byte) error {
+	parsed, err := DecodeMoney(data, DefaultCurrency())
+	if err != nil {
+		return err
+	}
+	*money = parsed
+	return nil
+}
+
+// DecodeMoney reads a JSON number or string amount of currency, without
+// going through float64. null is the zero Money.
+func DecodeMoney(data json.RawMessage, currency Currency) (Money, error) {
+	text := strings.TrimSpace(string(data))
+	if text == "" || text == "null" {
+		return Money{}, nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return Money{}, fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+		}
+	}
+	return ParseMoney(text, currency)
+}
+
+// Rate is an exact decimal factor to multiply amounts by, e.g. 1.21.
+type Rate struct {
+	numerator   int64
+	denominator int64
+}
+
+// NewRate returns numerator/denominator, e.g. NewRate(21, 100) for 21%.
+func NewRate(numerator, denominator int64) Rate {
+	if denominator < 0 {
+		numerator, denominator = -numerator, -denominator
+	}
+	return Rate{numerator: numerator, denominator: denominator}
+}
+
+// ParseRate reads a decimal factor, e.g. 1.21 or 0.9.
+func ParseRate(value string) (Rate, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(value), ".")
+	if len(fraction) > 9 || !isDigits(fraction) {
+		return Rate{}, fmt.Errorf("invalid rate %q", value)
+	}
+	numerator, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid rate %q", value)
+	}
+	denominator := int64(1)
+	for range fraction {
+		denominator *= 10
+	}
+	return NewRate(numerator, denominator), nil
+}
+
+// ParsePercent reads a decimal percentage, e.g. 21 or 10.5, as the rate
+// 0.21 or 0.105.
+func ParsePercent(value string) (Rate, error) {
+	rate, err := ParseRate(value)
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid percent %q", value)
+	}
+	rate.denominator *= 100
+	return rate, nil
+}
+
+// Sign is -1, 0 or 1 as the rate is negative, zero or positive.
+func (rate Rate) Sign() int {
+	switch {
+	case rate.numerator < 0:
+		return -1
+	case rate.numerator > 0:
+		return 1
+	}
+	return 0
+}
+
+func (rate Rate) String() string {
+	return new(big.Rat).SetFrac64(rate.numerator, rate.denominator).FloatString(4)
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := map[string]Money{
+		"275.47": NewMoney(27547, "ARS"),
+		"275.4":  NewMoney(27540, "ARS"),
+		"275.40": NewMoney(27540, "ARS"),
+		"-0.05":  NewMoney(-5, "ARS"),
+		"3":      NewMoney(300, "ARS"),
+	}
+	for value, expected := range cases {
+		money, err := ParseMoney(value, "ARS")
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, money, value)
+	}
+
+	jpy, err := ParseMoney("1500", "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, "1500", jpy.String())
+
+	for _, value := range []string{"275.475", "1.5e2", "", ".5", "5.", "abc", "99999999999999999999"} {
+		_, err := ParseMoney(value, "ARS")
+		assert.ErrorIs(t, err, ErrInvalidAmount, value)
+	}
+	_, err = ParseMoney("1.5", "JPY")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "275.40", NewMoney(27540, "ARS").String())
+	assert.Equal(t, "0.05", NewMoney(5, "USD").String())
+	assert.Equal(t, "-0.05", NewMoney(-5, "USD").String())
+	assert.Equal(t, "1.250", NewMoney(1250, "KWD").String())
+}
+
+// mul returns money times rate, failing the test when it cannot.
+func mul(t *testing.T, money Money, rate Rate, mode RoundingMode) Money {
+	t.Helper()
+	product, err := money.Mul(rate, mode)
+	require.NoError(t, err)
+	return product
+}
+
+func TestMoney_Mul(t *testing.T) {
+	t.Run("should not accumulate float errors", func(t *testing.T) {
+		// 352.79 * 1.21 is 426.87589999999994 in float64.
+		assert.Equal(t, NewMoney(42688, "ARS"), mul(t, NewMoney(35279, "ARS"), NewRate(121, 100), RoundHalfUp))
+	})
+
+	t.Run("should round halves by mode", func(t *testing.T) {
+		half := NewRate(1, 2)
+		cases := []struct {
+			amount           int64
+			halfUp, halfEven int64
+		}{
+			{25, 13, 12},
+			{27, 14, 14},
+			{-25, -13, -12},
+			{-27, -14, -14},
+			{3, 2, 2},
+			{1, 1, 0},
+		}
+		for _, c := range cases {
+			money := NewMoney(c.amount, "ARS")
+			assert.Equal(t, c.halfUp, mul(t, money, half, RoundHalfUp).Amount, "half up %d", c.amount)
+			assert.Equal(t, c.halfEven, mul(t, money, half, RoundHalfEven).Amount, "half even %d", c.amount)
+		}
+	})
+
+	rate, err := ParseRate("0.85")
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(8500, "ARS"), mul(t, NewMoney(10000, "ARS"), rate, RoundHalfUp))
+	_, err = ParseRate("1.2.1")
+	assert.Error(t, err)
+
+	t.Run("should fail when the result is out of range", func(t *testing.T) {
+		_, err := NewMoney(math.MaxInt64, "ARS").Mul(NewRate(121, 100), RoundHalfUp)
+		assert.ErrorIs(t, err, ErrInvalidAmount)
+		_, err = NewMoney(math.MinInt64, "ARS").Mul(NewRate(-1, 1), RoundHalfUp)
+		assert.ErrorIs(t, err, ErrInvalidAmount)
+	})
+}
+
+func TestMoney_Add(t *testing.T) {
+	sum, err := Money{}.Add(NewMoney(100, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(100, "USD"), sum)
+
+	sum, err = sum.Add(NewMoney(5, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(105, "USD"), sum)
+
+	_, err = sum.Add(NewMoney(5, "ARS"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = NewMoney(math.MaxInt64, "USD").Add(NewMoney(1, "USD"))
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	_, err = NewMoney(math.MinInt64, "USD").Add(NewMoney(-1, "USD"))
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestProduct_JSON(t *testing.T) {
+	t.Cleanup(func() { SetMoneyEncoding(MoneyAsNumber) })
+	product := Product{ID: 1, Name: "Wine", Price: NewMoney(27540, "USD")}
+
+	data, err := json.Marshal(product)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"price":275.40,"currency":"USD"`)
+
+	SetMoneyEncoding(MoneyAsString)
+	data, err = json.Marshal(product)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"price":"275.40"`)
+
+	var decoded Product
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, product, decoded)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"price": 352.79}`), &decoded))
+	assert.Equal(t, NewMoney(35279, DefaultCurrency()), decoded.Price, "products saved before currencies")
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"price": 1.5, "currency": "JPY"}`), &decoded), ErrInvalidAmount)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"price": 1, "currency": "XXX"}`), &decoded), ErrUnknownCurrency)
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CartStatus is open while lines can be changed, checked out once the cart
+// became an order.
+type CartStatus string
+
+const (
+	CartOpen       CartStatus = "open"
+	CartCheckedOut CartStatus = "checked_out"
+)
+
+type Cart struct {
+	ID        string      `json:"id" example:"01H8XGJWBWBAQ4Z4J1TYXKRRFD"`
+	Items     []QuoteItem `json:"items"`
+	Status    CartStatus  `json:"status" enums:"open,checked_out" example:"open"`
+	OrderID   string      `json:"order_id,omitempty"`
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// PricedCart is a cart with the quote of its items at current prices.
+type PricedCart struct {
+	Cart
+	Quote Quote `json:"quote"`
+}
+
+// OrderStatus is the state of an Order, see CanTransition.
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// orderTransitions are the statuses each status can change to. Shipped and
+// cancelled orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+}
+
+// CanTransition reports whether an order can go from status from to to.
+func CanTransition(from, to OrderStatus) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderEvent is a status an order went through.
+type OrderEvent struct {
+	Status OrderStatus `json:"status" example:"paid"`
+	At     time.Time   `json:"at"`
+}
+
+// Order is a checked out cart. Its lines and totals are the prices at
+// checkout and never change, only its status does.
+type Order struct {
+	ID            string       `json:"id" example:"01H8XGKA2JY1N6V1JPSZCB5ZBM"`
+	CartID        string       `json:"cart_id" example:"01H8XGJWBWBAQ4Z4J1TYXKRRFD"`
+	ReservationID string       `json:"reservation_id" example:"01H8XGK9ZC0QK3M2R9X3T1R7QX"`
+	Lines         []PriceLine  `json:"lines"`
+	Subtotal      Money        `json:"subtotal" swaggertype:"number" example:"567.05"`
+	Surcharges    Money        `json:"surcharges" swaggertype:"number" example:"119.08"`
+	TotalPrice    Money        `json:"total_price" swaggertype:"number" example:"686.13"`
+	Status        OrderStatus  `json:"status" enums:"pending,paid,shipped,cancelled" example:"pending"`
+	History       []OrderEvent `json:"history"`
+	Version       int          `json:"version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+func (order Order) MarshalJSON() ([]byte, error) {
+	type fields Order
+	currency := order.TotalPrice.Currency
+	if currency == "" {
+		currency = DefaultCurrency()
+	}
+	return json.Marshal(struct {
+		fields
+		Currency Currency `json:"currency"`
+	}{fields(order), currency})
+}
@@ -1,11 +1,87 @@
 package domain
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Product struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Quantity    int     `json:"quantity"`
-	CodeValue   string  `json:"code_value"`
-	IsPublished bool    `json:"is_published"`
-	Expiration  string  `json:"expiration"`
-	Price       float64 `json:"price"`
+	ID          int    `json:"id"`
+	UID         string `json:"uid,omitempty"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity"`
+	CodeValue   string `json:"code_value"`
+	IsPublished bool   `json:"is_published"`
+	Expiration  Date   `json:"expiration" swaggertype:"string" example:"28/01/2022"`
+	Price       Money  `json:"price" swaggertype:"number" example:"275.47"`
+	// Categories are the IDs of the categories the product is in, sorted.
+	Categories []int `json:"categories,omitempty"`
+	// ParentID is the product a variant, or SKU, belongs to, 0 for the
+	// products that are not variants.
+	ParentID int `json:"parent_id,omitempty"`
+	// Attributes tell a variant apart from the other variants of its
+	// parent, e.g. {"size": "750ml"}.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Version    int               `json:"version"`
+	// DeletedAt is when the product was moved to the trash, nil for the
+	// products out of it, and DeletedBy who moved it there.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+}
+
+// Attributes of variants.
+const (
+	AttributeSize    = "size"
+	AttributeColor   = "color"
+	AttributeFlavour = "flavour"
+)
+
+// VariantAttributes lists the attributes of variants, in the order they are
+// written in the names of variants.
+var VariantAttributes = []string{AttributeSize, AttributeColor, AttributeFlavour}
+
+// productFields is Product without its JSON methods.
+type productFields Product
+
+// productJSON writes the currency of the price next to it.
+type productJSON struct {
+	productFields
+	Price    json.RawMessage `json:"price"`
+	Currency Currency        `json:"currency"`
+}
+
+func (product Product) MarshalJSON() ([]byte, error) {
+	price, err := json.Marshal(product.Price)
+	if err != nil {
+		return nil, err
+	}
+	currency := product.Price.Currency
+	if currency == "" {
+		currency = DefaultCurrency()
+	}
+	return json.Marshal(productJSON{productFields: productFields(product), Price: price, Currency: currency})
+}
+
+// UnmarshalJSON reads the price in the currency field, the DefaultCurrency
+// when missing, as in products saved before prices had a currency.
+func (product *Product) UnmarshalJSON(data []byte) error {
+	var decoded productJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	currency := DefaultCurrency()
+	if decoded.Currency != "" {
+		var err error
+		if currency, err = ParseCurrency(string(decoded.Currency)); err != nil {
+			return err
+		}
+	}
+	price, err := DecodeMoney(decoded.Price, currency)
+	if err != nil {
+		return err
+	}
+	*product = Product(decoded.productFields)
+	product.Price = price
+	product.Price.Currency = currency
+	return nil
 }
@@ -1,6 +1,43 @@
 package domain
 
+import "encoding/json"
+
 type ProductsConsumer struct {
-	Products   []Product `json:"products"`
-	TotalPrice float64   `json:"total_price"`
+	Products   []Product   `json:"products"`
+	Lines      []PriceLine `json:"lines"`
+	Subtotal   Money       `json:"subtotal" swaggertype:"number" example:"352.79"`
+	TotalPrice Money       `json:"total_price" swaggertype:"number" example:"426.88"`
+}
+
+func (consumer ProductsConsumer) MarshalJSON() ([]byte, error) {
+	type fields ProductsConsumer
+	currency := consumer.TotalPrice.Currency
+	if currency == "" {
+		currency = DefaultCurrency()
+	}
+	return json.Marshal(struct {
+		fields
+		Currency Currency `json:"currency"`
+	}{fields(consumer), currency})
+}
+
+// PriceLine is the price of some units of a product and the adjustments,
+// like discounts or taxes, that made its total.
+type PriceLine struct {
+	ProductID   int               `json:"product_id"`
+	Name        string            `json:"name"`
+	Quantity    int               `json:"quantity"`
+	UnitPrice   Money             `json:"unit_price" swaggertype:"number"`
+	Subtotal    Money             `json:"subtotal" swaggertype:"number"`
+	Adjustments []PriceAdjustment `json:"adjustments"`
+	Total       Money             `json:"total" swaggertype:"number"`
+}
+
+// PriceAdjustment is the amount a pricing rule added to a line, negative for
+// discounts.
+type PriceAdjustment struct {
+	Rule    string `json:"rule"`
+	Kind    string `json:"kind"`
+	Percent string `json:"percent"`
+	Amount  Money  `json:"amount" swaggertype:"number"`
 }
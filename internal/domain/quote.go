@@ -0,0 +1,47 @@
+package domain
+
+import "encoding/json"
+
+// Reasons a quoted item cannot be sold.
+const (
+	UnavailableNotFound          = "not_found"
+	UnavailableUnpublished       = "unpublished"
+	UnavailableInsufficientStock = "insufficient_stock"
+)
+
+// QuoteItem is a number of units of a product to quote.
+type QuoteItem struct {
+	ID       int `json:"id" example:"2"`
+	Quantity int `json:"qty" example:"3"`
+}
+
+// UnavailableItem is a quoted item left out of the quote, with the stock
+// there was for it.
+type UnavailableItem struct {
+	ID        int    `json:"id" example:"2"`
+	Quantity  int    `json:"qty" example:"3"`
+	Available int    `json:"available" example:"1"`
+	Reason    string `json:"reason" enums:"not_found,unpublished,insufficient_stock" example:"insufficient_stock"`
+}
+
+// Quote is the price of the available items of an order. Surcharges are the
+// adjustments of all the lines together, net of discounts.
+type Quote struct {
+	Lines       []PriceLine       `json:"lines"`
+	Unavailable []UnavailableItem `json:"unavailable"`
+	Subtotal    Money             `json:"subtotal" swaggertype:"number" example:"1058.37"`
+	Surcharges  Money             `json:"surcharges" swaggertype:"number" example:"222.26"`
+	TotalPrice  Money             `json:"total_price" swaggertype:"number" example:"1280.63"`
+}
+
+func (quote Quote) MarshalJSON() ([]byte, error) {
+	type fields Quote
+	currency := quote.TotalPrice.Currency
+	if currency == "" {
+		currency = DefaultCurrency()
+	}
+	return json.Marshal(struct {
+		fields
+		Currency Currency `json:"currency"`
+	}{fields(quote), currency})
+}
@@ -0,0 +1,399 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestService returns a service over a product with 10 units and an
+// unpublished one with 5, on a clock that only moves when now is changed.
+func newTestService(t *testing.T, ledgerPath string) (DefaultService, *time.Time) {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	for _, product := range []domain.Product{
+		{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(17923, "ARS")},
+		{Name: "Cookie", Quantity: 5, CodeValue: "M7157", Price: domain.NewMoney(27547, "ARS")},
+	} {
+		require.NoError(t, storage.Create(&product))
+	}
+
+	ledger, err := OpenLedger(ledgerPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { ledger.Close() })
+	now := testNow
+	repository, err := NewRepository(storage, ledger)
+	require.NoError(t, err)
+	repository.Now = func() time.Time { return now }
+	return DefaultService{Storage: repository}, &now
+}
+
+func TestRepository(t *testing.T) {
+	t.Run("should open the ledger with the quantities of the products", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+
+		movements, err := service.Movements(1)
+		require.NoError(t, err)
+		require.Len(t, movements, 1)
+		assert.Equal(t, domain.MovementAdjustment, movements[0].Kind)
+		assert.Equal(t, 10, movements[0].Quantity)
+		assert.Equal(t, 10, movements[0].Balance)
+		assert.Equal(t, ReasonOpeningBalance, movements[0].Reason)
+	})
+
+	t.Run("should record the quantity changed by a product update", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		product, err := service.Storage.FindById(1)
+		require.NoError(t, err)
+
+		product.Quantity = 7
+		require.NoError(t, service.Storage.Update(&product))
+		product.Name = "Red wine"
+		require.NoError(t, service.Storage.Update(&product))
+
+		movements, err := service.Movements(1)
+		require.NoError(t, err)
+		require.Len(t, movements, 2)
+		assert.Equal(t, -3, movements[1].Quantity)
+		assert.Equal(t, 7, movements[1].Balance)
+		assert.Equal(t, ReasonProductUpdate, movements[1].Reason)
+	})
+
+	t.Run("should keep the quantity when the ledger cannot be written", func(t *testing.T) {
+		service, _ := newTestService(t, filepath.Join(t.TempDir(), "ledger.jsonl"))
+		require.NoError(t, service.Storage.Ledger.Close())
+
+		_, err := service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 4})
+		assert.Error(t, err)
+		product, err := service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, 10, product.Quantity)
+		assert.Equal(t, 10, service.Storage.Ledger.Balance(1))
+	})
+
+	t.Run("should not keep a product write the ledger cannot record", func(t *testing.T) {
+		service, _ := newTestService(t, filepath.Join(t.TempDir(), "ledger.jsonl"))
+		require.NoError(t, service.Storage.Ledger.Close())
+
+		product, err := service.Storage.FindById(1)
+		require.NoError(t, err)
+		product.Quantity = 7
+		product.Name = "Red wine"
+		assert.Error(t, service.Storage.Update(&product))
+		product, err = service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, "Wine", product.Name)
+		assert.Equal(t, 10, product.Quantity)
+
+		created := domain.Product{Name: "Cheese", Quantity: 3, CodeValue: "Q1", Price: domain.NewMoney(5000, "ARS")}
+		assert.Error(t, service.Storage.Create(&created))
+		_, err = service.Storage.FindById(created.ID)
+		assert.ErrorIs(t, err, products.ErrProductNotFound)
+	})
+
+	t.Run("should replay the ledger file and drop a torn entry", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ledger.jsonl")
+		service, _ := newTestService(t, path)
+		_, err := service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementReceipt, Quantity: 4})
+		require.NoError(t, err)
+		require.NoError(t, service.Storage.Ledger.Close())
+
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"movements":[{"id":4,"product_id":1,"kind":"rece`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		ledger, err := OpenLedger(path)
+		require.NoError(t, err)
+		assert.Equal(t, 14, ledger.Balance(1))
+		assert.Equal(t, 5, ledger.Balance(2))
+		entry, err := ledger.Append(Entry{Movements: []domain.Movement{{ProductID: 2, Kind: domain.MovementReturn, Quantity: 1}}})
+		require.NoError(t, err)
+		assert.Equal(t, 4, entry.Movements[0].ID)
+		require.NoError(t, ledger.Close())
+
+		ledger, err = OpenLedger(path)
+		require.NoError(t, err)
+		assert.Equal(t, 6, ledger.Balance(2))
+		assert.Len(t, ledger.Movements(1), 2)
+		require.NoError(t, ledger.Close())
+	})
+}
+
+func TestDefaultService_Record(t *testing.T) {
+	t.Run("should update the quantity of the product", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+
+		movement, err := service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 4, Reason: "counter sale"})
+		require.NoError(t, err)
+		assert.Equal(t, -4, movement.Quantity)
+		assert.Equal(t, 6, movement.Balance)
+		assert.Equal(t, testNow, movement.CreatedAt)
+
+		product, err := service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, 6, product.Quantity)
+	})
+
+	t.Run("should tell the products whose stock changed", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		var quantities []int
+		service.Storage.Changed = func(previous *domain.Product, current domain.Product) {
+			quantities = append(quantities, previous.Quantity, current.Quantity)
+		}
+
+		_, err := service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 4, Reason: "counter sale"})
+		require.NoError(t, err)
+		assert.Equal(t, []int{10, 6}, quantities)
+	})
+
+	t.Run("should not sell more than the stock available", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		_, err := service.Reserve([]domain.QuoteItem{{ID: 1, Quantity: 8}}, 0)
+		require.NoError(t, err)
+
+		_, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 3})
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		_, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementAdjustment, Quantity: -11})
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		_, err = service.Record(domain.Movement{ProductID: 99, Kind: domain.MovementReceipt, Quantity: 1})
+		assert.ErrorIs(t, err, products.ErrProductNotFound)
+	})
+
+	t.Run("should validate the movement", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+
+		_, err := service.Record(domain.Movement{ProductID: 1, Kind: "theft", Quantity: -1})
+		assert.Equal(t, validation.Errors{
+			{Field: "kind", Code: "enum", Message: "must be receipt, sale, adjustment or return"},
+			{Field: "qty", Code: "positive", Message: "must be greater than 0"},
+		}, err)
+		_, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementAdjustment})
+		assert.Equal(t, validation.Errors{{Field: "qty", Code: "required", Message: "is required"}}, err)
+	})
+}
+
+func TestDefaultService_Reserve(t *testing.T) {
+	t.Run("should hold stock until the reservation expires", func(t *testing.T) {
+		service, now := newTestService(t, "")
+
+		reservation, err := service.Reserve([]domain.QuoteItem{{ID: 1, Quantity: 2}, {ID: 1, Quantity: 1}}, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.QuoteItem{{ID: 1, Quantity: 3}}, reservation.Items)
+		assert.Equal(t, testNow.Add(time.Minute), reservation.ExpiresAt)
+
+		stock, err := service.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, domain.Stock{ProductID: 1, OnHand: 10, Reserved: 3, Available: 7}, stock)
+		_, err = service.Reserve([]domain.QuoteItem{{ID: 1, Quantity: 8}}, 0)
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+
+		*now = now.Add(time.Minute)
+		stock, err = service.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, 10, stock.Available)
+		assert.Empty(t, service.Storage.Ledger.active[1])
+		reservation, err = service.FindReservation(reservation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReservationExpired, reservation.Status)
+		_, err = service.Commit(reservation.ID)
+		assert.ErrorIs(t, err, ErrReservationClosed)
+	})
+
+	t.Run("should not reserve unpublished products", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+
+		_, err := service.Reserve([]domain.QuoteItem{{ID: 1, Quantity: 1}, {ID: 2, Quantity: 1}}, 0)
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		stock, err := service.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, stock.Reserved)
+	})
+
+	t.Run("should commit the items as sales once", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		reservation, err := service.Reserve([]domain.QuoteItem{{ID: 1, Quantity: 3}}, 0)
+		require.NoError(t, err)
+
+		committed, err := service.Commit(reservation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReservationCommitted, committed.Status)
+		movements, err := service.Movements(1)
+		require.NoError(t, err)
+		assert.Equal(t, domain.MovementSale, movements[1].Kind)
+		assert.Equal(t, -3, movements[1].Quantity)
+		assert.Equal(t, reservation.ID, movements[1].ReservationID)
+		stock, err := service.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, domain.Stock{ProductID: 1, OnHand: 7, Available: 7}, stock)
+
+		_, err = service.Commit(reservation.ID)
+		assert.ErrorIs(t, err, ErrReservationClosed)
+		_, err = service.Release(reservation.ID)
+		assert.ErrorIs(t, err, ErrReservationClosed)
+	})
+
+	t.Run("should return the stock of a released reservation", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		reservation, err := service.Reserve([]domain.QuoteItem{{ID: 1, Quantity: 3}}, 0)
+		require.NoError(t, err)
+
+		released, err := service.Release(reservation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReservationReleased, released.Status)
+		assert.Empty(t, service.Storage.Ledger.active[1])
+		stock, err := service.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, domain.Stock{ProductID: 1, OnHand: 10, Available: 10}, stock)
+		_, err = service.Release("unknown")
+		assert.ErrorIs(t, err, ErrReservationNotFound)
+	})
+
+	t.Run("should validate the items", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+
+		_, err := service.Reserve([]domain.QuoteItem{{ID: 1, Quantity: 0}}, 48*time.Hour)
+		assert.Equal(t, validation.Errors{
+			{Field: "ttl_seconds", Code: "max", Message: "must be at most 86400"},
+			{Field: "items[0].qty", Code: "positive", Message: "must be greater than 0"},
+		}, err)
+	})
+}
+
+func TestDefaultService_Lots(t *testing.T) {
+	t.Run("should sell the lots first-expired-first-out", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		_, err := service.Receive(domain.Lot{ProductID: 1, Code: "L2", Quantity: 4, Expiration: domain.NewDate(2022, time.March, 1)}, "delivery")
+		require.NoError(t, err)
+		lot, err := service.Receive(domain.Lot{ProductID: 1, Code: "L1", Quantity: 3, Expiration: domain.NewDate(2022, time.February, 1)}, "")
+		require.NoError(t, err)
+		assert.Equal(t, domain.Lot{ProductID: 1, Code: "L1", Quantity: 3, Expiration: domain.NewDate(2022, time.February, 1), ReceivedAt: testNow}, lot)
+		product, err := service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, 17, product.Quantity)
+		assert.Equal(t, domain.NewDate(2022, time.February, 1), service.Expiration(product))
+
+		// The 10 units of the opening balance are outside lots, have no
+		// expiration and go first.
+		movement, err := service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 12})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.LotQuantity{{Lot: "L1", Quantity: -2}}, movement.Lots)
+		movement, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 3})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.LotQuantity{{Lot: "L1", Quantity: -1}, {Lot: "L2", Quantity: -2}}, movement.Lots)
+		product, err = service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, product.Quantity)
+		assert.Equal(t, domain.NewDate(2022, time.March, 1), service.Expiration(product))
+
+		lots, err := service.Lots(1)
+		require.NoError(t, err)
+		require.Len(t, lots, 1)
+		assert.Equal(t, "L2", lots[0].Code)
+		assert.Equal(t, 2, lots[0].Quantity)
+	})
+
+	t.Run("should sell the stock outside lots by the expiration of the product", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		product, err := service.Storage.FindById(1)
+		require.NoError(t, err)
+		product.Expiration = domain.NewDate(2022, time.June, 1)
+		require.NoError(t, service.Storage.Update(&product))
+		_, err = service.Receive(domain.Lot{ProductID: 1, Code: "L1", Quantity: 3, Expiration: domain.NewDate(2022, time.February, 1)}, "")
+		require.NoError(t, err)
+		_, err = service.Receive(domain.Lot{ProductID: 1, Code: "L2", Quantity: 4, Expiration: domain.NewDate(2022, time.September, 1)}, "")
+		require.NoError(t, err)
+
+		// L1 expires before the 10 units outside lots, and L2 after them.
+		movement, err := service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 5})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.LotQuantity{{Lot: "L1", Quantity: -3}}, movement.Lots)
+		movement, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 9})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.LotQuantity{{Lot: "L2", Quantity: -1}}, movement.Lots)
+		product, err = service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, 3, product.Quantity)
+		assert.Equal(t, domain.NewDate(2022, time.September, 1), service.Expiration(product))
+	})
+
+	t.Run("should keep the expiration of the stock outside lots", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		product, err := service.Storage.FindById(1)
+		require.NoError(t, err)
+		product.Expiration = domain.NewDate(2030, time.January, 1)
+		require.NoError(t, service.Storage.Update(&product))
+		_, err = service.Receive(domain.Lot{ProductID: 1, Code: "L1", Quantity: 3, Expiration: domain.NewDate(2022, time.February, 1)}, "")
+		require.NoError(t, err)
+		product, err = service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, domain.NewDate(2022, time.February, 1), service.Expiration(product))
+
+		_, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 3, Lot: "L1"})
+		require.NoError(t, err)
+		product, err = service.Storage.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, 10, product.Quantity)
+		assert.Equal(t, domain.NewDate(2030, time.January, 1), product.Expiration)
+		assert.Equal(t, domain.NewDate(2030, time.January, 1), service.Expiration(product))
+	})
+
+	t.Run("should only move the lot asked for", func(t *testing.T) {
+		service, _ := newTestService(t, "")
+		_, err := service.Receive(domain.Lot{ProductID: 1, Code: "L1", Quantity: 3, Expiration: domain.NewDate(2022, time.February, 1)}, "")
+		require.NoError(t, err)
+
+		_, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementAdjustment, Quantity: -4, Lot: "L1"})
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		_, err = service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementSale, Quantity: 1, Lot: "L9"})
+		assert.ErrorIs(t, err, ErrLotNotFound)
+		_, err = service.Receive(domain.Lot{ProductID: 1, Code: "L1", Quantity: 1, Expiration: domain.NewDate(2022, time.June, 1)}, "")
+		assert.ErrorIs(t, err, ErrLotAlreadyExists)
+
+		movement, err := service.Record(domain.Movement{ProductID: 1, Kind: domain.MovementAdjustment, Quantity: -3, Lot: "L1", Reason: "expired"})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.LotQuantity{{Lot: "L1", Quantity: -3}}, movement.Lots)
+		lots, err := service.Lots(1)
+		require.NoError(t, err)
+		assert.Empty(t, lots)
+	})
+
+	t.Run("should list the lots expiring within some days", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ledger.jsonl")
+		service, _ := newTestService(t, path)
+		_, err := service.Receive(domain.Lot{ProductID: 2, Code: "M1", Quantity: 2, Expiration: domain.NewDate(2022, time.January, 20)}, "")
+		require.NoError(t, err)
+		_, err = service.Receive(domain.Lot{ProductID: 1, Code: "L1", Quantity: 3, Expiration: domain.NewDate(2022, time.March, 1)}, "")
+		require.NoError(t, err)
+		require.NoError(t, service.Storage.Ledger.Close())
+
+		ledger, err := OpenLedger(path)
+		require.NoError(t, err)
+		t.Cleanup(func() { ledger.Close() })
+		service.Storage.Ledger = ledger
+
+		lots, err := service.Expiring(30)
+		require.NoError(t, err)
+		require.Len(t, lots, 1)
+		assert.Equal(t, "M1", lots[0].Code)
+		lots, err = service.Expiring(60)
+		require.NoError(t, err)
+		require.Len(t, lots, 2)
+		assert.Equal(t, "L1", lots[1].Code)
+		assert.Equal(t, 3, lots[1].Quantity)
+
+		_, err = service.Expiring(400)
+		assert.Equal(t, validation.Errors{{Field: "days", Code: "max", Message: "must be at most 365"}}, err)
+	})
+}
@@ -0,0 +1,307 @@
+// Package inventory records why the stock of the products changes: a ledger
+// of movements and the reservations that hold stock for quotes and carts.
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// Entry is a transaction of the ledger: the movements recorded together, the
+// lots they receive and the reservation they change, if any. An entry is a
+// single line of the ledger file, so a transaction is either replayed whole or
+// not at all.
+type Entry struct {
+	Movements []domain.Movement `json:"movements,omitempty"`
+	// Lots are the lots opened by the entry, empty until its movements add
+	// their stock.
+	Lots        []domain.Lot        `json:"lots,omitempty"`
+	Reservation *domain.Reservation `json:"reservation,omitempty"`
+}
+
+// Ledger keeps the movements and reservations in memory. With a path, every
+// entry is also synced to a JSON lines file before Append returns, and the
+// file is replayed on open.
+type Ledger struct {
+	mu           sync.RWMutex
+	file         *os.File
+	movements    []domain.Movement
+	balances     map[int]int
+	reservations map[string]domain.Reservation
+	// lots are the lots of each product in the order they were received.
+	lots map[int][]domain.Lot
+	// active are the IDs of the reservations holding stock of each product,
+	// until they are committed, released or found expired by Reserved.
+	active map[int]map[string]bool
+}
+
+// OpenLedger replays the ledger file at path, if any, and appends to it. An
+// empty path keeps the ledger in memory only.
+func OpenLedger(path string) (*Ledger, error) {
+	ledger := &Ledger{balances: map[int]int{}, reservations: map[string]domain.Reservation{}, lots: map[int][]domain.Lot{}, active: map[int]map[string]bool{}}
+	if path == "" {
+		return ledger, nil
+	}
+	entries, size, err := readEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		ledger.apply(entry)
+	}
+	// Drop a torn last entry, so the next one starts on a line of its own.
+	if err := os.Truncate(path, size); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error opening ledger: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening ledger: %w", err)
+	}
+	ledger.file = file
+	return ledger, nil
+}
+
+// Append numbers the movements of entry, computes their balances and saves
+// it. It returns the entry as saved.
+func (ledger *Ledger) Append(entry Entry) (Entry, error) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	movements := make([]domain.Movement, len(entry.Movements))
+	balances := map[int]int{}
+	for i, movement := range entry.Movements {
+		balance, ok := balances[movement.ProductID]
+		if !ok {
+			balance = ledger.balances[movement.ProductID]
+		}
+		movement.ID = len(ledger.movements) + i + 1
+		movement.Balance = balance + movement.Quantity
+		balances[movement.ProductID] = movement.Balance
+		movements[i] = movement
+	}
+	entry.Movements = movements
+
+	if ledger.file != nil {
+		if err := ledger.write(entry); err != nil {
+			return Entry{}, err
+		}
+	}
+	ledger.apply(entry)
+	return entry, nil
+}
+
+// write appends entry to the ledger file and syncs it. A write that fails
+// partway is cut off the file, so that the next entry does not follow a torn
+// line, which could not be replayed.
+func (ledger *Ledger) write(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.New("error converting text to json")
+	}
+	info, err := ledger.file.Stat()
+	if err != nil {
+		return fmt.Errorf("error writting ledger: %w", err)
+	}
+	if _, err := ledger.file.Write(append(data, '\n')); err != nil {
+		ledger.file.Truncate(info.Size())
+		return fmt.Errorf("error writting ledger: %w", err)
+	}
+	if err := ledger.file.Sync(); err != nil {
+		ledger.file.Truncate(info.Size())
+		return fmt.Errorf("error syncing ledger: %w", err)
+	}
+	return nil
+}
+
+func (ledger *Ledger) apply(entry Entry) {
+	for _, lot := range entry.Lots {
+		ledger.lots[lot.ProductID] = append(ledger.lots[lot.ProductID], lot)
+	}
+	for _, movement := range entry.Movements {
+		ledger.movements = append(ledger.movements, movement)
+		ledger.balances[movement.ProductID] = movement.Balance
+		lots := ledger.lots[movement.ProductID]
+		for _, change := range movement.Lots {
+			for i := range lots {
+				if lots[i].Code == change.Lot {
+					lots[i].Quantity += change.Quantity
+				}
+			}
+		}
+	}
+	if entry.Reservation != nil {
+		reservation := *entry.Reservation
+		ledger.reservations[reservation.ID] = reservation
+		for _, item := range reservation.Items {
+			if reservation.Status != domain.ReservationActive {
+				delete(ledger.active[item.ID], reservation.ID)
+				continue
+			}
+			if ledger.active[item.ID] == nil {
+				ledger.active[item.ID] = map[string]bool{}
+			}
+			ledger.active[item.ID][reservation.ID] = true
+		}
+	}
+}
+
+// Balance is the stock on hand of a product, the sum of its movements.
+func (ledger *Ledger) Balance(productID int) int {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+	return ledger.balances[productID]
+}
+
+// Recorded reports whether a product has any movement.
+func (ledger *Ledger) Recorded(productID int) bool {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+	_, ok := ledger.balances[productID]
+	return ok
+}
+
+// Movements returns the movements of a product, oldest first.
+func (ledger *Ledger) Movements(productID int) []domain.Movement {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+	movements := []domain.Movement{}
+	for _, movement := range ledger.movements {
+		if movement.ProductID == productID {
+			movements = append(movements, movement)
+		}
+	}
+	return movements
+}
+
+// Lots returns the lots of a product in the order they were received,
+// including the ones with no stock left.
+func (ledger *Ledger) Lots(productID int) []domain.Lot {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+	return append([]domain.Lot{}, ledger.lots[productID]...)
+}
+
+// Expiring returns the lots with stock left that expire on until or before,
+// the earliest expiration first.
+func (ledger *Ledger) Expiring(until domain.Date) []domain.Lot {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+	expiring := []domain.Lot{}
+	for _, lots := range ledger.lots {
+		for _, lot := range lots {
+			if lot.Quantity > 0 && !lot.Expiration.After(until) {
+				expiring = append(expiring, lot)
+			}
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		if !expiring[i].Expiration.Time().Equal(expiring[j].Expiration.Time()) {
+			return expiring[i].Expiration.Before(expiring[j].Expiration)
+		}
+		if expiring[i].ProductID != expiring[j].ProductID {
+			return expiring[i].ProductID < expiring[j].ProductID
+		}
+		return expiring[i].ReceivedAt.Before(expiring[j].ReceivedAt)
+	})
+	return expiring
+}
+
+func (ledger *Ledger) Reservation(id string) (domain.Reservation, bool) {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+	reservation, ok := ledger.reservations[id]
+	return reservation, ok
+}
+
+// Reserved is the number of units of a product held at now by active
+// reservations. The reservations found expired stop being looked at.
+func (ledger *Ledger) Reserved(productID int, now time.Time) int {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	reserved := 0
+	for id := range ledger.active[productID] {
+		reservation := ledger.reservations[id]
+		if reservation.StatusAt(now) != domain.ReservationActive {
+			delete(ledger.active[productID], id)
+			continue
+		}
+		for _, item := range reservation.Items {
+			if item.ID == productID {
+				reserved += item.Quantity
+			}
+		}
+	}
+	return reserved
+}
+
+// Reservations returns the reservations at now, oldest first, with their
+// status at now.
+func (ledger *Ledger) Reservations(now time.Time) []domain.Reservation {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+	reservations := make([]domain.Reservation, 0, len(ledger.reservations))
+	for _, reservation := range ledger.reservations {
+		reservation.Status = reservation.StatusAt(now)
+		reservations = append(reservations, reservation)
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].CreatedAt.Before(reservations[j].CreatedAt)
+	})
+	return reservations
+}
+
+func (ledger *Ledger) Close() error {
+	if ledger.file == nil {
+		return nil
+	}
+	return ledger.file.Close()
+}
+
+// readEntries returns the entries of the ledger file at path and the size of
+// the lines they were read from. A missing file is empty and a last line
+// that cannot be decoded is a torn write and is skipped.
+func readEntries(path string) ([]Entry, int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("error opening ledger: %w", err)
+	}
+
+	var (
+		entries []Entry
+		size    int64
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			size += int64(len(line)) + 1
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, line) {
+				return entries, size, nil
+			}
+			return nil, 0, fmt.Errorf("error decoding ledger: %w", err)
+		}
+		entries = append(entries, entry)
+		size += int64(len(line)) + 1
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error reading ledger: %w", err)
+	}
+	return entries, int64(len(data)), nil
+}
@@ -0,0 +1,248 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+var (
+	ErrLotNotFound      = errors.New("lot not found")
+	ErrLotAlreadyExists = errors.New("lot already exists")
+)
+
+// stock is the stock of a product split in lots while an entry is built:
+// lots in the order they were received and outside, the units that are not
+// in any lot.
+type stock struct {
+	lots    []domain.Lot
+	outside int
+	// expiration is the expiration of the product, which stands for the
+	// units outside lots.
+	expiration domain.Date
+}
+
+// stockOf returns the stock of product in the ledger.
+func (repository *Repository) stockOf(product domain.Product) *stock {
+	current := &stock{
+		lots:       repository.Ledger.Lots(product.ID),
+		outside:    repository.Ledger.Balance(product.ID),
+		expiration: product.Expiration,
+	}
+	for _, lot := range current.lots {
+		current.outside -= lot.Quantity
+	}
+	return current
+}
+
+// open adds a lot without stock, which fails with ErrLotAlreadyExists when
+// the product has a lot with the same code.
+func (current *stock) open(lot domain.Lot) error {
+	if current.find(lot.Code) >= 0 {
+		return fmt.Errorf("%w: %s", ErrLotAlreadyExists, lot.Code)
+	}
+	lot.Quantity = 0
+	current.lots = append(current.lots, lot)
+	return nil
+}
+
+func (current *stock) find(code string) int {
+	for i, lot := range current.lots {
+		if lot.Code == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// move applies movement and sets the units it changes in each lot. A
+// movement of a lot only changes that lot. Otherwise, units added are outside
+// lots and units removed are taken first-expired-first-out, the stock outside
+// lots, e.g. opening balances, counting as one more lot that expires with the
+// product.
+func (current *stock) move(movement *domain.Movement) error {
+	movement.Lots = nil
+	if movement.Lot != "" {
+		i := current.find(movement.Lot)
+		if i < 0 {
+			return fmt.Errorf("%w: %s", ErrLotNotFound, movement.Lot)
+		}
+		if current.lots[i].Quantity+movement.Quantity < 0 {
+			return fmt.Errorf("%w: lot %s", ErrInsufficientStock, movement.Lot)
+		}
+		current.lots[i].Quantity += movement.Quantity
+		movement.Lots = []domain.LotQuantity{{Lot: movement.Lot, Quantity: movement.Quantity}}
+		return nil
+	}
+	if movement.Quantity >= 0 {
+		current.outside += movement.Quantity
+		return nil
+	}
+
+	missing := -movement.Quantity
+	for _, i := range current.fefo() {
+		if missing == 0 {
+			break
+		}
+		if i == outsideLots {
+			taken := min(missing, current.outside)
+			current.outside -= taken
+			missing -= taken
+			continue
+		}
+		taken := min(missing, current.lots[i].Quantity)
+		current.lots[i].Quantity -= taken
+		movement.Lots = append(movement.Lots, domain.LotQuantity{Lot: current.lots[i].Code, Quantity: -taken})
+		missing -= taken
+	}
+	if missing > 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// outsideLots stands for the stock outside lots in the order of fefo.
+const outsideLots = -1
+
+// fefo returns the indexes of the lots with stock left, the earliest
+// expiration first and the earliest received first on the same day. The stock
+// outside lots is outsideLots, which expires with the product and goes before
+// the lots expiring on the same day.
+func (current *stock) fefo() []int {
+	order := []int{}
+	if current.outside > 0 {
+		order = append(order, outsideLots)
+	}
+	for i, lot := range current.lots {
+		if lot.Quantity > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return current.expirationOf(order[i]).Before(current.expirationOf(order[j]))
+	})
+	return order
+}
+
+func (current *stock) expirationOf(i int) domain.Date {
+	if i == outsideLots {
+		return current.expiration
+	}
+	return current.lots[i].Expiration
+}
+
+// earliestExpiration is the earliest expiration of the lots with stock left
+// and, when there are units outside lots, of the product. It is the
+// expiration of the product when no lot has stock left.
+func (current *stock) earliestExpiration() domain.Date {
+	earliest := domain.Date{}
+	if current.outside > 0 {
+		earliest = current.expiration
+	}
+	inLots := false
+	for _, lot := range current.lots {
+		if lot.Quantity <= 0 {
+			continue
+		}
+		inLots = true
+		if earliest.IsZero() || lot.Expiration.Before(earliest) {
+			earliest = lot.Expiration
+		}
+	}
+	if !inLots {
+		return current.expiration
+	}
+	return earliest
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// MaxLotCodeLength bounds the codes of lots.
+const MaxLotCodeLength = 50
+
+// MaxExpiringDays bounds how far ahead Expiring looks.
+const MaxExpiringDays = 365
+
+// Receive records the receipt of lot.Quantity units of a new lot of a
+// product, whose code is unique among the lots of the product.
+func (service DefaultService) Receive(lot domain.Lot, reason string) (domain.Lot, error) {
+	err := validation.Validate(
+		validation.Field("code", lot.Code, validation.Required[string](), validation.MaxLength(MaxLotCodeLength)),
+		validation.Field("qty", lot.Quantity, validation.Positive[int]()),
+		validation.Field("expiration", lot.Expiration, validation.Required[domain.Date]()),
+	)
+	if err != nil {
+		return domain.Lot{}, err
+	}
+
+	entry, err := service.Storage.Transact(func(now time.Time) (Entry, error) {
+		return Entry{
+			Lots: []domain.Lot{{ProductID: lot.ProductID, Code: lot.Code, Expiration: lot.Expiration}},
+			Movements: []domain.Movement{{
+				ProductID: lot.ProductID,
+				Kind:      domain.MovementReceipt,
+				Quantity:  lot.Quantity,
+				Reason:    reason,
+				Lot:       lot.Code,
+			}},
+		}, nil
+	})
+	if err != nil {
+		return domain.Lot{}, err
+	}
+	received := entry.Lots[0]
+	received.Quantity = lot.Quantity
+	return received, nil
+}
+
+// Lots returns the lots of a product with stock left, in the order their
+// units are sold: first-expired-first-out, after the stock outside lots.
+func (service DefaultService) Lots(productID int) ([]domain.Lot, error) {
+	if _, err := service.Storage.FindById(productID); err != nil {
+		return nil, err
+	}
+	lots := []domain.Lot{}
+	for _, lot := range service.Storage.Ledger.Lots(productID) {
+		if lot.Quantity > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		return lots[i].Expiration.Before(lots[j].Expiration)
+	})
+	return lots, nil
+}
+
+// Expiring returns the lots with stock left that expire within days from
+// today, the lots already expired included, the earliest expiration first.
+func (service DefaultService) Expiring(days int) ([]domain.Lot, error) {
+	err := validation.Validate(validation.Field("days", days, validation.Min(0), maxDays))
+	if err != nil {
+		return nil, err
+	}
+	today := domain.DateOf(service.Storage.now())
+	return service.Storage.Ledger.Expiring(domain.DateOf(today.Time().AddDate(0, 0, days))), nil
+}
+
+func maxDays(days int) *validation.Violation {
+	if days > MaxExpiringDays {
+		return &validation.Violation{Code: "max", Message: fmt.Sprintf("must be at most %d", MaxExpiringDays)}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package inventory
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+)
+
+var (
+	ErrInsufficientStock   = errors.New("insufficient stock")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationClosed   = errors.New("reservation is not active")
+)
+
+// Reasons of the adjustments the Repository records by itself.
+const (
+	ReasonOpeningBalance = "opening balance"
+	ReasonProductUpdate  = "product update"
+)
+
+// Repository wraps a products.Repository so that the Quantity of every
+// product is its stock on hand in the ledger: writes that change a Quantity
+// record an adjustment, and the movements recorded through Transact update
+// the Quantity of their products. Products without movements get an opening
+// balance when the Repository is created.
+type Repository struct {
+	products.Repository
+	Ledger *Ledger
+	// Now returns the time movements are recorded at, time.Now when nil.
+	Now func() time.Time
+	// Changed is called with every product whose stock Transact changed,
+	// and the product before the change. It must not block.
+	Changed func(previous *domain.Product, current domain.Product)
+
+	// mu serializes the writes that change stock, so that the ledger and
+	// the quantities of the products change together.
+	mu sync.Mutex
+}
+
+func NewRepository(repository products.Repository, ledger *Ledger) (*Repository, error) {
+	inventory := &Repository{Repository: repository, Ledger: ledger}
+	stored, err := repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range stored {
+		entry, err := inventory.reconcile(&product, ReasonOpeningBalance)
+		if err != nil {
+			return nil, err
+		}
+		if err := inventory.append(entry); err != nil {
+			return nil, err
+		}
+	}
+	return inventory, nil
+}
+
+func (repository *Repository) now() time.Time {
+	if repository.Now == nil {
+		return time.Now()
+	}
+	return repository.Now()
+}
+
+// reconcile records an adjustment when the Quantity of product is not its
+// stock on hand, units removed being taken first-expired-first-out. The
+// Expiration of product is left as is: it is the expiration of the units
+// outside lots, see Expiration.
+func (repository *Repository) reconcile(product *domain.Product, reason string) (Entry, error) {
+	current := repository.stockOf(*product)
+	delta := product.Quantity - repository.Ledger.Balance(product.ID)
+	if delta == 0 && repository.Ledger.Recorded(product.ID) {
+		return Entry{}, nil
+	}
+	movement := domain.Movement{
+		ProductID: product.ID,
+		Kind:      domain.MovementAdjustment,
+		Quantity:  delta,
+		Reason:    reason,
+		CreatedAt: repository.now(),
+	}
+	if err := current.move(&movement); err != nil {
+		return Entry{}, err
+	}
+	return Entry{Movements: []domain.Movement{movement}}, nil
+}
+
+// Expiration is the earliest expiration of the stock of product: of its lots
+// with stock left and, for the units outside lots, of product itself.
+func (repository *Repository) Expiration(product domain.Product) domain.Date {
+	return repository.stockOf(product).earliestExpiration()
+}
+
+func (repository *Repository) append(entry Entry) error {
+	if len(entry.Movements) == 0 {
+		return nil
+	}
+	_, err := repository.Ledger.Append(entry)
+	return err
+}
+
+// Create and Update write the product before its quantity is appended to the
+// ledger, and set it back when it cannot be, the way Transact does.
+func (repository *Repository) Create(product *domain.Product) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	if err := repository.Repository.Create(product); err != nil {
+		return err
+	}
+	entry, err := repository.reconcile(product, ReasonOpeningBalance)
+	if err == nil {
+		err = repository.append(entry)
+	}
+	if err != nil {
+		if err := repository.Repository.Delete(product.ID); err != nil {
+			log.Printf("error removing product %d: %v", product.ID, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (repository *Repository) Update(product *domain.Product) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	entry, err := repository.reconcile(product, ReasonProductUpdate)
+	if err != nil {
+		return err
+	}
+	previous, err := repository.Repository.FindById(product.ID)
+	if err != nil {
+		return err
+	}
+	if err := repository.Repository.Update(product); err != nil {
+		return err
+	}
+	if err := repository.append(entry); err != nil {
+		previous.Version = product.Version
+		if err := repository.Repository.Update(&previous); err != nil {
+			log.Printf("error restoring product %d: %v", product.ID, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateName, SetCategories, Trash and Restore go through the lock too,
+// since they change the version of the product that Transact updates.
+func (repository *Repository) UpdateName(id int, name string) (domain.Product, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	return repository.Repository.UpdateName(id, name)
+}
+
+func (repository *Repository) SetCategories(id int, version int, categories []int) (domain.Product, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	return repository.Repository.SetCategories(id, version, categories)
+}
+
+func (repository *Repository) Trash(id int, at time.Time, by string) (domain.Product, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	return repository.Repository.Trash(id, at, by)
+}
+
+func (repository *Repository) Restore(id int) (domain.Product, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	return repository.Repository.Restore(id)
+}
+
+func (repository *Repository) Delete(id int) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	return repository.Repository.Delete(id)
+}
+
+// Transact saves the entry built by change, which runs while no other stock
+// changes, and then sets the Quantity of the products moved to their new
+// balance. The lots of the entry are opened before its movements take units
+// from lots, which fails with ErrInsufficientStock when a balance or a lot
+// would go below zero.
+func (repository *Repository) Transact(change func(now time.Time) (Entry, error)) (Entry, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	now := repository.now()
+	entry, err := change(now)
+	if err != nil {
+		return Entry{}, err
+	}
+	stocks := map[int]*stock{}
+	stockOf := func(productID int) (*stock, error) {
+		if current, ok := stocks[productID]; ok {
+			return current, nil
+		}
+		product, err := repository.Repository.FindById(productID)
+		if err != nil {
+			return nil, err
+		}
+		stocks[productID] = repository.stockOf(product)
+		return stocks[productID], nil
+	}
+	for i, lot := range entry.Lots {
+		current, err := stockOf(lot.ProductID)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.Lots[i].Quantity = 0
+		entry.Lots[i].ReceivedAt = now
+		if err := current.open(entry.Lots[i]); err != nil {
+			return Entry{}, err
+		}
+	}
+	balances := map[int]int{}
+	for i, movement := range entry.Movements {
+		current, err := stockOf(movement.ProductID)
+		if err != nil {
+			return Entry{}, err
+		}
+		balance, ok := balances[movement.ProductID]
+		if !ok {
+			balance = repository.Ledger.Balance(movement.ProductID)
+		}
+		balances[movement.ProductID] = balance + movement.Quantity
+		if balances[movement.ProductID] < 0 {
+			return Entry{}, ErrInsufficientStock
+		}
+		if err := current.move(&entry.Movements[i]); err != nil {
+			return Entry{}, err
+		}
+		entry.Movements[i].CreatedAt = now
+	}
+
+	// The products are written before the entry is appended, and set back
+	// when it cannot be, so that the ledger never records stock the
+	// products do not have.
+	previous := map[int]domain.Product{}
+	updated := []domain.Product{}
+	for id, balance := range balances {
+		product, err := repository.Repository.FindById(id)
+		if err != nil {
+			repository.undo(updated, previous)
+			return Entry{}, err
+		}
+		previous[id] = product
+		product.Quantity = balance
+		if err := repository.Repository.Update(&product); err != nil {
+			repository.undo(updated, previous)
+			return Entry{}, err
+		}
+		updated = append(updated, product)
+	}
+	entry, err = repository.Ledger.Append(entry)
+	if err != nil {
+		repository.undo(updated, previous)
+		return Entry{}, err
+	}
+	if repository.Changed != nil {
+		for _, product := range updated {
+			before := previous[product.ID]
+			repository.Changed(&before, product)
+		}
+	}
+	return entry, nil
+}
+
+// undo sets the products Transact updated back to their previous quantity.
+func (repository *Repository) undo(updated []domain.Product, previous map[int]domain.Product) {
+	for _, product := range updated {
+		product.Quantity = previous[product.ID].Quantity
+		if err := repository.Repository.Update(&product); err != nil {
+			log.Printf("error restoring the quantity of product %d: %v", product.ID, err)
+		}
+	}
+}
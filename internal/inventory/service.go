@@ -0,0 +1,244 @@
+package inventory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+// DefaultReservationTTL is how long reservations hold stock unless asked
+// otherwise.
+const DefaultReservationTTL = 15 * time.Minute
+
+// MaxReservationTTL bounds the TTL asked for a reservation.
+const MaxReservationTTL = 24 * time.Hour
+
+type Service interface {
+	Record(movement domain.Movement) (domain.Movement, error)
+	Movements(productID int) ([]domain.Movement, error)
+	Receive(lot domain.Lot, reason string) (domain.Lot, error)
+	Lots(productID int) ([]domain.Lot, error)
+	Expiring(days int) ([]domain.Lot, error)
+	Stock(productID int) (domain.Stock, error)
+	Reserve(items []domain.QuoteItem, ttl time.Duration) (domain.Reservation, error)
+	FindReservation(id string) (domain.Reservation, error)
+	Commit(id string) (domain.Reservation, error)
+	Release(id string) (domain.Reservation, error)
+}
+
+type DefaultService struct {
+	Storage *Repository
+	// TTL is how long reservations hold stock when no TTL is asked for,
+	// DefaultReservationTTL when zero.
+	TTL time.Duration
+}
+
+// Record adds a movement to the ledger. Receipts and returns add stock and
+// sales remove it, so their quantity is positive; adjustments add or remove
+// stock as their quantity is positive or negative. A movement of a lot only
+// changes that lot, which must have been received with Receive.
+func (service DefaultService) Record(movement domain.Movement) (domain.Movement, error) {
+	err := validation.Validate(
+		validation.Field("kind", movement.Kind, validation.Required[domain.MovementKind](), knownKind),
+		validation.Field("qty", movement.Quantity,
+			validation.Required[int](),
+			validation.When(movement.Kind != domain.MovementAdjustment, validation.Positive[int]()),
+		),
+	)
+	if err != nil {
+		return domain.Movement{}, err
+	}
+	if movement.Kind == domain.MovementSale {
+		movement.Quantity = -movement.Quantity
+	}
+	movement.ID, movement.Balance, movement.ReservationID, movement.Lots = 0, 0, "", nil
+
+	entry, err := service.Storage.Transact(func(now time.Time) (Entry, error) {
+		if _, err := service.Storage.FindById(movement.ProductID); err != nil {
+			return Entry{}, err
+		}
+		// Sales cannot take the stock held by reservations.
+		if movement.Quantity < 0 && service.available(movement.ProductID, now) < -movement.Quantity {
+			return Entry{}, ErrInsufficientStock
+		}
+		return Entry{Movements: []domain.Movement{movement}}, nil
+	})
+	if err != nil {
+		return domain.Movement{}, err
+	}
+	return entry.Movements[0], nil
+}
+
+func knownKind(kind domain.MovementKind) *validation.Violation {
+	switch kind {
+	case domain.MovementReceipt, domain.MovementSale, domain.MovementAdjustment, domain.MovementReturn:
+		return nil
+	}
+	return &validation.Violation{Code: "enum", Message: "must be receipt, sale, adjustment or return"}
+}
+
+func (service DefaultService) Movements(productID int) ([]domain.Movement, error) {
+	if _, err := service.Storage.FindById(productID); err != nil {
+		return nil, err
+	}
+	return service.Storage.Ledger.Movements(productID), nil
+}
+
+func (service DefaultService) Stock(productID int) (domain.Stock, error) {
+	if _, err := service.Storage.FindById(productID); err != nil {
+		return domain.Stock{}, err
+	}
+	return service.stock(productID, service.Storage.now()), nil
+}
+
+func (service DefaultService) stock(productID int, now time.Time) domain.Stock {
+	stock := domain.Stock{
+		ProductID: productID,
+		OnHand:    service.Storage.Ledger.Balance(productID),
+		Reserved:  service.Storage.Ledger.Reserved(productID, now),
+	}
+	stock.Available = stock.OnHand - stock.Reserved
+	return stock
+}
+
+func (service DefaultService) available(productID int, now time.Time) int {
+	return service.stock(productID, now).Available
+}
+
+// Available is the stock of a product that is not held by reservations.
+func (service DefaultService) Available(productID int) int {
+	return service.available(productID, service.Storage.now())
+}
+
+// Expiration is the earliest expiration of the stock of product, its lots
+// included.
+func (service DefaultService) Expiration(product domain.Product) domain.Date {
+	return service.Storage.Expiration(product)
+}
+
+// Reserve holds the items for ttl, the TTL of the service when zero, adding
+// up the items of the same product. It fails with ErrInsufficientStock unless
+// every item is published and available.
+func (service DefaultService) Reserve(items []domain.QuoteItem, ttl time.Duration) (domain.Reservation, error) {
+	if err := validateItems(items, ttl); err != nil {
+		return domain.Reservation{}, err
+	}
+	if ttl == 0 {
+		ttl = service.TTL
+	}
+	if ttl == 0 {
+		ttl = DefaultReservationTTL
+	}
+
+	var merged []domain.QuoteItem
+	positions := make(map[int]int)
+	for _, item := range items {
+		if i, ok := positions[item.ID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		positions[item.ID] = len(merged)
+		merged = append(merged, item)
+	}
+
+	entry, err := service.Storage.Transact(func(now time.Time) (Entry, error) {
+		for _, item := range merged {
+			product, err := service.Storage.FindById(item.ID)
+			if err != nil {
+				return Entry{}, err
+			}
+			if !product.IsPublished || service.available(item.ID, now) < item.Quantity {
+				return Entry{}, fmt.Errorf("%w: product %d", ErrInsufficientStock, item.ID)
+			}
+		}
+		return Entry{Reservation: &domain.Reservation{
+			ID:        products.IDSchemeULID.NewUID(),
+			Items:     merged,
+			Status:    domain.ReservationActive,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}}, nil
+	})
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	return *entry.Reservation, nil
+}
+
+func validateItems(items []domain.QuoteItem, ttl time.Duration) error {
+	if len(items) == 0 {
+		return validation.Errors{{Field: "items", Code: "required", Message: "must list at least one item"}}
+	}
+	fields := []validation.Errors{
+		validation.Field("ttl_seconds", int(ttl/time.Second), validation.Min(0), maxTTL),
+	}
+	for i, item := range items {
+		prefix := fmt.Sprintf("items[%d].", i)
+		fields = append(fields,
+			validation.Field(prefix+"id", item.ID, validation.Positive[int]()),
+			validation.Field(prefix+"qty", item.Quantity, validation.Positive[int]()),
+		)
+	}
+	return validation.Validate(fields...)
+}
+
+func maxTTL(seconds int) *validation.Violation {
+	if max := int(MaxReservationTTL / time.Second); seconds > max {
+		return &validation.Violation{Code: "max", Message: fmt.Sprintf("must be at most %d", max)}
+	}
+	return nil
+}
+
+// FindReservation returns a reservation with its current status.
+func (service DefaultService) FindReservation(id string) (domain.Reservation, error) {
+	reservation, ok := service.Storage.Ledger.Reservation(id)
+	if !ok {
+		return domain.Reservation{}, ErrReservationNotFound
+	}
+	reservation.Status = reservation.StatusAt(service.Storage.now())
+	return reservation, nil
+}
+
+// Commit turns an active reservation into a sale of each of its items, all
+// of them in a single entry of the ledger.
+func (service DefaultService) Commit(id string) (domain.Reservation, error) {
+	return service.close(id, domain.ReservationCommitted)
+}
+
+// Release returns the stock held by an active reservation.
+func (service DefaultService) Release(id string) (domain.Reservation, error) {
+	return service.close(id, domain.ReservationReleased)
+}
+
+func (service DefaultService) close(id string, status domain.ReservationStatus) (domain.Reservation, error) {
+	entry, err := service.Storage.Transact(func(now time.Time) (Entry, error) {
+		reservation, ok := service.Storage.Ledger.Reservation(id)
+		if !ok {
+			return Entry{}, ErrReservationNotFound
+		}
+		if reservation.StatusAt(now) != domain.ReservationActive {
+			return Entry{}, fmt.Errorf("%w: it is %s", ErrReservationClosed, reservation.StatusAt(now))
+		}
+		reservation.Status = status
+
+		entry := Entry{Reservation: &reservation}
+		if status == domain.ReservationCommitted {
+			for _, item := range reservation.Items {
+				entry.Movements = append(entry.Movements, domain.Movement{
+					ProductID:     item.ID,
+					Kind:          domain.MovementSale,
+					Quantity:      -item.Quantity,
+					ReservationID: reservation.ID,
+				})
+			}
+		}
+		return entry, nil
+	})
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	return *entry.Reservation, nil
+}
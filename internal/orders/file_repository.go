@@ -0,0 +1,196 @@
+package orders
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// FileRepository keeps carts and orders in memory and, with a path, saves
+// them all to a file after every write. The file is gob encoded rather than
+// JSON: amounts keep their currency, which the JSON of the API only writes
+// once per order.
+type FileRepository struct {
+	mu     sync.RWMutex
+	path   string
+	carts  map[string]domain.Cart
+	orders map[string]domain.Order
+}
+
+// snapshot is the content of the file of a FileRepository.
+type snapshot struct {
+	Carts  map[string]domain.Cart
+	Orders map[string]domain.Order
+}
+
+// NewFileRepository loads the carts and orders saved at path, if any. An
+// empty path keeps them in memory only.
+func NewFileRepository(path string) (*FileRepository, error) {
+	repository := &FileRepository{
+		path:   path,
+		carts:  map[string]domain.Cart{},
+		orders: map[string]domain.Order{},
+	}
+	if path == "" {
+		return repository, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return repository, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening orders: %w", err)
+	}
+	var saved snapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("error decoding orders: %w", err)
+	}
+	for id, cart := range saved.Carts {
+		repository.carts[id] = cart
+	}
+	for id, order := range saved.Orders {
+		repository.orders[id] = order
+	}
+	return repository, nil
+}
+
+// save writes every cart and order to a temporary file, synced and renamed
+// over the file, so that a crash leaves either the old or the new content.
+func (repository *FileRepository) save() error {
+	if repository.path == "" {
+		return nil
+	}
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(snapshot{Carts: repository.carts, Orders: repository.orders}); err != nil {
+		return fmt.Errorf("error encoding orders: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(repository.path), filepath.Base(repository.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error writting orders: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data.Bytes()); err != nil {
+		temp.Close()
+		return fmt.Errorf("error writting orders: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("error syncing orders: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error writting orders: %w", err)
+	}
+	if err := os.Rename(temp.Name(), repository.path); err != nil {
+		return fmt.Errorf("error writting orders: %w", err)
+	}
+	return nil
+}
+
+func (repository *FileRepository) CreateCart(cart *domain.Cart) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	cart.Version = 1
+	repository.carts[cart.ID] = *cart
+	if err := repository.save(); err != nil {
+		delete(repository.carts, cart.ID)
+		return err
+	}
+	return nil
+}
+
+func (repository *FileRepository) FindCart(id string) (domain.Cart, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+	cart, ok := repository.carts[id]
+	if !ok {
+		return domain.Cart{}, ErrCartNotFound
+	}
+	// The items are the only part of a cart that is changed in place.
+	cart.Items = append([]domain.QuoteItem{}, cart.Items...)
+	return cart, nil
+}
+
+func (repository *FileRepository) UpdateCart(cart *domain.Cart) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	stored, ok := repository.carts[cart.ID]
+	if !ok {
+		return ErrCartNotFound
+	}
+	if cart.Version != 0 && cart.Version != stored.Version {
+		return ErrVersionConflict
+	}
+	cart.Version = stored.Version + 1
+	repository.carts[cart.ID] = *cart
+	if err := repository.save(); err != nil {
+		repository.carts[cart.ID] = stored
+		return err
+	}
+	return nil
+}
+
+func (repository *FileRepository) CreateOrder(order *domain.Order) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	order.Version = 1
+	repository.orders[order.ID] = *order
+	if err := repository.save(); err != nil {
+		delete(repository.orders, order.ID)
+		return err
+	}
+	return nil
+}
+
+func (repository *FileRepository) FindOrder(id string) (domain.Order, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+	order, ok := repository.orders[id]
+	if !ok {
+		return domain.Order{}, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (repository *FileRepository) UpdateOrder(order *domain.Order) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	stored, ok := repository.orders[order.ID]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if order.Version != 0 && order.Version != stored.Version {
+		return ErrVersionConflict
+	}
+	order.Version = stored.Version + 1
+	repository.orders[order.ID] = *order
+	if err := repository.save(); err != nil {
+		repository.orders[order.ID] = stored
+		return err
+	}
+	return nil
+}
+
+func (repository *FileRepository) Orders(query OrderQuery) ([]domain.Order, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+	orders := []domain.Order{}
+	for _, order := range repository.orders {
+		if query.Matches(order) {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].ID > orders[j].ID
+	})
+	return orders, nil
+}
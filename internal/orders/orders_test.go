@@ -0,0 +1,291 @@
+package orders
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestService returns a service selling a wine with 10 units at 100.00
+// and a cookie with 5 units at 50.00, priced with the default markups.
+func newTestService(t *testing.T) DefaultService {
+	storage, err := products.NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), products.IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	for _, product := range []domain.Product{
+		{Name: "Wine", Quantity: 10, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(10000, "ARS")},
+		{Name: "Cookie", Quantity: 5, CodeValue: "M7157", IsPublished: true, Price: domain.NewMoney(5000, "ARS")},
+	} {
+		require.NoError(t, storage.Create(&product))
+	}
+
+	ledger, err := inventory.OpenLedger("")
+	require.NoError(t, err)
+	stock, err := inventory.NewRepository(storage, ledger)
+	require.NoError(t, err)
+	now := func() time.Time { return testNow }
+	stock.Now = now
+	inventoryService := inventory.DefaultService{Storage: stock}
+
+	repository, err := NewFileRepository(filepath.Join(t.TempDir(), "orders.gob"))
+	require.NoError(t, err)
+	return DefaultService{
+		Storage:   repository,
+		Products:  products.DefaultService{Storage: stock, Now: now, Available: inventoryService.Available},
+		Inventory: inventoryService,
+		Now:       now,
+	}
+}
+
+// failingRepository fails the updates for which fail returns an error.
+type failingRepository struct {
+	Repository
+	fail func(cart *domain.Cart, order *domain.Order) error
+}
+
+func (repository failingRepository) UpdateCart(cart *domain.Cart) error {
+	if err := repository.fail(cart, nil); err != nil {
+		return err
+	}
+	return repository.Repository.UpdateCart(cart)
+}
+
+func (repository failingRepository) UpdateOrder(order *domain.Order) error {
+	if err := repository.fail(nil, order); err != nil {
+		return err
+	}
+	return repository.Repository.UpdateOrder(order)
+}
+
+func TestDefaultService_Cart(t *testing.T) {
+	t.Run("should price the items of the cart", func(t *testing.T) {
+		service := newTestService(t)
+
+		cart, err := service.CreateCart([]domain.QuoteItem{{ID: 1, Quantity: 1}})
+		require.NoError(t, err)
+		cart, err = service.AddItem(cart.ID, domain.QuoteItem{ID: 2, Quantity: 2})
+		require.NoError(t, err)
+		cart, err = service.AddItem(cart.ID, domain.QuoteItem{ID: 1, Quantity: 1})
+		require.NoError(t, err)
+
+		assert.Equal(t, []domain.QuoteItem{{ID: 1, Quantity: 2}, {ID: 2, Quantity: 2}}, cart.Items)
+		assert.Equal(t, domain.NewMoney(30000, "ARS"), cart.Quote.Subtotal)
+		assert.Equal(t, domain.NewMoney(36300, "ARS"), cart.Quote.TotalPrice)
+
+		cart, err = service.RemoveItem(cart.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.QuoteItem{{ID: 2, Quantity: 2}}, cart.Items)
+		_, err = service.RemoveItem(cart.ID, 1)
+		assert.ErrorIs(t, err, ErrCartLineNotFound)
+	})
+
+	t.Run("should reject unknown products and carts", func(t *testing.T) {
+		service := newTestService(t)
+
+		_, err := service.CreateCart([]domain.QuoteItem{{ID: 99, Quantity: 1}})
+		assert.ErrorIs(t, err, products.ErrProductNotFound)
+		_, err = service.FindCart("unknown")
+		assert.ErrorIs(t, err, ErrCartNotFound)
+	})
+}
+
+func TestDefaultService_Checkout(t *testing.T) {
+	t.Run("should take the stock and create a pending order once", func(t *testing.T) {
+		service := newTestService(t)
+		cart, err := service.CreateCart([]domain.QuoteItem{{ID: 1, Quantity: 3}, {ID: 2, Quantity: 5}})
+		require.NoError(t, err)
+
+		order, err := service.Checkout(cart.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderPending, order.Status)
+		assert.Equal(t, cart.ID, order.CartID)
+		assert.Len(t, order.Lines, 2)
+		assert.Equal(t, domain.NewMoney(55000, "ARS"), order.Subtotal)
+		assert.Equal(t, domain.NewMoney(66550, "ARS"), order.TotalPrice)
+		assert.Equal(t, []domain.OrderEvent{{Status: domain.OrderPending, At: testNow}}, order.History)
+
+		stock, err := service.Inventory.Stock(2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.Stock{ProductID: 2}, stock)
+		checkedOut, err := service.FindCart(cart.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CartCheckedOut, checkedOut.Status)
+		assert.Equal(t, order.ID, checkedOut.OrderID)
+
+		_, err = service.Checkout(cart.ID)
+		assert.ErrorIs(t, err, ErrCartCheckedOut)
+		_, err = service.AddItem(cart.ID, domain.QuoteItem{ID: 1, Quantity: 1})
+		assert.ErrorIs(t, err, ErrCartCheckedOut)
+	})
+
+	t.Run("should return the order when the cart cannot be linked to it", func(t *testing.T) {
+		service := newTestService(t)
+		service.Storage = failingRepository{Repository: service.Storage, fail: func(cart *domain.Cart, _ *domain.Order) error {
+			if cart != nil && cart.OrderID != "" {
+				return errors.New("disk full")
+			}
+			return nil
+		}}
+		cart, err := service.CreateCart([]domain.QuoteItem{{ID: 1, Quantity: 3}})
+		require.NoError(t, err)
+
+		order, err := service.Checkout(cart.ID)
+		require.NoError(t, err)
+		_, err = service.FindOrder(order.ID)
+		assert.NoError(t, err)
+		_, err = service.Checkout(cart.ID)
+		assert.ErrorIs(t, err, ErrCartCheckedOut)
+	})
+
+	t.Run("should take nothing when an item is unavailable", func(t *testing.T) {
+		service := newTestService(t)
+		cart, err := service.CreateCart([]domain.QuoteItem{{ID: 1, Quantity: 3}, {ID: 2, Quantity: 6}})
+		require.NoError(t, err)
+
+		_, err = service.Checkout(cart.ID)
+		assert.ErrorIs(t, err, ErrItemsUnavailable)
+		assert.EqualError(t, err, "items unavailable: product 2 is short of stock")
+
+		stock, err := service.Inventory.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, 10, stock.Available)
+		open, err := service.FindCart(cart.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CartOpen, open.Status)
+	})
+
+	t.Run("should not check out an empty cart", func(t *testing.T) {
+		service := newTestService(t)
+		cart, err := service.CreateCart(nil)
+		require.NoError(t, err)
+
+		_, err = service.Checkout(cart.ID)
+		assert.ErrorIs(t, err, ErrEmptyCart)
+	})
+}
+
+func TestDefaultService_UpdateStatus(t *testing.T) {
+	checkout := func(t *testing.T, service DefaultService) domain.Order {
+		cart, err := service.CreateCart([]domain.QuoteItem{{ID: 1, Quantity: 4}})
+		require.NoError(t, err)
+		order, err := service.Checkout(cart.ID)
+		require.NoError(t, err)
+		return order
+	}
+
+	t.Run("should follow the status transitions", func(t *testing.T) {
+		service := newTestService(t)
+		order := checkout(t, service)
+
+		order, err := service.UpdateStatus(order.ID, domain.OrderPaid)
+		require.NoError(t, err)
+		order, err = service.UpdateStatus(order.ID, domain.OrderShipped)
+		require.NoError(t, err)
+		assert.Len(t, order.History, 3)
+
+		_, err = service.UpdateStatus(order.ID, domain.OrderCancelled)
+		assert.ErrorIs(t, err, ErrInvalidTransition)
+		_, err = service.UpdateStatus(order.ID, "lost")
+		assert.Error(t, err)
+	})
+
+	t.Run("should return the items of a cancelled order to the stock", func(t *testing.T) {
+		service := newTestService(t)
+		order := checkout(t, service)
+
+		_, err := service.UpdateStatus(order.ID, domain.OrderCancelled)
+		require.NoError(t, err)
+
+		stock, err := service.Inventory.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, 10, stock.OnHand)
+		movements, err := service.Inventory.Movements(1)
+		require.NoError(t, err)
+		assert.Equal(t, domain.MovementReturn, movements[len(movements)-1].Kind)
+	})
+
+	t.Run("should leave the order and the stock when a cancellation fails", func(t *testing.T) {
+		service := newTestService(t)
+		order := checkout(t, service)
+		fail := errors.New("disk full")
+		service.Storage = failingRepository{Repository: service.Storage, fail: func(*domain.Cart, *domain.Order) error {
+			return fail
+		}}
+
+		_, err := service.UpdateStatus(order.ID, domain.OrderCancelled)
+		assert.ErrorIs(t, err, fail)
+		stock, err := service.Inventory.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, 6, stock.OnHand)
+		pending, err := service.FindOrder(order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderPending, pending.Status)
+
+		service.Storage = service.Storage.(failingRepository).Repository
+		_, err = service.UpdateStatus(order.ID, domain.OrderCancelled)
+		require.NoError(t, err)
+		stock, err = service.Inventory.Stock(1)
+		require.NoError(t, err)
+		assert.Equal(t, 10, stock.OnHand)
+	})
+
+	t.Run("should query the orders by status", func(t *testing.T) {
+		service := newTestService(t)
+		first := checkout(t, service)
+		second := checkout(t, service)
+		_, err := service.UpdateStatus(first.ID, domain.OrderPaid)
+		require.NoError(t, err)
+
+		paid, err := service.Orders(OrderQuery{Status: domain.OrderPaid})
+		require.NoError(t, err)
+		require.Len(t, paid, 1)
+		assert.Equal(t, first.ID, paid[0].ID)
+
+		all, err := service.Orders(OrderQuery{From: domain.NewDate(2022, time.January, 1), To: domain.NewDate(2022, time.January, 1)})
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+		none, err := service.Orders(OrderQuery{From: domain.NewDate(2022, time.January, 2)})
+		require.NoError(t, err)
+		assert.Empty(t, none)
+		assert.NotEqual(t, first.ID, second.ID)
+	})
+}
+
+func TestFileRepository(t *testing.T) {
+	t.Run("should keep the orders with their currency across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.gob")
+		repository, err := NewFileRepository(path)
+		require.NoError(t, err)
+		order := domain.Order{
+			ID:         "01H8XGKA2JY1N6V1JPSZCB5ZBM",
+			Lines:      []domain.PriceLine{{ProductID: 1, Quantity: 1, UnitPrice: domain.NewMoney(1500, "JPY"), Total: domain.NewMoney(1500, "JPY")}},
+			TotalPrice: domain.NewMoney(1500, "JPY"),
+			Status:     domain.OrderPending,
+			CreatedAt:  testNow,
+		}
+		require.NoError(t, repository.CreateOrder(&order))
+		order.Status = domain.OrderPaid
+		require.NoError(t, repository.UpdateOrder(&order))
+
+		stale := order
+		stale.Version = 1
+		assert.ErrorIs(t, repository.UpdateOrder(&stale), ErrVersionConflict)
+
+		reopened, err := NewFileRepository(path)
+		require.NoError(t, err)
+		stored, err := reopened.FindOrder(order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(1500, "JPY"), stored.TotalPrice)
+		assert.Equal(t, domain.OrderPaid, stored.Status)
+		assert.Equal(t, 2, stored.Version)
+	})
+}
@@ -0,0 +1,52 @@
+// Package orders turns carts of products into orders: carts priced with the
+// consumer pricing, a checkout that takes the stock through the inventory
+// and orders that only change status afterwards.
+package orders
+
+import (
+	"errors"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+var (
+	ErrCartNotFound    = errors.New("cart not found")
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrVersionConflict = errors.New("cart or order was modified")
+)
+
+// OrderQuery selects orders by status and by the day they were created in,
+// both bounds included. Zero values select every order.
+type OrderQuery struct {
+	Status   domain.OrderStatus
+	From, To domain.Date
+}
+
+// Matches reports whether order is selected by query.
+func (query OrderQuery) Matches(order domain.Order) bool {
+	if query.Status != "" && order.Status != query.Status {
+		return false
+	}
+	created := domain.DateOf(order.CreatedAt)
+	if !query.From.IsZero() && created.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && created.After(query.To) {
+		return false
+	}
+	return true
+}
+
+// Repository stores carts and orders. Like products.Repository, every write
+// increments the Version, starting at 1 on create, and updates with a
+// non-zero Version are compare-and-swaps that fail with ErrVersionConflict.
+type Repository interface {
+	CreateCart(cart *domain.Cart) error
+	FindCart(id string) (domain.Cart, error)
+	UpdateCart(cart *domain.Cart) error
+	CreateOrder(order *domain.Order) error
+	FindOrder(id string) (domain.Order, error)
+	UpdateOrder(order *domain.Order) error
+	// Orders returns the orders selected by query, newest first.
+	Orders(query OrderQuery) ([]domain.Order, error)
+}
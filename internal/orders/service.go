@@ -0,0 +1,373 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/inventory"
+	"github.com/Andrea-Reyna/go-web/internal/products"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+var (
+	ErrCartLineNotFound  = errors.New("product not in cart")
+	ErrCartCheckedOut    = errors.New("cart already checked out")
+	ErrEmptyCart         = errors.New("cart is empty")
+	ErrItemsUnavailable  = errors.New("items unavailable")
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
+type Service interface {
+	CreateCart(items []domain.QuoteItem) (domain.PricedCart, error)
+	FindCart(id string) (domain.PricedCart, error)
+	AddItem(cartID string, item domain.QuoteItem) (domain.PricedCart, error)
+	RemoveItem(cartID string, productID int) (domain.PricedCart, error)
+	Checkout(cartID string) (domain.Order, error)
+	FindOrder(id string) (domain.Order, error)
+	Orders(query OrderQuery) ([]domain.Order, error)
+	UpdateStatus(id string, status domain.OrderStatus) (domain.Order, error)
+}
+
+type DefaultService struct {
+	Storage   Repository
+	Products  products.Service
+	Inventory inventory.Service
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+}
+
+func (service DefaultService) now() time.Time {
+	if service.Now == nil {
+		return time.Now()
+	}
+	return service.Now()
+}
+
+// writeAttempts bounds how many times a change of a cart or an order is
+// reapplied after losing a race with another write.
+const writeAttempts = 3
+
+func (service DefaultService) CreateCart(items []domain.QuoteItem) (domain.PricedCart, error) {
+	var merged []domain.QuoteItem
+	for i, item := range items {
+		if err := service.validateItem(item); err != nil {
+			var violations validation.Errors
+			if errors.As(err, &violations) {
+				return domain.PricedCart{}, violations.Prefix(fmt.Sprintf("items[%d].", i))
+			}
+			return domain.PricedCart{}, err
+		}
+		merged = addItem(merged, item)
+	}
+	now := service.now()
+	cart := domain.Cart{
+		ID:        products.IDSchemeULID.NewUID(),
+		Items:     merged,
+		Status:    domain.CartOpen,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if cart.Items == nil {
+		cart.Items = []domain.QuoteItem{}
+	}
+	if err := service.Storage.CreateCart(&cart); err != nil {
+		return domain.PricedCart{}, err
+	}
+	return service.price(cart)
+}
+
+// validateItem checks the quantity of item and that its product exists.
+func (service DefaultService) validateItem(item domain.QuoteItem) error {
+	err := validation.Validate(
+		validation.Field("id", item.ID, validation.Positive[int]()),
+		validation.Field("qty", item.Quantity, validation.Positive[int]()),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = service.Products.FindById(item.ID)
+	return err
+}
+
+func addItem(items []domain.QuoteItem, item domain.QuoteItem) []domain.QuoteItem {
+	for i := range items {
+		if items[i].ID == item.ID {
+			items[i].Quantity += item.Quantity
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+// FindCart returns a cart priced at current prices. The quote lists the
+// items that could not be checked out right now as unavailable.
+func (service DefaultService) FindCart(id string) (domain.PricedCart, error) {
+	cart, err := service.Storage.FindCart(id)
+	if err != nil {
+		return domain.PricedCart{}, err
+	}
+	return service.price(cart)
+}
+
+func (service DefaultService) price(cart domain.Cart) (domain.PricedCart, error) {
+	priced := domain.PricedCart{Cart: cart, Quote: domain.Quote{Lines: []domain.PriceLine{}, Unavailable: []domain.UnavailableItem{}}}
+	if len(cart.Items) == 0 {
+		return priced, nil
+	}
+	quote, err := service.Products.Quote(cart.Items)
+	if err != nil {
+		return domain.PricedCart{}, err
+	}
+	priced.Quote = quote
+	return priced, nil
+}
+
+// AddItem adds the quantity of item to the cart.
+func (service DefaultService) AddItem(cartID string, item domain.QuoteItem) (domain.PricedCart, error) {
+	if err := service.validateItem(item); err != nil {
+		return domain.PricedCart{}, err
+	}
+	return service.changeCart(cartID, func(cart *domain.Cart) error {
+		cart.Items = addItem(cart.Items, item)
+		return nil
+	})
+}
+
+// RemoveItem removes a product from the cart, whatever its quantity.
+func (service DefaultService) RemoveItem(cartID string, productID int) (domain.PricedCart, error) {
+	return service.changeCart(cartID, func(cart *domain.Cart) error {
+		for i, item := range cart.Items {
+			if item.ID == productID {
+				cart.Items = append(cart.Items[:i], cart.Items[i+1:]...)
+				return nil
+			}
+		}
+		return ErrCartLineNotFound
+	})
+}
+
+// changeCart applies change to an open cart, again on the latest version of
+// the cart if another write got first.
+func (service DefaultService) changeCart(cartID string, change func(cart *domain.Cart) error) (domain.PricedCart, error) {
+	for attempt := 1; ; attempt++ {
+		cart, err := service.Storage.FindCart(cartID)
+		if err != nil {
+			return domain.PricedCart{}, err
+		}
+		if cart.Status != domain.CartOpen {
+			return domain.PricedCart{}, ErrCartCheckedOut
+		}
+		if err := change(&cart); err != nil {
+			return domain.PricedCart{}, err
+		}
+		cart.UpdatedAt = service.now()
+		err = service.Storage.UpdateCart(&cart)
+		if errors.Is(err, ErrVersionConflict) && attempt < writeAttempts {
+			continue
+		}
+		if err != nil {
+			return domain.PricedCart{}, err
+		}
+		return service.price(cart)
+	}
+}
+
+// Checkout turns an open cart into a pending order. Every item must be
+// available: the stock of all of them is taken at once through a
+// reservation that is committed right away, and given back if the order
+// cannot be saved. The cart is checked out at most once.
+func (service DefaultService) Checkout(cartID string) (domain.Order, error) {
+	cart, err := service.Storage.FindCart(cartID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	if cart.Status != domain.CartOpen {
+		return domain.Order{}, ErrCartCheckedOut
+	}
+	if len(cart.Items) == 0 {
+		return domain.Order{}, ErrEmptyCart
+	}
+	quote, err := service.Products.Quote(cart.Items)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	if len(quote.Unavailable) > 0 {
+		return domain.Order{}, unavailable(quote.Unavailable)
+	}
+
+	// Claiming the cart first keeps a concurrent checkout or change of the
+	// cart from going through.
+	cart.Status = domain.CartCheckedOut
+	cart.UpdatedAt = service.now()
+	if err := service.Storage.UpdateCart(&cart); err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			return domain.Order{}, ErrCartCheckedOut
+		}
+		return domain.Order{}, err
+	}
+	reopen := func() {
+		cart.Status = domain.CartOpen
+		if err := service.Storage.UpdateCart(&cart); err != nil {
+			log.Printf("error reopening cart %s: %v", cart.ID, err)
+		}
+	}
+
+	reservation, err := service.Inventory.Reserve(cart.Items, 0)
+	if err != nil {
+		reopen()
+		if errors.Is(err, inventory.ErrInsufficientStock) {
+			return domain.Order{}, fmt.Errorf("%w: %s", ErrItemsUnavailable, err)
+		}
+		return domain.Order{}, err
+	}
+	if _, err := service.Inventory.Commit(reservation.ID); err != nil {
+		service.Inventory.Release(reservation.ID)
+		reopen()
+		return domain.Order{}, err
+	}
+
+	now := service.now()
+	order := domain.Order{
+		ID:            products.IDSchemeULID.NewUID(),
+		CartID:        cart.ID,
+		ReservationID: reservation.ID,
+		Lines:         quote.Lines,
+		Subtotal:      quote.Subtotal,
+		Surcharges:    quote.Surcharges,
+		TotalPrice:    quote.TotalPrice,
+		Status:        domain.OrderPending,
+		History:       []domain.OrderEvent{{Status: domain.OrderPending, At: now}},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := service.Storage.CreateOrder(&order); err != nil {
+		if err := service.restock(order, "checkout of cart "+cart.ID+" failed"); err != nil {
+			log.Printf("error returning the items of cart %s to the stock: %v", cart.ID, err)
+		}
+		reopen()
+		return domain.Order{}, err
+	}
+
+	// The order exists and holds its stock by now, so a cart that cannot
+	// link to it is only logged: the order refers to the cart anyway.
+	cart.OrderID = order.ID
+	if err := service.Storage.UpdateCart(&cart); err != nil {
+		log.Printf("error linking cart %s to order %s: %v", cart.ID, order.ID, err)
+	}
+	return order, nil
+}
+
+// unavailableReasons describe the reasons of domain.UnavailableItem.
+var unavailableReasons = map[string]string{
+	domain.UnavailableNotFound:          "not found",
+	domain.UnavailableUnpublished:       "not published",
+	domain.UnavailableInsufficientStock: "short of stock",
+}
+
+func unavailable(items []domain.UnavailableItem) error {
+	descriptions := make([]string, len(items))
+	for i, item := range items {
+		descriptions[i] = fmt.Sprintf("product %d is %s", item.ID, unavailableReasons[item.Reason])
+	}
+	return fmt.Errorf("%w: %s", ErrItemsUnavailable, strings.Join(descriptions, ", "))
+}
+
+// restock returns the items of order to the stock, all of them or none: the
+// items returned before one that cannot be are taken back.
+func (service DefaultService) restock(order domain.Order, reason string) error {
+	for i, line := range order.Lines {
+		_, err := service.Inventory.Record(domain.Movement{
+			ProductID: line.ProductID,
+			Kind:      domain.MovementReturn,
+			Quantity:  line.Quantity,
+			Reason:    reason,
+		})
+		if err != nil {
+			service.unstock(order.Lines[:i], "return of order "+order.ID+" failed")
+			return err
+		}
+	}
+	return nil
+}
+
+// unstock takes lines returned by restock back from the stock. The lines
+// that cannot be taken back are only logged, since the stock is then short
+// of them anyway.
+func (service DefaultService) unstock(lines []domain.PriceLine, reason string) {
+	for _, line := range lines {
+		_, err := service.Inventory.Record(domain.Movement{
+			ProductID: line.ProductID,
+			Kind:      domain.MovementSale,
+			Quantity:  line.Quantity,
+			Reason:    reason,
+		})
+		if err != nil {
+			log.Printf("error taking product %d back from the stock: %v", line.ProductID, err)
+		}
+	}
+}
+
+func (service DefaultService) FindOrder(id string) (domain.Order, error) {
+	return service.Storage.FindOrder(id)
+}
+
+func (service DefaultService) Orders(query OrderQuery) ([]domain.Order, error) {
+	if err := validation.Validate(validation.Field("status", query.Status, knownStatus)); err != nil {
+		return nil, err
+	}
+	return service.Storage.Orders(query)
+}
+
+func knownStatus(status domain.OrderStatus) *validation.Violation {
+	switch status {
+	case "", domain.OrderPending, domain.OrderPaid, domain.OrderShipped, domain.OrderCancelled:
+		return nil
+	}
+	return &validation.Violation{Code: "enum", Message: "must be pending, paid, shipped or cancelled"}
+}
+
+// UpdateStatus moves an order to status, see domain.CanTransition. The items
+// of a cancelled order go back to the stock.
+func (service DefaultService) UpdateStatus(id string, status domain.OrderStatus) (domain.Order, error) {
+	err := validation.Validate(validation.Field("status", status, validation.Required[domain.OrderStatus](), knownStatus))
+	if err != nil {
+		return domain.Order{}, err
+	}
+	for attempt := 1; ; attempt++ {
+		order, err := service.Storage.FindOrder(id)
+		if err != nil {
+			return domain.Order{}, err
+		}
+		if !domain.CanTransition(order.Status, status) {
+			return domain.Order{}, fmt.Errorf("%w: from %s to %s", ErrInvalidTransition, order.Status, status)
+		}
+		// The items of a cancelled order are returned before it is saved,
+		// and taken back when it cannot be, so that a cancellation that
+		// fails leaves the order and the stock as they were.
+		cancelled := status == domain.OrderCancelled
+		if cancelled {
+			if err := service.restock(order, "order "+order.ID+" cancelled"); err != nil {
+				return domain.Order{}, err
+			}
+		}
+		now := service.now()
+		order.Status = status
+		order.History = append(append([]domain.OrderEvent{}, order.History...), domain.OrderEvent{Status: status, At: now})
+		order.UpdatedAt = now
+		err = service.Storage.UpdateOrder(&order)
+		if err != nil && cancelled {
+			service.unstock(order.Lines, "cancellation of order "+order.ID+" failed")
+		}
+		if errors.Is(err, ErrVersionConflict) && attempt < writeAttempts {
+			continue
+		}
+		if err != nil {
+			return domain.Order{}, err
+		}
+		return order, nil
+	}
+}
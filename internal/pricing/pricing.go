@@ -0,0 +1,107 @@
+package pricing
+
+import (
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// Line is some units of a product to price.
+type Line struct {
+	ProductID int
+	Name      string
+	// Categories are the paths of the categories of the product, e.g.
+	// beverages/wine.
+	Categories []string
+	Quantity   int
+	UnitPrice  domain.Money
+}
+
+// Quote is the price of some lines.
+type Quote struct {
+	Lines    []domain.PriceLine
+	Subtotal domain.Money
+	Total    domain.Money
+}
+
+// Price prices lines on day, rounding every adjustment with mode. Tiers by
+// number of items count the units of all the lines together.
+func (rules *Rules) Price(lines []Line, day domain.Date, mode domain.RoundingMode) (Quote, error) {
+	items := 0
+	for _, line := range lines {
+		items += line.Quantity
+	}
+
+	var quote Quote
+	quote.Lines = make([]domain.PriceLine, 0, len(lines))
+	for _, line := range lines {
+		priced, err := rules.priceLine(line, items, day, mode)
+		if err != nil {
+			return Quote{}, err
+		}
+		quote.Lines = append(quote.Lines, priced)
+		if quote.Subtotal, err = quote.Subtotal.Add(priced.Subtotal); err != nil {
+			return Quote{}, err
+		}
+		if quote.Total, err = quote.Total.Add(priced.Total); err != nil {
+			return Quote{}, err
+		}
+	}
+	return quote, nil
+}
+
+// priceLine adds up the percentages of the rules of a kind, rather than
+// compounding them. It fails with domain.ErrInvalidAmount when an amount of
+// the line is out of range.
+func (rules *Rules) priceLine(line Line, items int, day domain.Date, mode domain.RoundingMode) (domain.PriceLine, error) {
+	subtotal, err := line.UnitPrice.Mul(domain.NewRate(int64(line.Quantity), 1), mode)
+	if err != nil {
+		return domain.PriceLine{}, err
+	}
+	priced := domain.PriceLine{
+		ProductID:   line.ProductID,
+		Name:        line.Name,
+		Quantity:    line.Quantity,
+		UnitPrice:   line.UnitPrice,
+		Subtotal:    subtotal,
+		Adjustments: []domain.PriceAdjustment{},
+		Total:       subtotal,
+	}
+
+	// The rules are sorted by kind: each kind applies to the total the
+	// previous kinds left, until an exclusive rule closes it.
+	base, kind, closed := subtotal, Kind(""), false
+	for _, rule := range rules.rules {
+		if rule.Kind != kind {
+			base, kind, closed = priced.Total, rule.Kind, false
+		}
+		if closed || !rule.applies(line, items, day) || rule.Stacking == Exclusive && appliedKind(priced, rule.Kind) {
+			continue
+		}
+		amount, err := base.Mul(rule.rate, mode)
+		if err != nil {
+			return domain.PriceLine{}, err
+		}
+		if rule.Kind == KindDiscount {
+			amount.Amount = -amount.Amount
+		}
+		priced.Adjustments = append(priced.Adjustments, domain.PriceAdjustment{
+			Rule:    rule.Name,
+			Kind:    string(rule.Kind),
+			Percent: rule.Percent,
+			Amount:  amount,
+		})
+		if priced.Total, err = priced.Total.Add(amount); err != nil {
+			return domain.PriceLine{}, err
+		}
+		closed = rule.Stacking == Exclusive
+	}
+	return priced, nil
+}
+
+func appliedKind(line domain.PriceLine, kind Kind) bool {
+	for _, adjustment := range line.Adjustments {
+		if adjustment.Kind == string(kind) {
+			return true
+		}
+	}
+	return false
+}
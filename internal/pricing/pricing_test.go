@@ -0,0 +1,115 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ars(amount int64) domain.Money {
+	return domain.NewMoney(amount, "ARS")
+}
+
+func TestDefaultRules(t *testing.T) {
+	// The markups used to skip exactly 10 and 20 items.
+	cases := map[int]int64{1: 121, 10: 1210, 11: 1287, 20: 2340, 21: 2415}
+	for items, total := range cases {
+		quote, err := DefaultRules().Price([]Line{{ProductID: 1, Quantity: items, UnitPrice: ars(100)}}, domain.NewDate(2022, time.January, 1), domain.RoundHalfUp)
+		require.NoError(t, err)
+		assert.Equal(t, ars(total), quote.Total, items)
+	}
+}
+
+func TestRules_Price(t *testing.T) {
+	rules, err := Load("testdata/rules.yaml")
+	require.NoError(t, err)
+	lines := []Line{
+		{ProductID: 2, Name: "Pineapple", Categories: []string{"food/fruit"}, Quantity: 1, UnitPrice: ars(10000)},
+		{ProductID: 3, Name: "Soap", Quantity: 2, UnitPrice: ars(5000)},
+	}
+
+	t.Run("should apply the rules by kind and priority", func(t *testing.T) {
+		quote, err := rules.Price(lines, domain.NewDate(2022, time.January, 12), domain.RoundHalfUp)
+		require.NoError(t, err)
+
+		expected := []domain.PriceLine{
+			{
+				ProductID: 2, Name: "Pineapple", Quantity: 1, UnitPrice: ars(10000), Subtotal: ars(10000),
+				Adjustments: []domain.PriceAdjustment{
+					{Rule: "pineapple week", Kind: "discount", Percent: "50", Amount: ars(-5000)},
+					{Rule: "up to 10 items", Kind: "markup", Percent: "21", Amount: ars(1050)},
+					{Rule: "reduced VAT", Kind: "tax", Percent: "10.5", Amount: ars(635)},
+				},
+				Total: ars(6685),
+			},
+			{
+				ProductID: 3, Name: "Soap", Quantity: 2, UnitPrice: ars(5000), Subtotal: ars(10000),
+				Adjustments: []domain.PriceAdjustment{
+					{Rule: "summer sale", Kind: "discount", Percent: "10", Amount: ars(-1000)},
+					{Rule: "up to 10 items", Kind: "markup", Percent: "21", Amount: ars(1890)},
+					{Rule: "VAT", Kind: "tax", Percent: "21", Amount: ars(2287)},
+				},
+				Total: ars(13177),
+			},
+		}
+		assert.Equal(t, expected, quote.Lines)
+		assert.Equal(t, ars(20000), quote.Subtotal)
+		assert.Equal(t, ars(19862), quote.Total)
+	})
+
+	t.Run("should skip promotions out of their validity window", func(t *testing.T) {
+		quote, err := rules.Price(lines[1:], domain.NewDate(2022, time.April, 1), domain.RoundHalfUp)
+		require.NoError(t, err)
+		assert.Len(t, quote.Lines[0].Adjustments, 2)
+		assert.Equal(t, ars(14641), quote.Total)
+	})
+
+	t.Run("should fail on lines of different currencies", func(t *testing.T) {
+		mixed := append([]Line{{ProductID: 4, Quantity: 1, UnitPrice: domain.NewMoney(100, "USD")}}, lines...)
+		_, err := rules.Price(mixed, domain.NewDate(2022, time.April, 1), domain.RoundHalfUp)
+		assert.ErrorIs(t, err, domain.ErrCurrencyMismatch)
+	})
+
+	t.Run("should fail on amounts out of range", func(t *testing.T) {
+		huge := []Line{{ProductID: 4, Quantity: 3, UnitPrice: ars(math.MaxInt64 / 2)}}
+		_, err := rules.Price(huge, domain.NewDate(2022, time.April, 1), domain.RoundHalfUp)
+		assert.ErrorIs(t, err, domain.ErrInvalidAmount)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("should read JSON", func(t *testing.T) {
+		rules, err := Parse([]byte(`[
+			{"name": "loyalty", "kind": "discount", "percent": "5", "stacking": "exclusive"},
+			{"name": "coupon", "kind": "discount", "percent": 20, "priority": 1}
+		]`))
+		require.NoError(t, err)
+
+		// The coupon goes first, so the exclusive loyalty discount does not apply.
+		quote, err := rules.Price([]Line{{ProductID: 1, Quantity: 1, UnitPrice: ars(1000)}}, domain.NewDate(2022, time.January, 1), domain.RoundHalfUp)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.PriceAdjustment{{Rule: "coupon", Kind: "discount", Percent: "20", Amount: ars(-200)}}, quote.Lines[0].Adjustments)
+	})
+
+	t.Run("should reject invalid rules", func(t *testing.T) {
+		files := []string{
+			`- {name: a, kind: rebate, percent: 5}`,
+			`- {kind: tax, percent: 5}`,
+			`- {name: a, kind: tax, percent: -5}`,
+			`- {name: a, kind: tax, percent: abc}`,
+			`- {name: a, kind: tax, percent: 5, stacking: never}`,
+			`- {name: a, kind: tax, percent: 5, min_items: 10, max_items: 5}`,
+			`- {name: a, kind: tax, percent: 5, from: 2022-02-01, until: 2022-01-01}`,
+			`- {name: a, kind: tax, percent: 5, from: 01/13/2022}`,
+			`{name: a}`,
+		}
+		for _, file := range files {
+			_, err := Parse([]byte(file))
+			assert.ErrorIs(t, err, ErrInvalidRules, file)
+		}
+	})
+}
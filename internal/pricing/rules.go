@@ -0,0 +1,239 @@
+// Package pricing computes consumer prices from rules loaded from a YAML or
+// JSON file: discounts, markups by number of items and taxes by category.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidRules = errors.New("invalid pricing rules")
+
+// Kind is what a rule does to a line. Kinds apply in this order, each one to
+// the line as the previous kinds left it: discounts, then markups, then
+// taxes.
+type Kind string
+
+const (
+	KindDiscount Kind = "discount"
+	KindMarkup   Kind = "markup"
+	KindTax      Kind = "tax"
+)
+
+var kinds = []Kind{KindDiscount, KindMarkup, KindTax}
+
+// Stacking tells whether a rule combines with other rules of its kind.
+type Stacking string
+
+const (
+	// Stack rules add up with the other stack rules of their kind.
+	Stack Stacking = "stack"
+	// Exclusive rules only apply to lines no rule of their kind applied to
+	// yet, and then no other rule of their kind does.
+	Exclusive Stacking = "exclusive"
+)
+
+// Rule is a percentage of the line added by a kind of rule when its
+// conditions hold. Empty conditions always hold.
+type Rule struct {
+	Name string
+	Kind Kind
+	// Percent is the percentage as written in the rules file, e.g. 10.5.
+	Percent  string
+	Priority int
+	Stacking Stacking
+
+	rate domain.Rate
+	// MinItems and MaxItems bound the number of items priced together,
+	// MaxItems 0 having no bound.
+	MinItems, MaxItems int
+	// Categories are paths of categories, e.g. beverages/wine, that hold
+	// for the products in them or in their descendants.
+	Categories []string
+	Products   []int
+	// From and Until bound the days the rule is valid, both included.
+	From, Until domain.Date
+}
+
+// ruleFile is a rule as written in a rules file.
+type ruleFile struct {
+	Name       string   `yaml:"name"`
+	Kind       Kind     `yaml:"kind"`
+	Percent    string   `yaml:"percent"`
+	Priority   int      `yaml:"priority"`
+	Stacking   Stacking `yaml:"stacking"`
+	MinItems   int      `yaml:"min_items"`
+	MaxItems   int      `yaml:"max_items"`
+	Categories []string `yaml:"categories"`
+	Products   []int    `yaml:"products"`
+	From       string   `yaml:"from"`
+	Until      string   `yaml:"until"`
+}
+
+// Rules are the pricing rules, sorted by kind and then by priority, highest
+// first, keeping the file order between rules of equal priority.
+type Rules struct {
+	rules []Rule
+}
+
+var (
+	defaultRules     *Rules
+	defaultRulesOnce sync.Once
+)
+
+// DefaultRules are the markups used when no rules file is configured: 21% up
+// to 10 items, 17% up to 20 and 15% from 21. They are parsed once and shared,
+// rules being only read once parsed.
+func DefaultRules() *Rules {
+	defaultRulesOnce.Do(func() {
+		rules, err := Parse([]byte(`
+- {name: "up to 10 items", kind: markup, percent: 21, min_items: 1, max_items: 10}
+- {name: "11 to 20 items", kind: markup, percent: 17, min_items: 11, max_items: 20}
+- {name: "more than 20 items", kind: markup, percent: 15, min_items: 21}
+`))
+		if err != nil {
+			panic(err)
+		}
+		defaultRules = rules
+	})
+	return defaultRules
+}
+
+// Load reads the rules in the YAML or JSON file at path.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pricing rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads a YAML or JSON list of rules, JSON being valid YAML. Errors
+// wrap ErrInvalidRules and name the rule at fault.
+func Parse(data []byte) (*Rules, error) {
+	var files []ruleFile
+	if err := yaml.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRules, err)
+	}
+	rules := make([]Rule, len(files))
+	for i, file := range files {
+		rule, err := file.compile()
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d (%s): %s", ErrInvalidRules, i, file.Name, err)
+		}
+		rules[i] = rule
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Kind != rules[j].Kind {
+			return kindOrder(rules[i].Kind) < kindOrder(rules[j].Kind)
+		}
+		return rules[i].Priority > rules[j].Priority
+	})
+	return &Rules{rules: rules}, nil
+}
+
+func kindOrder(kind Kind) int {
+	for i, candidate := range kinds {
+		if candidate == kind {
+			return i
+		}
+	}
+	return len(kinds)
+}
+
+func (file ruleFile) compile() (Rule, error) {
+	rule := Rule{
+		Name:       file.Name,
+		Kind:       file.Kind,
+		Percent:    file.Percent,
+		Priority:   file.Priority,
+		Stacking:   file.Stacking,
+		MinItems:   file.MinItems,
+		MaxItems:   file.MaxItems,
+		Categories: file.Categories,
+		Products:   file.Products,
+	}
+	if rule.Name == "" {
+		return Rule{}, errors.New("missing name")
+	}
+	if kindOrder(rule.Kind) == len(kinds) {
+		return Rule{}, fmt.Errorf("unknown kind %q", rule.Kind)
+	}
+	switch rule.Stacking {
+	case "":
+		rule.Stacking = Stack
+	case Stack, Exclusive:
+	default:
+		return Rule{}, fmt.Errorf("unknown stacking %q", rule.Stacking)
+	}
+	rate, err := domain.ParsePercent(file.Percent)
+	if err != nil || rate.Sign() < 0 {
+		return Rule{}, fmt.Errorf("percent must be a non-negative number, got %q", file.Percent)
+	}
+	rule.rate = rate
+	if rule.MinItems < 0 || rule.MaxItems < 0 || rule.MaxItems != 0 && rule.MaxItems < rule.MinItems {
+		return Rule{}, fmt.Errorf("invalid items range %d to %d", rule.MinItems, rule.MaxItems)
+	}
+	for _, bound := range []struct {
+		value string
+		date  *domain.Date
+	}{{file.From, &rule.From}, {file.Until, &rule.Until}} {
+		if bound.value == "" {
+			continue
+		}
+		if *bound.date, err = domain.ParseDate(bound.value); err != nil {
+			return Rule{}, err
+		}
+	}
+	if !rule.From.IsZero() && !rule.Until.IsZero() && rule.Until.Before(rule.From) {
+		return Rule{}, errors.New("until is before from")
+	}
+	return rule, nil
+}
+
+// applies tells whether the rule applies to line, priced with items in all
+// on day.
+func (rule Rule) applies(line Line, items int, day domain.Date) bool {
+	if items < rule.MinItems || rule.MaxItems != 0 && items > rule.MaxItems {
+		return false
+	}
+	if !rule.From.IsZero() && day.Before(rule.From) || !rule.Until.IsZero() && day.After(rule.Until) {
+		return false
+	}
+	if len(rule.Categories) > 0 && !inCategories(line.Categories, rule.Categories) {
+		return false
+	}
+	if len(rule.Products) > 0 && !contains(rule.Products, line.ProductID) {
+		return false
+	}
+	return true
+}
+
+// inCategories reports whether any of the category paths is one of
+// categories or a descendant of one of them, e.g. food/fruit of food.
+func inCategories(paths, categories []string) bool {
+	for _, path := range paths {
+		for _, category := range categories {
+			if path == category || strings.HasPrefix(path, category+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,99 @@
 package products
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/internal/pricing"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
 )
 
 type DefaultService struct {
 	Storage Repository
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+	// Rounding rounds computed amounts, like prices with taxes.
+	Rounding domain.RoundingMode
+	// Pricing prices consumer prices, pricing.DefaultRules when nil.
+	Pricing *pricing.Rules
+	// Available returns the stock of a product that can be sold, its
+	// Quantity when nil.
+	Available func(productID int) int
+	// Expiration returns the earliest expiration of the stock of a product,
+	// e.g. of its lots, reported by FindById. Its Expiration when nil.
+	Expiration func(product domain.Product) domain.Date
+	// CategoryPaths returns the paths of categories, for pricing rules by
+	// category. Products are priced without categories when nil.
+	CategoryPaths func(categories []int) []string
+	// Changed is called with every product written, after the write, and
+	// the product it replaced, nil for new products, e.g. to alert about
+	// low stock. It must not block.
+	Changed func(previous *domain.Product, current domain.Product)
+	// History records a revision of every product written, and nothing
+	// when nil.
+	History *History
+	// Author makes the writes of the service, see By.
+	Author Author
+}
+
+// By returns the service making its writes as author.
+func (service DefaultService) By(author Author) Service {
+	service.Author = author
+	return service
+}
+
+func (service DefaultService) now() time.Time {
+	if service.Now == nil {
+		return time.Now()
+	}
+	return service.Now()
+}
+
+func (service DefaultService) changed(previous *domain.Product, current domain.Product) {
+	if service.Changed != nil {
+		service.Changed(previous, current)
+	}
+}
+
+// written records the write of current by operation, which replaced
+// previous, nil for new products, and tells Changed about it.
+func (service DefaultService) written(operation Operation, previous *domain.Product, current domain.Product) {
+	service.record(Revision{Operation: operation}, previous, current)
+	service.changed(previous, current)
+}
+
+// Record records the write of current by operation made outside of the
+// service, e.g. of its stock by the inventory, which replaced previous, and
+// tells Changed about it, like the writes of the service.
+func (service DefaultService) Record(operation Operation, previous *domain.Product, current domain.Product) {
+	service.written(operation, previous, current)
+}
+
+// record appends revision of current, which replaced previous, to the
+// history. The write is already done, so a revision that cannot be recorded
+// is only logged.
+func (service DefaultService) record(revision Revision, previous *domain.Product, current domain.Product) {
+	if service.History == nil {
+		return
+	}
+	changes, err := diff(previous, current)
+	if err != nil {
+		log.Printf("error recording revision of product %d: %v", current.ID, err)
+		return
+	}
+	revision.ProductID = current.ID
+	revision.Actor = service.Author.Actor
+	revision.RequestID = service.Author.RequestID
+	revision.At = service.now()
+	revision.Changes = changes
+	revision.Snapshot = current
+	if _, err := service.History.Append(revision); err != nil {
+		log.Printf("error recording revision of product %d: %v", current.ID, err)
+	}
 }
 
 func (service DefaultService) Create(product *domain.Product) error {
@@ -19,10 +105,19 @@ func (service DefaultService) Create(product *domain.Product) error {
 	if err != nil {
 		return err
 	}
+	service.written(OperationCreate, nil, *product)
 	return nil
 }
 
+// Update replaces product, keeping whether it is a variant and its
+// attributes, which only change through UpdateVariant.
 func (service DefaultService) Update(product *domain.Product) error {
+	var previous *domain.Product
+	if stored, err := service.Storage.FindById(product.ID); err == nil {
+		product.ParentID = stored.ParentID
+		product.Attributes = stored.Attributes
+		previous = &stored
+	}
 	err := service.validations(product)
 	if err != nil {
 		return err
@@ -31,21 +126,153 @@ func (service DefaultService) Update(product *domain.Product) error {
 	if err != nil {
 		return err
 	}
+	service.written(OperationUpdate, previous, *product)
 	return nil
 }
 
+// patchAttempts bounds how many times Patch reapplies a change that lost a
+// race with another write.
+const patchAttempts = 3
+
+// Patch saves the result of change applied to product id. With a version,
+// the product must still have it; without one, a concurrent write makes
+// Patch read the product again and reapply change, which therefore must only
+// depend on the product it receives. The ID and version are not changeable.
+func (service DefaultService) Patch(id int, version int, change func(domain.Product) (domain.Product, error)) (domain.Product, error) {
+	previous, patched, err := service.patch(id, version, change)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	service.written(OperationPatch, &previous, patched)
+	return patched, nil
+}
+
+// patch saves the result of change like Patch, without recording it, and
+// returns the product it replaced too.
+func (service DefaultService) patch(id int, version int, change func(domain.Product) (domain.Product, error)) (domain.Product, domain.Product, error) {
+	for attempt := 1; ; attempt++ {
+		product, err := service.Storage.FindById(id)
+		if err != nil {
+			return domain.Product{}, domain.Product{}, ErrProductNotFound
+		}
+		if version != 0 && product.Version != version {
+			return domain.Product{}, domain.Product{}, ErrVersionConflict
+		}
+
+		patched, err := change(product)
+		if err != nil {
+			return domain.Product{}, domain.Product{}, err
+		}
+		patched.ID = product.ID
+		patched.Version = product.Version
+		if err := service.validations(&patched); err != nil {
+			return domain.Product{}, domain.Product{}, err
+		}
+
+		err = service.Storage.Update(&patched)
+		if err == ErrVersionConflict && version == 0 && attempt < patchAttempts {
+			continue
+		}
+		if err != nil {
+			return domain.Product{}, domain.Product{}, err
+		}
+		return product, patched, nil
+	}
+}
+
+// Import creates products, all of them or none when any is invalid: the
+// violations of every product are returned at once, with fields prefixed by
+// the index of the product, e.g. "[2].price". Code values must be unique
+// within the batch too.
+func (service DefaultService) Import(products []domain.Product) ([]domain.Product, error) {
+	stored, err := service.Storage.GetAll()
+	if err != nil {
+		return []domain.Product{}, err
+	}
+	trashed, err := service.Storage.Trashed()
+	if err != nil {
+		return []domain.Product{}, err
+	}
+	codes := map[string]bool{}
+	for _, product := range append(stored, trashed...) {
+		codes[product.CodeValue] = true
+	}
+
+	var violations validation.Errors
+	now := service.now()
+	for i, product := range products {
+		prefix := fmt.Sprintf("[%d].", i)
+		var productViolations validation.Errors
+		if errors.As(ValidateProduct(product, nil, now), &productViolations) {
+			violations = append(violations, productViolations.Prefix(prefix)...)
+		}
+		if codes[product.CodeValue] {
+			violations = append(violations, validation.Violation{Field: prefix + "code_value", Code: "unique", Message: "must be unique"})
+		}
+		codes[product.CodeValue] = true
+	}
+	if len(violations) > 0 {
+		return []domain.Product{}, violations
+	}
+
+	created := make([]domain.Product, 0, len(products))
+	for _, product := range products {
+		if err := service.Storage.Create(&product); err != nil {
+			service.undoImport(created)
+			return []domain.Product{}, err
+		}
+		created = append(created, product)
+	}
+	for _, product := range created {
+		service.written(OperationCreate, nil, product)
+	}
+	return created, nil
+}
+
+// undoImport deletes the products an import created before it failed. The
+// products that cannot be deleted are only logged, the error of the import
+// being the one to report.
+func (service DefaultService) undoImport(created []domain.Product) {
+	for _, product := range created {
+		if err := service.Storage.Delete(product.ID); err != nil {
+			log.Printf("error deleting product %d of a failed import: %v", product.ID, err)
+		}
+	}
+}
+
 func (service DefaultService) UpdateName(id int, name string) (domain.Product, error) {
-	if name == "" {
-		return domain.Product{}, ErrInvalidData
+	if err := ValidateName(name); err != nil {
+		return domain.Product{}, err
 	}
 
+	var previous *domain.Product
+	if stored, err := service.Storage.FindById(id); err == nil {
+		previous = &stored
+	}
 	newProduct, err := service.Storage.UpdateName(id, name)
 	if err != nil {
 		return domain.Product{}, err
 	}
+	service.written(OperationRename, previous, newProduct)
 	return newProduct, err
 }
 
+// SetCategories replaces the categories of product id, which must still have
+// version unless it is 0. The categories are not checked against the tree,
+// see categories.DefaultService.Assign.
+func (service DefaultService) SetCategories(id int, version int, categories []int) (domain.Product, error) {
+	previous, err := service.Storage.FindById(id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	product, err := service.Storage.SetCategories(id, version, categories)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	service.written(OperationCategorize, &previous, product)
+	return product, nil
+}
+
 func (service DefaultService) GetAll() ([]domain.Product, error) {
 	products, err := service.Storage.GetAll()
 	if err != nil {
@@ -54,76 +281,256 @@ func (service DefaultService) GetAll() ([]domain.Product, error) {
 	return products, nil
 }
 
+func (service DefaultService) GetPage(query PageQuery) (Page, error) {
+	if err := query.validate(); err != nil {
+		return Page{}, err
+	}
+	page, err := service.Storage.GetPage(query)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			return Page{}, err
+		}
+		return Page{}, ErrInternalServerError
+	}
+	return page, nil
+}
+
+// FindById returns product id with the earliest expiration of its stock.
 func (service DefaultService) FindById(id int) (domain.Product, error) {
-	products, err := service.Storage.FindById(id)
+	product, err := service.Storage.FindById(id)
 	if err != nil {
 		return domain.Product{}, ErrProductNotFound
+	}
+	if service.Expiration != nil {
+		product.Expiration = service.Expiration(product)
+	}
+	return product, nil
+}
 
+func (service DefaultService) FindByUID(uid string) (domain.Product, error) {
+	product, err := service.Storage.FindByUID(uid)
+	if err != nil {
+		return domain.Product{}, ErrProductNotFound
 	}
-	return products, nil
+	return product, nil
 }
 
-func (service DefaultService) Search(priceGt float64) ([]domain.Product, error) {
-	if priceGt <= 0 {
-		return []domain.Product{}, ErrInvalidData
+func (service DefaultService) Search(expression string) ([]domain.Product, error) {
+	parsed, err := ParseFilter(expression)
+	if err != nil {
+		return []domain.Product{}, err
 	}
-	products, err := service.Storage.Search(priceGt)
+	products, err := service.Storage.Search(parsed)
 	if err != nil {
 		return []domain.Product{}, ErrInternalServerError
 	}
 	return products, nil
 }
 
-func (service DefaultService) Delete(id int) error {
-	err := service.Storage.Delete(id)
+func (service DefaultService) SearchText(query string, limit int) ([]ScoredProduct, error) {
+	if strings.TrimSpace(query) == "" || limit < 0 {
+		return []ScoredProduct{}, ErrInvalidData
+	}
+	searcher, ok := service.Storage.(TextSearcher)
+	if !ok {
+		return []ScoredProduct{}, ErrSearchUnavailable
+	}
+	products, err := searcher.SearchText(query, limit)
 	if err != nil {
-		return ErrProductNotFound
+		return []ScoredProduct{}, ErrInternalServerError
 	}
-	return nil
+	return products, nil
+}
+
+func (service DefaultService) Suggest(prefix string, limit int, rank string) ([]Suggestion, error) {
+	if strings.TrimSpace(prefix) == "" || limit <= 0 || limit > MaxPageLimit {
+		return []Suggestion{}, ErrInvalidData
+	}
+	suggester, ok := service.Storage.(Suggester)
+	if !ok {
+		return []Suggestion{}, ErrSearchUnavailable
+	}
+	suggestions, err := suggester.Suggest(prefix, limit, rank)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRank) {
+			return []Suggestion{}, err
+		}
+		return []Suggestion{}, ErrInternalServerError
+	}
+	return suggestions, nil
 }
 
+// Viewed counts a read of the products ids by a client, if the storage does.
+func (service DefaultService) Viewed(ids ...int) {
+	if viewer, ok := service.Storage.(Viewer); ok {
+		viewer.Viewed(ids...)
+	}
+}
+
+// ConsumerPrice prices the published products in list with the pricing
+// rules, a product listed several times making a line of several units.
 func (service DefaultService) ConsumerPrice(list []int) (domain.ProductsConsumer, error) {
 	filterProducts, err := service.Storage.ConsumerPrice(list)
 	if err != nil {
 		return domain.ProductsConsumer{}, ErrInternalServerError
 	}
-	var productsConsumer domain.ProductsConsumer
-	productsConsumer.Products = filterProducts
 
-	for _, value := range filterProducts {
-		productsConsumer.TotalPrice += value.Price
+	var lines []pricing.Line
+	positions := make(map[int]int)
+	for _, product := range filterProducts {
+		if i, ok := positions[product.ID]; ok {
+			lines[i].Quantity++
+			continue
+		}
+		positions[product.ID] = len(lines)
+		lines = append(lines, pricing.Line{
+			ProductID:  product.ID,
+			Name:       product.Name,
+			Categories: service.categoryPaths(product),
+			Quantity:   1,
+			UnitPrice:  product.Price,
+		})
+	}
+
+	quote, err := service.price(lines)
+	if err != nil {
+		return domain.ProductsConsumer{}, err
+	}
+	return domain.ProductsConsumer{
+		Products:   filterProducts,
+		Lines:      quote.Lines,
+		Subtotal:   quote.Subtotal,
+		TotalPrice: quote.Total,
+	}, nil
+}
+
+// Quote prices the quantities of the items, adding up the items of the same
+// product. Items that are not found, not published or short of stock are
+// left out of the price and reported as unavailable.
+func (service DefaultService) Quote(items []domain.QuoteItem) (domain.Quote, error) {
+	if err := validateQuote(items); err != nil {
+		return domain.Quote{}, err
+	}
+
+	var ids []int
+	quantities := make(map[int]int)
+	for _, item := range items {
+		if _, ok := quantities[item.ID]; !ok {
+			ids = append(ids, item.ID)
+		}
+		quantities[item.ID] += item.Quantity
+	}
+
+	quote := domain.Quote{Lines: []domain.PriceLine{}, Unavailable: []domain.UnavailableItem{}}
+	var lines []pricing.Line
+	for _, id := range ids {
+		unavailable := domain.UnavailableItem{ID: id, Quantity: quantities[id]}
+		product, err := service.Storage.FindById(id)
+		switch {
+		case errors.Is(err, ErrProductNotFound):
+			unavailable.Reason = domain.UnavailableNotFound
+		case err != nil:
+			return domain.Quote{}, ErrInternalServerError
+		case !product.IsPublished:
+			unavailable.Reason = domain.UnavailableUnpublished
+		case service.available(product) < quantities[id]:
+			unavailable.Available = service.available(product)
+			unavailable.Reason = domain.UnavailableInsufficientStock
+		default:
+			lines = append(lines, pricing.Line{
+				ProductID:  product.ID,
+				Name:       product.Name,
+				Categories: service.categoryPaths(product),
+				Quantity:   quantities[id],
+				UnitPrice:  product.Price,
+			})
+			continue
+		}
+		quote.Unavailable = append(quote.Unavailable, unavailable)
+	}
+
+	priced, err := service.price(lines)
+	if errors.Is(err, domain.ErrInvalidAmount) {
+		return domain.Quote{}, validation.Errors{{Field: "items", Code: "amount", Message: "must not add up to an amount out of range"}}
+	}
+	if err != nil {
+		return domain.Quote{}, err
+	}
+	quote.Lines = append(quote.Lines, priced.Lines...)
+	quote.Subtotal = priced.Subtotal
+	quote.TotalPrice = priced.Total
+	quote.Surcharges = domain.NewMoney(priced.Total.Amount-priced.Subtotal.Amount, priced.Total.Currency)
+	return quote, nil
+}
+
+func (service DefaultService) available(product domain.Product) int {
+	if service.Available == nil {
+		return product.Quantity
 	}
+	return service.Available(product.ID)
+}
 
-	if len(filterProducts) < 10 {
-		productsConsumer.TotalPrice = productsConsumer.TotalPrice * 1.21
+func (service DefaultService) categoryPaths(product domain.Product) []string {
+	if service.CategoryPaths == nil || len(product.Categories) == 0 {
+		return nil
 	}
+	return service.CategoryPaths(product.Categories)
+}
 
-	if len(filterProducts) > 10 && len(filterProducts) < 20 {
-		productsConsumer.TotalPrice = productsConsumer.TotalPrice * 1.17
+func validateQuote(items []domain.QuoteItem) error {
+	if len(items) == 0 {
+		return validation.Errors{{Field: "items", Code: "required", Message: "must list at least one item"}}
 	}
-	if len(filterProducts) > 20 {
-		productsConsumer.TotalPrice = productsConsumer.TotalPrice * 1.15
+	var fields []validation.Errors
+	for i, item := range items {
+		prefix := fmt.Sprintf("[%d].", i)
+		fields = append(fields,
+			validation.Field(prefix+"id", item.ID, validation.Positive[int]()),
+			validation.Field(prefix+"qty", item.Quantity, validation.Positive[int]()),
+		)
 	}
+	return validation.Validate(fields...)
+}
 
-	return productsConsumer, nil
+// price prices lines with the pricing rules on the current day.
+func (service DefaultService) price(lines []pricing.Line) (pricing.Quote, error) {
+	rules := service.Pricing
+	if rules == nil {
+		rules = pricing.DefaultRules()
+	}
+	return rules.Price(lines, domain.DateOf(service.now()), service.Rounding)
 }
 
+// validations checks product with ValidateProduct against the product it
+// replaces, if any, and then that its code value is unique, products in the
+// trash included.
 func (service DefaultService) validations(product *domain.Product) error {
 	products, err := service.Storage.GetAll()
 	if err != nil {
 		return err
 	}
 
+	var previous *domain.Product
+	for i := range products {
+		if product.ID != 0 && products[i].ID == product.ID {
+			previous = &products[i]
+		}
+	}
+	if err := ValidateProduct(*product, previous, service.now()); err != nil {
+		return err
+	}
+
 	for _, prod := range products {
 		if product.CodeValue == prod.CodeValue && product.ID != prod.ID {
 			return ErrProductAlreadyExists
 		}
 	}
-
-	_, err = time.Parse("02/01/2006", product.Expiration)
+	inTrash, err := service.codeInTrash(product.CodeValue)
 	if err != nil {
-		return ErrFormateDate
+		return err
+	}
+	if inTrash {
+		return ErrCodeValueInTrash
 	}
-
 	return nil
 }
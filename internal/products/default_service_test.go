@@ -0,0 +1,67 @@
+package products
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRepository fails the creates after the first ones it lets through.
+type failingRepository struct {
+	Repository
+	creates int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (repository *failingRepository) Create(product *domain.Product) error {
+	if repository.creates == 0 {
+		return errDiskFull
+	}
+	repository.creates--
+	return repository.Repository.Create(product)
+}
+
+func TestDefaultService_Import(t *testing.T) {
+	t.Run("should create none of the products when one cannot be stored", func(t *testing.T) {
+		storage, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), IDSchemeSequential)
+		require.NoError(t, err)
+		t.Cleanup(func() { storage.Close() })
+		now := time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+		service := DefaultService{Storage: &failingRepository{Repository: storage, creates: 2}, Now: func() time.Time { return now }}
+
+		_, err = service.Import([]domain.Product{
+			{Name: "Wine", Quantity: 10, CodeValue: "T65812", Expiration: domain.NewDate(2030, time.June, 1), Price: domain.NewMoney(17923, "ARS")},
+			{Name: "Cookie", Quantity: 5, CodeValue: "M7157", Expiration: domain.NewDate(2030, time.June, 1), Price: domain.NewMoney(27547, "ARS")},
+			{Name: "Bread", Quantity: 2, CodeValue: "B1234", Expiration: domain.NewDate(2030, time.June, 1), Price: domain.NewMoney(9900, "ARS")},
+		})
+		assert.ErrorIs(t, err, errDiskFull)
+		stored, err := storage.GetAll()
+		require.NoError(t, err)
+		assert.Empty(t, stored)
+	})
+}
+
+func TestDefaultService_Quote(t *testing.T) {
+	t.Run("should reject items adding up to an amount out of range", func(t *testing.T) {
+		storage, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), IDSchemeSequential)
+		require.NoError(t, err)
+		t.Cleanup(func() { storage.Close() })
+		product := domain.Product{Name: "Gold", Quantity: 3, CodeValue: "G1", IsPublished: true, Price: domain.NewMoney(math.MaxInt64/2, "ARS")}
+		require.NoError(t, storage.Create(&product))
+		service := DefaultService{Storage: storage}
+
+		_, err = service.Quote([]domain.QuoteItem{{ID: product.ID, Quantity: 3}})
+		var violations validation.Errors
+		require.ErrorAs(t, err, &violations)
+		assert.Equal(t, "items", violations[0].Field)
+		assert.Equal(t, "amount", violations[0].Code)
+	})
+}
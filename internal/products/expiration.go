@@ -0,0 +1,59 @@
+package products
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// errNotExpired skips the products published again or given a later
+// expiration while UnpublishExpired runs.
+var errNotExpired = errors.New("product is not expired")
+
+// UnpublishedProduct is a product UnpublishExpired unpublished.
+type UnpublishedProduct struct {
+	ID         int         `json:"id" example:"2"`
+	Name       string      `json:"name" example:"Wine"`
+	CodeValue  string      `json:"code_value" example:"T65812"`
+	Expiration domain.Date `json:"expiration" swaggertype:"string" example:"28/01/2022"`
+}
+
+// UnpublishExpired unpublishes the published products whose expiration has
+// passed, a product being valid through the day it expires on. It returns
+// the products it changed, with the errors of the products it could not
+// change, which do not stop it from changing the others.
+func (service DefaultService) UnpublishExpired() ([]UnpublishedProduct, error) {
+	stored, err := service.Storage.GetAll()
+	if err != nil {
+		return []UnpublishedProduct{}, ErrInternalServerError
+	}
+	today := domain.DateOf(service.now())
+	expired := func(product domain.Product) bool {
+		return product.IsPublished && !product.Expiration.IsZero() && product.Expiration.Before(today)
+	}
+
+	unpublished := []UnpublishedProduct{}
+	var errs []error
+	for _, product := range stored {
+		if !expired(product) {
+			continue
+		}
+		changed, err := service.Patch(product.ID, 0, func(current domain.Product) (domain.Product, error) {
+			if !expired(current) {
+				return domain.Product{}, errNotExpired
+			}
+			current.IsPublished = false
+			return current, nil
+		})
+		if errors.Is(err, errNotExpired) || errors.Is(err, ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("product %d: %w", product.ID, err))
+			continue
+		}
+		unpublished = append(unpublished, UnpublishedProduct{ID: changed.ID, Name: changed.Name, CodeValue: changed.CodeValue, Expiration: changed.Expiration})
+	}
+	return unpublished, errors.Join(errs...)
+}
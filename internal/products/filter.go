@@ -0,0 +1,54 @@
+package products
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/filter"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// productSchema lists the fields a search expression can use.
+var productSchema = filter.Schema{
+	"id":           filter.TypeNumber,
+	"name":         filter.TypeString,
+	"quantity":     filter.TypeNumber,
+	"code_value":   filter.TypeString,
+	"is_published": filter.TypeBool,
+	"expiration":   filter.TypeDate,
+	"price":        filter.TypeNumber,
+}
+
+// ParseFilter parses a search expression over products. Errors wrap
+// ErrInvalidFilter and describe what is wrong and where.
+func ParseFilter(expression string) (filter.Expression, error) {
+	parsed, err := filter.Parse(expression, productSchema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return parsed, nil
+}
+
+func productRecord(product domain.Product) filter.Record {
+	return func(field string) interface{} {
+		switch field {
+		case "id":
+			return float64(product.ID)
+		case "name":
+			return product.Name
+		case "quantity":
+			return float64(product.Quantity)
+		case "code_value":
+			return product.CodeValue
+		case "is_published":
+			return product.IsPublished
+		case "expiration":
+			return product.Expiration.Time()
+		case "price":
+			return product.Price.Float()
+		}
+		return nil
+	}
+}
@@ -0,0 +1,338 @@
+package products
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Operation is the write that made a revision.
+type Operation string
+
+const (
+	OperationCreate     Operation = "create"
+	OperationUpdate     Operation = "update"
+	OperationPatch      Operation = "patch"
+	OperationRename     Operation = "rename"
+	OperationCategorize Operation = "categorize"
+	OperationDelete     Operation = "delete"
+	OperationRestore    Operation = "restore"
+	OperationRevert     Operation = "revert"
+	OperationPurge      Operation = "purge"
+	// OperationStock is a change of the stock of a product by an inventory
+	// movement, see DefaultService.Record.
+	OperationStock Operation = "stock"
+)
+
+// Author is who makes the writes of a service, recorded in the revisions.
+type Author struct {
+	Actor string
+	// RequestID is the request the writes were made in, if any.
+	RequestID string
+}
+
+// Revision is a write of a product: the product as written, what the write
+// changed and who made it. Revisions are numbered from 1 for every product,
+// in the order they are recorded, and keep their number for good.
+type Revision struct {
+	Number    int       `json:"revision" example:"2"`
+	ProductID int       `json:"product_id" example:"1"`
+	Operation Operation `json:"operation" enums:"create,update,patch,rename,categorize,delete,restore,revert,purge,stock" example:"patch"`
+	// RevertedTo is the revision a revert went back to.
+	RevertedTo int `json:"reverted_to,omitempty"`
+	// Actor is absent for the changes of the stock, which the inventory
+	// makes without one.
+	Actor     string    `json:"actor,omitempty" example:"ana"`
+	RequestID string    `json:"request_id,omitempty" example:"4f9c2a1b7d3e8f60"`
+	At        time.Time `json:"at"`
+	Changes   []Change  `json:"changes"`
+	// Snapshot is the product as written, and as deleted for the deletes.
+	Snapshot domain.Product `json:"snapshot"`
+}
+
+// Change is a field a revision changed, with its JSON values before and
+// after the write, From being absent for the fields of new products.
+type Change struct {
+	Field string `json:"field" example:"price"`
+	From  any    `json:"from,omitempty" swaggertype:"string" example:"179.23"`
+	To    any    `json:"to,omitempty" swaggertype:"string" example:"199.99"`
+}
+
+// History keeps the revisions of the products in memory. With a path, every
+// revision is also synced to a JSON lines file before Append returns, and the
+// file is replayed on open. Revisions are never changed nor removed, the
+// revisions of purged products included.
+//
+// Writes are recorded after they are applied, so two concurrent writes of a
+// product may be recorded in the opposite order: the revisions of a product
+// are listed in the order of the versions of their snapshots instead, a purge
+// after the delete it has the version of, whatever their numbers.
+type History struct {
+	mu        sync.RWMutex
+	file      *os.File
+	revisions map[int][]Revision
+}
+
+// OpenHistory replays the history file at path, if any, and appends to it.
+// An empty path keeps the history in memory only.
+func OpenHistory(path string) (*History, error) {
+	history := &History{revisions: map[int][]Revision{}}
+	if path == "" {
+		return history, nil
+	}
+	revisions, size, err := readRevisions(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		history.insert(revision)
+	}
+	// Drop a torn last revision, so the next one starts on a line of its own.
+	if err := os.Truncate(path, size); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error opening history: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening history: %w", err)
+	}
+	history.file = file
+	return history, nil
+}
+
+// Append saves revision, numbered after the other revisions of its product.
+// It returns the revision as saved.
+func (history *History) Append(revision Revision) (Revision, error) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	revision.Number = len(history.revisions[revision.ProductID]) + 1
+	if history.file != nil {
+		data, err := json.Marshal(revision)
+		if err != nil {
+			return Revision{}, fmt.Errorf("error encoding revision: %w", err)
+		}
+		info, err := history.file.Stat()
+		if err != nil {
+			return Revision{}, fmt.Errorf("error writing history: %w", err)
+		}
+		// A write that fails partway is cut off, so that the next
+		// revision does not follow a torn line.
+		if _, err := history.file.Write(append(data, '\n')); err != nil {
+			history.file.Truncate(info.Size())
+			return Revision{}, fmt.Errorf("error writing history: %w", err)
+		}
+		if err := history.file.Sync(); err != nil {
+			history.file.Truncate(info.Size())
+			return Revision{}, fmt.Errorf("error syncing history: %w", err)
+		}
+	}
+	history.insert(revision)
+	return revision, nil
+}
+
+// place returns the index revision goes at among the revisions of its
+// product: after the revisions of earlier versions and of the same version,
+// but before a purge.
+func (history *History) place(revision Revision) int {
+	revisions := history.revisions[revision.ProductID]
+	return sort.Search(len(revisions), func(i int) bool {
+		if revisions[i].Snapshot.Version != revision.Snapshot.Version {
+			return revisions[i].Snapshot.Version > revision.Snapshot.Version
+		}
+		return revisions[i].Operation == OperationPurge && revision.Operation != OperationPurge
+	})
+}
+
+// insert adds revision at its place among the revisions of its product.
+func (history *History) insert(revision Revision) {
+	i := history.place(revision)
+	revisions := append(history.revisions[revision.ProductID], Revision{})
+	copy(revisions[i+1:], revisions[i:])
+	revisions[i] = revision
+	history.revisions[revision.ProductID] = revisions
+}
+
+// Revisions returns the revisions of a product in the order of their
+// versions, oldest first.
+func (history *History) Revisions(productID int) []Revision {
+	history.mu.RLock()
+	defer history.mu.RUnlock()
+	return append([]Revision{}, history.revisions[productID]...)
+}
+
+func (history *History) Close() error {
+	if history.file == nil {
+		return nil
+	}
+	return history.file.Close()
+}
+
+// Revisions returns the revisions of product id, oldest first, those of
+// products in the trash or purged included.
+func (service DefaultService) Revisions(id int) ([]Revision, error) {
+	var revisions []Revision
+	if service.History != nil {
+		revisions = service.History.Revisions(id)
+	}
+	if len(revisions) > 0 {
+		return revisions, nil
+	}
+	if _, err := service.Storage.FindById(id); err == nil {
+		return []Revision{}, nil
+	}
+	if _, err := service.trashed(id); err == nil {
+		return []Revision{}, nil
+	}
+	return []Revision{}, ErrProductNotFound
+}
+
+// FindByIdAsOf returns product id as the latest write recorded by a time
+// left it, the one of the highest version. A product deleted by then is not
+// found, even if it was restored later.
+func (service DefaultService) FindByIdAsOf(id int, at time.Time) (domain.Product, error) {
+	revisions, err := service.Revisions(id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	var last *Revision
+	for i := range revisions {
+		if !revisions[i].At.After(at) {
+			last = &revisions[i]
+		}
+	}
+	if last == nil {
+		return domain.Product{}, ErrRevisionNotFound
+	}
+	if last.Operation == OperationDelete || last.Operation == OperationPurge {
+		return domain.Product{}, ErrProductNotFound
+	}
+	return last.Snapshot, nil
+}
+
+// Revert sets the name, code value, publication, expiration and price of
+// product id back to those of one of its revisions, recording a revision
+// that refers to it. The stock, categories and attributes are kept, since
+// they change through inventory movements, category assignments and
+// variants. With a version, the product must still have it.
+func (service DefaultService) Revert(id int, version int, revision int) (domain.Product, error) {
+	revisions, err := service.Revisions(id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	var snapshot *domain.Product
+	for i := range revisions {
+		if revisions[i].Number == revision {
+			snapshot = &revisions[i].Snapshot
+		}
+	}
+	if snapshot == nil {
+		return domain.Product{}, ErrRevisionNotFound
+	}
+
+	previous, reverted, err := service.patch(id, version, func(product domain.Product) (domain.Product, error) {
+		product.Name = snapshot.Name
+		product.CodeValue = snapshot.CodeValue
+		product.IsPublished = snapshot.IsPublished
+		product.Expiration = snapshot.Expiration
+		product.Price = snapshot.Price
+		return product, nil
+	})
+	if err != nil {
+		return domain.Product{}, err
+	}
+	service.record(Revision{Operation: OperationRevert, RevertedTo: revision}, &previous, reverted)
+	service.changed(&previous, reverted)
+	return reverted, nil
+}
+
+// readRevisions returns the revisions of the history file at path and the
+// size of the lines they were read from. A missing file is empty and a last
+// line cut short by a crash is left out.
+func readRevisions(path string) ([]Revision, int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("error opening history: %w", err)
+	}
+
+	var (
+		revisions []Revision
+		size      int64
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			size += int64(len(line)) + 1
+			continue
+		}
+		var revision Revision
+		if err := json.Unmarshal(line, &revision); err != nil {
+			if !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, line) {
+				return revisions, size, nil
+			}
+			return nil, 0, fmt.Errorf("error decoding history: %w", err)
+		}
+		revisions = append(revisions, revision)
+		size += int64(len(line)) + 1
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error reading history: %w", err)
+	}
+	return revisions, int64(len(data)), nil
+}
+
+// diff returns the fields of the JSON objects of previous and current that
+// differ, sorted by name, every field of current when previous is nil. The
+// version is left out, since every write changes it.
+func diff(previous *domain.Product, current domain.Product) ([]Change, error) {
+	before := map[string]any{}
+	if previous != nil {
+		if err := toFields(*previous, &before); err != nil {
+			return nil, err
+		}
+	}
+	after := map[string]any{}
+	if err := toFields(current, &after); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	delete(fields, "version")
+	changes := []Change{}
+	for field := range fields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, Change{Field: field, From: before[field], To: after[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func toFields(product domain.Product, fields *map[string]any) error {
+	data, err := json.Marshal(product)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, fields)
+}
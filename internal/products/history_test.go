@@ -0,0 +1,116 @@
+package products
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	t.Run("should replay the revisions of the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		history, err := OpenHistory(path)
+		require.NoError(t, err)
+		_, err = history.Append(Revision{ProductID: 1, Operation: OperationCreate})
+		require.NoError(t, err)
+		_, err = history.Append(Revision{ProductID: 2, Operation: OperationCreate})
+		require.NoError(t, err)
+		require.NoError(t, history.Close())
+
+		history, err = OpenHistory(path)
+		require.NoError(t, err)
+		defer history.Close()
+		revision, err := history.Append(Revision{ProductID: 1, Operation: OperationPatch})
+		require.NoError(t, err)
+		assert.Equal(t, 2, revision.Number)
+		assert.Len(t, history.Revisions(1), 2)
+		assert.Len(t, history.Revisions(2), 1)
+	})
+
+	t.Run("should drop a revision cut short", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		history, err := OpenHistory(path)
+		require.NoError(t, err)
+		_, err = history.Append(Revision{ProductID: 1, Operation: OperationCreate})
+		require.NoError(t, err)
+		require.NoError(t, history.Close())
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"revision":2,"product_id":1,"oper`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		history, err = OpenHistory(path)
+		require.NoError(t, err)
+		_, err = history.Append(Revision{ProductID: 1, Operation: OperationPatch})
+		require.NoError(t, err)
+		require.NoError(t, history.Close())
+		history, err = OpenHistory(path)
+		require.NoError(t, err)
+		defer history.Close()
+		revisions := history.Revisions(1)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, OperationPatch, revisions[1].Operation)
+	})
+
+	t.Run("should order the revisions by version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		history, err := OpenHistory(path)
+		require.NoError(t, err)
+		_, err = history.Append(Revision{ProductID: 1, Operation: OperationCreate, Snapshot: domain.Product{Version: 1}})
+		require.NoError(t, err)
+		_, err = history.Append(Revision{ProductID: 1, Operation: OperationPatch, Snapshot: domain.Product{Version: 3}})
+		require.NoError(t, err)
+		revision, err := history.Append(Revision{ProductID: 1, Operation: OperationUpdate, Snapshot: domain.Product{Version: 2}})
+		require.NoError(t, err)
+		assert.Equal(t, 3, revision.Number)
+		_, err = history.Append(Revision{ProductID: 1, Operation: OperationPurge, Snapshot: domain.Product{Version: 4}})
+		require.NoError(t, err)
+		_, err = history.Append(Revision{ProductID: 1, Operation: OperationDelete, Snapshot: domain.Product{Version: 4}})
+		require.NoError(t, err)
+		require.NoError(t, history.Close())
+
+		history, err = OpenHistory(path)
+		require.NoError(t, err)
+		defer history.Close()
+		revisions := history.Revisions(1)
+		require.Len(t, revisions, 5)
+		// Revisions keep the numbers they were recorded with.
+		numbers := []int{1, 3, 2, 5, 4}
+		for i, operation := range []Operation{OperationCreate, OperationUpdate, OperationPatch, OperationDelete, OperationPurge} {
+			assert.Equal(t, numbers[i], revisions[i].Number)
+			assert.Equal(t, operation, revisions[i].Operation)
+		}
+	})
+}
+
+func TestDiff(t *testing.T) {
+	previous := domain.Product{ID: 1, Name: "Wine", Quantity: 10, CodeValue: "T65812", Expiration: domain.NewDate(2030, time.June, 1), Price: domain.NewMoney(17923, "ARS"), Version: 1}
+
+	t.Run("should list every field of a new product", func(t *testing.T) {
+		changes, err := diff(nil, previous)
+		require.NoError(t, err)
+		assert.Contains(t, changes, Change{Field: "name", To: "Wine"})
+		for _, change := range changes {
+			assert.NotEqual(t, "version", change.Field)
+		}
+	})
+
+	t.Run("should list the fields that changed, by name", func(t *testing.T) {
+		current := previous
+		current.Name = "Red wine"
+		current.IsPublished = true
+		current.Version = 2
+		changes, err := diff(&previous, current)
+		require.NoError(t, err)
+		assert.Equal(t, []Change{
+			{Field: "is_published", From: false, To: true},
+			{Field: "name", From: "Wine", To: "Red wine"},
+		}, changes)
+	})
+}
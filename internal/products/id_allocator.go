@@ -0,0 +1,144 @@
+package products
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/store"
+	"github.com/google/uuid"
+)
+
+// IDScheme selects the public identifier given to new products. Products
+// always get a numeric ID; with the uuid and ulid schemes they also get a UID
+// that handlers accept wherever an :id is expected.
+type IDScheme string
+
+const (
+	IDSchemeSequential IDScheme = "sequential"
+	IDSchemeUUID       IDScheme = "uuid"
+	IDSchemeULID       IDScheme = "ulid"
+)
+
+func ParseIDScheme(value string) (IDScheme, error) {
+	switch scheme := IDScheme(strings.ToLower(value)); scheme {
+	case "", IDSchemeSequential:
+		return IDSchemeSequential, nil
+	case IDSchemeUUID, IDSchemeULID:
+		return scheme, nil
+	default:
+		return "", fmt.Errorf("unknown id scheme %q", value)
+	}
+}
+
+// NewUID returns a new identifier for the scheme, or an empty string for the
+// sequential scheme.
+func (scheme IDScheme) NewUID() string {
+	switch scheme {
+	case IDSchemeUUID:
+		return uuid.NewString()
+	case IDSchemeULID:
+		return newULID(time.Now())
+	default:
+		return ""
+	}
+}
+
+// IsUID reports whether value has the shape of an identifier of any scheme.
+func IsUID(value string) bool {
+	if _, err := uuid.Parse(value); err == nil {
+		return true
+	}
+	return isULID(value)
+}
+
+// IDAllocator hands out monotonically increasing product IDs. The highest ID
+// ever allocated is persisted next to the store before it is returned, so IDs
+// are never reused, not even after the product that had them is deleted.
+type IDAllocator struct {
+	Scheme IDScheme
+
+	mu   sync.Mutex
+	path string
+	last int
+}
+
+// NewIDAllocator loads the high-water mark stored at path. existing products
+// raise it, which covers stores created before the mark was persisted.
+func NewIDAllocator(path string, scheme IDScheme, existing []domain.Product) (*IDAllocator, error) {
+	allocator := &IDAllocator{Scheme: scheme, path: path}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		allocator.last, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding id high-water mark: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("error opening id high-water mark: %w", err)
+	}
+
+	for _, product := range existing {
+		if product.ID > allocator.last {
+			allocator.last = product.ID
+		}
+	}
+	return allocator, nil
+}
+
+// Assign sets the next ID, and the UID when the scheme uses one, on product.
+func (allocator *IDAllocator) Assign(product *domain.Product) error {
+	allocator.mu.Lock()
+	defer allocator.mu.Unlock()
+
+	next := allocator.last + 1
+	if err := store.WriteFileAtomic(allocator.path, []byte(strconv.Itoa(next)), 0644); err != nil {
+		return err
+	}
+	allocator.last = next
+
+	product.ID = next
+	product.UID = allocator.Scheme.NewUID()
+	return nil
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID encodes a 48 bit millisecond timestamp and 80 random bits as 26
+// Crockford base32 characters, so ULIDs sort by creation time.
+func newULID(now time.Time) string {
+	var data [16]byte
+	binary.BigEndian.PutUint64(data[:8], uint64(now.UnixMilli())<<16)
+	if _, err := rand.Read(data[6:]); err != nil {
+		panic(err)
+	}
+
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(data[:8])
+	lo := binary.BigEndian.Uint64(data[8:])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+func isULID(value string) bool {
+	if len(value) != 26 || value[0] > '7' {
+		return false
+	}
+	for _, char := range strings.ToUpper(value) {
+		if !strings.ContainsRune(crockford, char) {
+			return false
+		}
+	}
+	return true
+}
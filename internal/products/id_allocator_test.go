@@ -0,0 +1,51 @@
+package products
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDAllocator(t *testing.T) {
+	t.Run("should never reuse an id after a restart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "products.json.ids")
+		existing := []domain.Product{{ID: 2}, {ID: 500}}
+
+		allocator, err := NewIDAllocator(path, IDSchemeSequential, existing)
+		require.NoError(t, err)
+		var product domain.Product
+		require.NoError(t, allocator.Assign(&product))
+		assert.Equal(t, 501, product.ID)
+		assert.Empty(t, product.UID)
+
+		// Product 501 was deleted before the restart.
+		allocator, err = NewIDAllocator(path, IDSchemeSequential, existing)
+		require.NoError(t, err)
+		require.NoError(t, allocator.Assign(&product))
+		assert.Equal(t, 502, product.ID)
+	})
+
+	t.Run("should assign uids for the uuid and ulid schemes", func(t *testing.T) {
+		for _, scheme := range []IDScheme{IDSchemeUUID, IDSchemeULID} {
+			allocator, err := NewIDAllocator(filepath.Join(t.TempDir(), "ids"), scheme, nil)
+			require.NoError(t, err)
+			var product domain.Product
+			require.NoError(t, allocator.Assign(&product))
+			assert.Equal(t, 1, product.ID)
+			assert.True(t, IsUID(product.UID), product.UID)
+		}
+	})
+
+	t.Run("should sort ulids by creation time", func(t *testing.T) {
+		now := time.Now()
+		first := newULID(now)
+		second := newULID(now.Add(time.Millisecond))
+		assert.Len(t, first, 26)
+		assert.Less(t, first, second)
+		assert.False(t, IsUID("12"))
+	})
+}
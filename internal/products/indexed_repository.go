@@ -0,0 +1,268 @@
+package products
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/search"
+)
+
+var (
+	ErrSearchUnavailable = errors.New("text search unavailable")
+	ErrInvalidRank       = errors.New("invalid rank")
+)
+
+// Suggestion ranks, see IndexedRepository.Suggest.
+const (
+	RankPopularity = "popularity"
+	RankPrice      = "price"
+)
+
+type ScoredProduct struct {
+	domain.Product
+	Score float64 `json:"score"`
+}
+
+// MarshalJSON adds the score to the JSON object of the product, which would
+// otherwise be written alone by the MarshalJSON of domain.Product.
+func (product ScoredProduct) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(product.Product)
+	if err != nil {
+		return nil, err
+	}
+	score, err := json.Marshal(product.Score)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(append(data[:len(data)-1], `,"score":`...), score...), '}'), nil
+}
+
+// Suggestion is the summary of a product shown while typing its name or code.
+type Suggestion struct {
+	ID         int             `json:"id"`
+	Name       string          `json:"name"`
+	CodeValue  string          `json:"code_value"`
+	Price      domain.Money    `json:"price" swaggertype:"number"`
+	Currency   domain.Currency `json:"currency"`
+	Popularity int             `json:"popularity"`
+}
+
+// TextSearcher is implemented by repositories that keep a full-text index of
+// product names.
+type TextSearcher interface {
+	SearchText(query string, limit int) ([]ScoredProduct, error)
+}
+
+// Suggester is implemented by repositories that index product names and
+// codes by prefix.
+type Suggester interface {
+	Suggest(prefix string, limit int, rank string) ([]Suggestion, error)
+}
+
+// Viewer is implemented by repositories that rank products by how many
+// times clients read them.
+type Viewer interface {
+	Viewed(ids ...int)
+}
+
+// IndexedRepository wraps a Repository and keeps an inverted index of the
+// product names and a prefix index of names and codes in sync with every
+// write that goes through it. Popularity counts how many times clients read a
+// product since the process started, as told by Viewed: the reads of the
+// service itself, e.g. before a write, are not counted.
+type IndexedRepository struct {
+	Repository
+
+	// writes serializes each write with its index update, so the index
+	// never ends up with the name of an older write.
+	writes   sync.Mutex
+	names    *search.Index
+	prefixes *search.Suggester
+
+	mu          sync.RWMutex
+	suggestions map[int]Suggestion
+}
+
+func NewIndexedRepository(repository Repository) (*IndexedRepository, error) {
+	products, err := repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	indexed := &IndexedRepository{
+		Repository:  repository,
+		names:       search.NewIndex(),
+		prefixes:    search.NewSuggester(),
+		suggestions: map[int]Suggestion{},
+	}
+	keys := make(map[int][]string, len(products))
+	for _, product := range products {
+		indexed.names.Put(product.ID, product.Name)
+		keys[product.ID] = suggestionKeys(product)
+		indexed.suggestions[product.ID] = Suggestion{
+			ID:        product.ID,
+			Name:      product.Name,
+			CodeValue: product.CodeValue,
+			Price:     product.Price,
+			Currency:  product.Price.Currency,
+		}
+	}
+	indexed.prefixes.Load(keys)
+	return indexed, nil
+}
+
+func suggestionKeys(product domain.Product) []string {
+	return append(search.SuggestionKeys(product.Name), search.SuggestionKeys(product.CodeValue)...)
+}
+
+func (repository *IndexedRepository) put(product domain.Product) {
+	repository.names.Put(product.ID, product.Name)
+	repository.prefixes.Put(product.ID, suggestionKeys(product))
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	repository.suggestions[product.ID] = Suggestion{
+		ID:         product.ID,
+		Name:       product.Name,
+		CodeValue:  product.CodeValue,
+		Price:      product.Price,
+		Currency:   product.Price.Currency,
+		Popularity: repository.suggestions[product.ID].Popularity,
+	}
+}
+
+func (repository *IndexedRepository) remove(id int) {
+	repository.names.Remove(id)
+	repository.prefixes.Remove(id)
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	delete(repository.suggestions, id)
+}
+
+// Viewed counts a read of the products ids by a client, once per ID given.
+func (repository *IndexedRepository) Viewed(ids ...int) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+	for _, id := range ids {
+		if suggestion, ok := repository.suggestions[id]; ok {
+			suggestion.Popularity++
+			repository.suggestions[id] = suggestion
+		}
+	}
+}
+
+func (repository *IndexedRepository) Create(product *domain.Product) error {
+	repository.writes.Lock()
+	defer repository.writes.Unlock()
+
+	if err := repository.Repository.Create(product); err != nil {
+		return err
+	}
+	repository.put(*product)
+	return nil
+}
+
+func (repository *IndexedRepository) Update(product *domain.Product) error {
+	repository.writes.Lock()
+	defer repository.writes.Unlock()
+
+	if err := repository.Repository.Update(product); err != nil {
+		return err
+	}
+	repository.put(*product)
+	return nil
+}
+
+func (repository *IndexedRepository) UpdateName(id int, name string) (domain.Product, error) {
+	repository.writes.Lock()
+	defer repository.writes.Unlock()
+
+	product, err := repository.Repository.UpdateName(id, name)
+	if err != nil {
+		return product, err
+	}
+	repository.put(product)
+	return product, nil
+}
+
+// Trash removes the product from the indexes, and Restore puts it back.
+func (repository *IndexedRepository) Trash(id int, at time.Time, by string) (domain.Product, error) {
+	repository.writes.Lock()
+	defer repository.writes.Unlock()
+
+	product, err := repository.Repository.Trash(id, at, by)
+	if err != nil {
+		return product, err
+	}
+	repository.remove(id)
+	return product, nil
+}
+
+func (repository *IndexedRepository) Restore(id int) (domain.Product, error) {
+	repository.writes.Lock()
+	defer repository.writes.Unlock()
+
+	product, err := repository.Repository.Restore(id)
+	if err != nil {
+		return product, err
+	}
+	repository.put(product)
+	return product, nil
+}
+
+func (repository *IndexedRepository) Delete(id int) error {
+	repository.writes.Lock()
+	defer repository.writes.Unlock()
+
+	if err := repository.Repository.Delete(id); err != nil {
+		return err
+	}
+	repository.remove(id)
+	return nil
+}
+
+// SearchText ranks products by how well their name matches query.
+func (repository *IndexedRepository) SearchText(query string, limit int) ([]ScoredProduct, error) {
+	results := repository.names.Search(query, limit)
+	scored := make([]ScoredProduct, 0, len(results))
+	for _, result := range results {
+		product, err := repository.Repository.FindById(result.ID)
+		if errors.Is(err, ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scored = append(scored, ScoredProduct{Product: product, Score: result.Score})
+	}
+	return scored, nil
+}
+
+// Suggest returns up to limit products whose name, a word of their name or
+// code value starts with prefix, most popular or most expensive first.
+func (repository *IndexedRepository) Suggest(prefix string, limit int, rank string) ([]Suggestion, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	var score func(id int) float64
+	switch rank {
+	case RankPopularity:
+		score = func(id int) float64 { return float64(repository.suggestions[id].Popularity) }
+	case RankPrice:
+		score = func(id int) float64 { return repository.suggestions[id].Price.Float() }
+	default:
+		return nil, ErrInvalidRank
+	}
+
+	ids := repository.prefixes.Suggest(prefix, limit, score)
+	suggestions := make([]Suggestion, 0, len(ids))
+	for _, id := range ids {
+		if suggestion, ok := repository.suggestions[id]; ok {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,250 @@
+package products
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// MaxPageLimit bounds the page size a client can ask for.
+const MaxPageLimit = 1000
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidPage   = errors.New("invalid page")
+)
+
+// sortColumns maps the sortable fields to their column and value in a
+// product. Every page is finally ordered by id so the order is total and
+// cursors are stable.
+var sortColumns = map[string]func(product domain.Product) interface{}{
+	"id":           func(product domain.Product) interface{} { return float64(product.ID) },
+	"name":         func(product domain.Product) interface{} { return product.Name },
+	"quantity":     func(product domain.Product) interface{} { return float64(product.Quantity) },
+	"code_value":   func(product domain.Product) interface{} { return product.CodeValue },
+	"is_published": func(product domain.Product) interface{} { return boolValue(product.IsPublished) },
+	"expiration":   func(product domain.Product) interface{} { return product.Expiration.ISO() },
+	"price":        func(product domain.Product) interface{} { return product.Price.Float() },
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma separated list of fields, each optionally
+// prefixed with "-" for descending order, e.g. "price,-name".
+func ParseSort(value string) ([]SortField, error) {
+	var fields []SortField
+	if value == "" {
+		return fields, nil
+	}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		field := SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field, field.Desc = field.Field[1:], true
+		}
+		if _, ok := sortColumns[field.Field]; !ok || seen[field.Field] {
+			return nil, ErrInvalidSort
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func formatSort(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Field
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// PageQuery selects a page of products. Limit 0 means no limit. Cursor and
+// Offset are mutually exclusive: a cursor continues right after the last
+// product of the previous page, whatever was created or deleted meanwhile.
+// With Categories, only the products in any of them are paged.
+type PageQuery struct {
+	Limit      int
+	Offset     int
+	Cursor     string
+	Sort       []SortField
+	Categories []int
+}
+
+type Page struct {
+	Products   []domain.Product
+	Total      int
+	NextCursor string
+}
+
+func (query PageQuery) validate() error {
+	if query.Limit < 0 || query.Limit > MaxPageLimit || query.Offset < 0 {
+		return ErrInvalidPage
+	}
+	if query.Cursor != "" && query.Offset > 0 {
+		return ErrInvalidPage
+	}
+	return nil
+}
+
+// orderBy is the sort with the id tiebreaker appended.
+func (query PageQuery) orderBy() []SortField {
+	for _, field := range query.Sort {
+		if field.Field == "id" {
+			return query.Sort
+		}
+	}
+	return append(append([]SortField{}, query.Sort...), SortField{Field: "id"})
+}
+
+// inCategories reports whether product is in any of the categories of the
+// query, or whether the query has none.
+func (query PageQuery) inCategories(product domain.Product) bool {
+	if len(query.Categories) == 0 {
+		return true
+	}
+	for _, category := range product.Categories {
+		for _, wanted := range query.Categories {
+			if category == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type cursor struct {
+	Sort string        `json:"s"`
+	Keys []interface{} `json:"k"`
+}
+
+func encodeCursor(fields []SortField, product domain.Product) string {
+	data, _ := json.Marshal(cursor{Sort: formatSort(fields), Keys: sortKeys(fields, product)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the sort keys of the last product of the previous
+// page. A cursor is only valid with the sort it was created for.
+func decodeCursor(value string, fields []SortField) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var decoded cursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if decoded.Sort != formatSort(fields) || len(decoded.Keys) != len(fields) {
+		return nil, ErrInvalidCursor
+	}
+	for i, field := range fields {
+		switch decoded.Keys[i].(type) {
+		case float64:
+			if _, ok := sortColumns[field.Field](domain.Product{}).(float64); !ok {
+				return nil, ErrInvalidCursor
+			}
+		case string:
+			if _, ok := sortColumns[field.Field](domain.Product{}).(string); !ok {
+				return nil, ErrInvalidCursor
+			}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return decoded.Keys, nil
+}
+
+// compareKeys compares two sort key lists field by field honouring the
+// direction of each field.
+func compareKeys(fields []SortField, a, b []interface{}) int {
+	for i, field := range fields {
+		result := compareValues(a[i], b[i])
+		if field.Desc {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+func sortKeys(fields []SortField, product domain.Product) []interface{} {
+	keys := make([]interface{}, len(fields))
+	for i, field := range fields {
+		keys[i] = sortColumns[field.Field](product)
+	}
+	return keys
+}
+
+// paginate applies query in memory, for repositories that cannot push the
+// pagination down to their storage.
+func paginate(products []domain.Product, query PageQuery) (Page, error) {
+	fields := query.orderBy()
+	sorted := []domain.Product{}
+	for _, product := range products {
+		if query.inCategories(product) {
+			sorted = append(sorted, product)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareKeys(fields, sortKeys(fields, sorted[i]), sortKeys(fields, sorted[j])) < 0
+	})
+
+	page := Page{Total: len(sorted)}
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, fields)
+		if err != nil {
+			return Page{}, err
+		}
+		start := sort.Search(len(sorted), func(i int) bool {
+			return compareKeys(fields, sortKeys(fields, sorted[i]), after) > 0
+		})
+		sorted = sorted[start:]
+	}
+
+	if query.Offset > len(sorted) {
+		query.Offset = len(sorted)
+	}
+	sorted = sorted[query.Offset:]
+	if query.Limit > 0 && len(sorted) > query.Limit {
+		sorted = sorted[:query.Limit]
+		page.NextCursor = encodeCursor(fields, sorted[len(sorted)-1])
+	}
+	page.Products = sorted
+	return page, nil
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
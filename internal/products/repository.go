@@ -2,22 +2,47 @@ package products
 
 import (
 	"errors"
+	"time"
 
 	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/filter"
 )
 
 var (
 	ErrProductAlreadyExists = errors.New("product already exist")
 	ErrProductNotFound      = errors.New("product not found")
+	ErrVersionConflict      = errors.New("product was modified")
 )
 
+// Repository stores products. Every write increments the Version of the
+// product, starting at 1 on Create. Update and SetCategories are
+// compare-and-swaps: a product with a non-zero Version is only written if it
+// still has that version, otherwise ErrVersionConflict is returned. The
+// categories of a product are only set by SetCategories: they start empty on
+// Create and, like the UID, are kept by Update.
+//
+// Products moved to the trash are only found by Trashed: the other reads
+// leave them out and the writes other than Restore and Delete fail with
+// ErrProductNotFound. Their code values stay taken until they are deleted.
 type Repository interface {
 	Create(product *domain.Product) error
 	GetAll() ([]domain.Product, error)
+	GetPage(query PageQuery) (Page, error)
 	FindById(id int) (domain.Product, error)
-	Search(priceGt float64) ([]domain.Product, error)
+	FindByUID(uid string) (domain.Product, error)
+	// Variants returns the variants of the product parentID, sorted by ID.
+	Variants(parentID int) ([]domain.Product, error)
+	Search(expression filter.Expression) ([]domain.Product, error)
 	Update(product *domain.Product) error
 	UpdateName(id int, name string) (domain.Product, error)
+	SetCategories(id int, version int, categories []int) (domain.Product, error)
+	// Trash moves a product to the trash, deleted at a time by someone.
+	Trash(id int, at time.Time, by string) (domain.Product, error)
+	// Trashed returns the products in the trash, oldest deletion first.
+	Trashed() ([]domain.Product, error)
+	// Restore moves a product out of the trash.
+	Restore(id int) (domain.Product, error)
+	// Delete deletes a product for good, whether in the trash or not.
 	Delete(id int) error
 	ConsumerPrice(list []int) ([]domain.Product, error)
 }
@@ -2,13 +2,13 @@ package products
 
 import (
 	"errors"
+	"time"
 
 	"github.com/Andrea-Reyna/go-web/internal/domain"
 )
 
 var (
 	ErrProductInvalid      = errors.New("invalid product")
-	ErrFormateDate         = errors.New("invalid format date")
 	ErrInvalidData         = errors.New("invalid data")
 	ErrInternalServerError = errors.New("internal server error")
 )
@@ -16,10 +16,30 @@ var (
 type Service interface {
 	Create(product *domain.Product) error
 	GetAll() ([]domain.Product, error)
+	GetPage(query PageQuery) (Page, error)
 	FindById(id int) (domain.Product, error)
-	Search(priceGt float64) ([]domain.Product, error)
+	FindByUID(uid string) (domain.Product, error)
+	Search(expression string) ([]domain.Product, error)
+	SearchText(query string, limit int) ([]ScoredProduct, error)
+	Suggest(prefix string, limit int, rank string) ([]Suggestion, error)
+	Viewed(ids ...int)
+	Import(products []domain.Product) ([]domain.Product, error)
 	Update(product *domain.Product) error
+	Patch(id int, version int, change func(domain.Product) (domain.Product, error)) (domain.Product, error)
 	UpdateName(id int, name string) (domain.Product, error)
+	SetCategories(id int, version int, categories []int) (domain.Product, error)
 	Delete(id int) error
+	Trash() ([]domain.Product, error)
+	Restore(id int) (domain.Product, error)
+	Purge(before time.Time) ([]domain.Product, error)
 	ConsumerPrice(list []int) (domain.ProductsConsumer, error)
+	Quote(items []domain.QuoteItem) (domain.Quote, error)
+	Variants(parentID int) ([]domain.Product, error)
+	CreateVariant(parentID int, variant *domain.Product) error
+	UpdateVariant(parentID int, variant *domain.Product) error
+	UnpublishExpired() ([]UnpublishedProduct, error)
+	Revisions(id int) ([]Revision, error)
+	FindByIdAsOf(id int, at time.Time) (domain.Product, error)
+	Revert(id int, version int, revision int) (domain.Product, error)
+	By(author Author) Service
 }
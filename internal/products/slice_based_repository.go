@@ -2,115 +2,323 @@ package products
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/filter"
 	"github.com/Andrea-Reyna/go-web/pkg/store"
 )
 
+// SliceBasedRepository keeps products in memory and is safe for concurrent
+// use: writers hold the lock while persisting and mutating the slice, and
+// readers always get copies so they never observe in-flight changes.
 type SliceBasedRepository struct {
+	mu       sync.RWMutex
 	products []domain.Product
+	storage  *store.DurableStore
+	ids      *IDAllocator
 }
 
 func NewSliceBasedRepository() (*SliceBasedRepository, error) {
-	prod, err := store.LoadFile()
-
+	scheme, err := ParseIDScheme(os.Getenv("ID_SCHEME"))
+	if err != nil {
+		return nil, err
+	}
+	storage, err := store.OpenDurableStore(os.Getenv("FILE"))
 	if err != nil {
 		return nil, fmt.Errorf("error data: %w", err)
 	}
+	products := storage.Products()
+	for i := range products {
+		// Products saved before versioning start at the first version.
+		if products[i].Version == 0 {
+			products[i].Version = 1
+		}
+	}
+	ids, err := NewIDAllocator(os.Getenv("FILE")+".ids", scheme, products)
+	if err != nil {
+		return nil, err
+	}
 	repository := &SliceBasedRepository{
-		products: prod,
+		products: products,
+		storage:  storage,
+		ids:      ids,
 	}
 	return repository, nil
 }
 
+func (repository *SliceBasedRepository) Close() error {
+	return repository.storage.Close()
+}
+
 func (repository *SliceBasedRepository) Create(product *domain.Product) (err error) {
-	product.ID = len(repository.products) + 1
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if repository.codeTaken(product.CodeValue, 0) {
+		return ErrProductAlreadyExists
+	}
+	if err = repository.ids.Assign(product); err != nil {
+		return
+	}
+	product.Categories = nil
+	product.Version = 1
+	if err = repository.storage.Append(store.OperationCreate, *product); err != nil {
+		return
+	}
 	repository.products = append(repository.products, *product)
-	//store.SaveProducts(repository.products)
 	return
 }
 
 func (repository *SliceBasedRepository) Update(product *domain.Product) (err error) {
-	var updated bool
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if repository.codeTaken(product.CodeValue, product.ID) {
+		return ErrProductAlreadyExists
+	}
 	for i := range repository.products {
-		if repository.products[i].ID == product.ID {
+		if repository.products[i].ID == product.ID && repository.products[i].DeletedAt == nil {
+			if product.Version != 0 && product.Version != repository.products[i].Version {
+				return ErrVersionConflict
+			}
+			product.UID = repository.products[i].UID
+			product.Categories = repository.products[i].Categories
+			product.DeletedAt, product.DeletedBy = nil, ""
+			product.Version = repository.products[i].Version + 1
+			if err = repository.storage.Append(store.OperationUpdate, *product); err != nil {
+				return
+			}
 			repository.products[i] = *product
-			updated = true
-			break
+			return
 		}
 	}
-	if !updated {
-		return ErrProductNotFound
-	}
-	//store.SaveProducts(repository.products)
-	return
+	return ErrProductNotFound
 }
 
 func (repository *SliceBasedRepository) UpdateName(id int, name string) (domain.Product, error) {
-	var updated bool
-	var product domain.Product
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
 	for i := range repository.products {
-		if repository.products[i].ID == id {
-			repository.products[i].Name = name
-			updated = true
-			break
+		if repository.products[i].ID == id && repository.products[i].DeletedAt == nil {
+			product := repository.products[i]
+			product.Name = name
+			product.Version++
+			if err := repository.storage.Append(store.OperationUpdate, product); err != nil {
+				return domain.Product{}, err
+			}
+			repository.products[i] = product
+			return product, nil
 		}
 	}
-	if !updated {
-		return domain.Product{}, ErrProductNotFound
+	return domain.Product{}, ErrProductNotFound
+}
+
+func (repository *SliceBasedRepository) SetCategories(id int, version int, categories []int) (domain.Product, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	for i := range repository.products {
+		if repository.products[i].ID == id && repository.products[i].DeletedAt == nil {
+			if version != 0 && repository.products[i].Version != version {
+				return domain.Product{}, ErrVersionConflict
+			}
+			product := repository.products[i]
+			product.Categories = append([]int(nil), categories...)
+			product.Version++
+			if err := repository.storage.Append(store.OperationUpdate, product); err != nil {
+				return domain.Product{}, err
+			}
+			repository.products[i] = product
+			return product, nil
+		}
 	}
-	//store.SaveProducts(repository.products)
-	return product, nil
+	return domain.Product{}, ErrProductNotFound
 }
 
 func (repository *SliceBasedRepository) GetAll() ([]domain.Product, error) {
-	return repository.products, nil
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	return repository.live(), nil
+}
+
+func (repository *SliceBasedRepository) GetPage(query PageQuery) (Page, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	return paginate(repository.live(), query)
 }
 
 func (repository *SliceBasedRepository) FindById(id int) (domain.Product, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
 	for i := range repository.products {
-		if repository.products[i].ID == id {
+		if repository.products[i].ID == id && repository.products[i].DeletedAt == nil {
 			return repository.products[i], nil
 		}
 	}
 	return domain.Product{}, ErrProductNotFound
 }
-func (repository *SliceBasedRepository) Search(priceGt float64) ([]domain.Product, error) {
+
+func (repository *SliceBasedRepository) FindByUID(uid string) (domain.Product, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	for i := range repository.products {
+		if uid != "" && strings.EqualFold(repository.products[i].UID, uid) && repository.products[i].DeletedAt == nil {
+			return repository.products[i], nil
+		}
+	}
+	return domain.Product{}, ErrProductNotFound
+}
+
+func (repository *SliceBasedRepository) Variants(parentID int) ([]domain.Product, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	variants := []domain.Product{}
+	for i := range repository.products {
+		if parentID != 0 && repository.products[i].ParentID == parentID && repository.products[i].DeletedAt == nil {
+			variants = append(variants, repository.products[i])
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+	return variants, nil
+}
+
+func (repository *SliceBasedRepository) Search(expression filter.Expression) ([]domain.Product, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
 	var filterProducts []domain.Product
 	for i := range repository.products {
-		if repository.products[i].Price > priceGt {
+		if repository.products[i].DeletedAt == nil && filter.Match(expression, productRecord(repository.products[i])) {
 			filterProducts = append(filterProducts, repository.products[i])
 		}
 	}
 	return filterProducts, nil
 }
 
+func (repository *SliceBasedRepository) Trash(id int, at time.Time, by string) (domain.Product, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	for i := range repository.products {
+		if repository.products[i].ID == id && repository.products[i].DeletedAt == nil {
+			product := repository.products[i]
+			product.DeletedAt = &at
+			product.DeletedBy = by
+			product.Version++
+			if err := repository.storage.Append(store.OperationUpdate, product); err != nil {
+				return domain.Product{}, err
+			}
+			repository.products[i] = product
+			return product, nil
+		}
+	}
+	return domain.Product{}, ErrProductNotFound
+}
+
+func (repository *SliceBasedRepository) Trashed() ([]domain.Product, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	trashed := []domain.Product{}
+	for i := range repository.products {
+		if repository.products[i].DeletedAt != nil {
+			trashed = append(trashed, repository.products[i])
+		}
+	}
+	sortTrashed(trashed)
+	return trashed, nil
+}
+
+func (repository *SliceBasedRepository) Restore(id int) (domain.Product, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	for i := range repository.products {
+		if repository.products[i].ID == id && repository.products[i].DeletedAt != nil {
+			product := repository.products[i]
+			product.DeletedAt, product.DeletedBy = nil, ""
+			product.Version++
+			if err := repository.storage.Append(store.OperationUpdate, product); err != nil {
+				return domain.Product{}, err
+			}
+			repository.products[i] = product
+			return product, nil
+		}
+	}
+	return domain.Product{}, ErrProductNotFound
+}
+
 func (repository *SliceBasedRepository) Delete(id int) error {
-	var deleted bool
-	var index int
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
 	for i := range repository.products {
 		if repository.products[i].ID == id {
-			index = i
-			deleted = true
+			if err := repository.storage.Append(store.OperationDelete, repository.products[i]); err != nil {
+				return err
+			}
+			repository.products = append(repository.products[:i], repository.products[i+1:]...)
+			return nil
 		}
 	}
-	if !deleted {
-		return ErrProductNotFound
-	}
-	repository.products = append(repository.products[:index], repository.products[index+1:]...)
-	store.SaveProducts(repository.products)
-	return nil
+	return ErrProductNotFound
 }
 
 func (repository *SliceBasedRepository) ConsumerPrice(list []int) ([]domain.Product, error) {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
 	var filterProducts []domain.Product
 	for _, id := range list {
 		for i := range repository.products {
-			if repository.products[i].ID == id && repository.products[i].IsPublished {
+			if repository.products[i].ID == id && repository.products[i].IsPublished && repository.products[i].DeletedAt == nil {
 				filterProducts = append(filterProducts, repository.products[i])
 			}
 		}
 	}
-	//store.SaveProducts(repository.products)
 	return filterProducts, nil
 }
+
+// live returns a copy of the products out of the trash.
+func (repository *SliceBasedRepository) live() []domain.Product {
+	products := []domain.Product{}
+	for i := range repository.products {
+		if repository.products[i].DeletedAt == nil {
+			products = append(products, repository.products[i])
+		}
+	}
+	return products
+}
+
+// sortTrashed sorts products in the trash by deletion time, then by ID.
+func sortTrashed(products []domain.Product) {
+	sort.Slice(products, func(i, j int) bool {
+		if !products[i].DeletedAt.Equal(*products[j].DeletedAt) {
+			return products[i].DeletedAt.Before(*products[j].DeletedAt)
+		}
+		return products[i].ID < products[j].ID
+	})
+}
+
+// codeTaken reports whether another product than id already uses code, in
+// the trash or not. It is checked under the write lock so two concurrent
+// requests cannot both pass the service validation with the same code.
+func (repository *SliceBasedRepository) codeTaken(code string, id int) bool {
+	for i := range repository.products {
+		if repository.products[i].CodeValue == code && repository.products[i].ID != id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,665 @@
+package products
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/filter"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// migrations are applied in order and tracked with PRAGMA user_version, so
+// existing databases are upgraded in place. Only append to this list.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS products (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		name         TEXT    NOT NULL,
+		quantity     INTEGER NOT NULL,
+		code_value   TEXT    NOT NULL,
+		is_published INTEGER NOT NULL DEFAULT 0,
+		expiration   TEXT    NOT NULL,
+		price        REAL    NOT NULL
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_products_code_value ON products (code_value);`,
+	`ALTER TABLE products ADD COLUMN uid TEXT;
+	CREATE UNIQUE INDEX idx_products_uid ON products (uid);`,
+	`ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	// Expirations move from DD/MM/YYYY to YYYY-MM-DD, which compares and
+	// sorts in date order.
+	`UPDATE products SET expiration = substr(expiration, 7, 4) || '-' || substr(expiration, 4, 2) || '-' || substr(expiration, 1, 2)
+	WHERE expiration GLOB '[0-9][0-9]/[0-9][0-9]/[0-9][0-9][0-9][0-9]';`,
+	// Prices move to exact minor units with their currency, empty for the
+	// default currency of rows priced before, which had two decimals. The
+	// price column is kept, computed in major units, for filters and sorts.
+	`ALTER TABLE products ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+	UPDATE products SET price_minor = CAST(round(price * 100) AS INTEGER);
+	ALTER TABLE products DROP COLUMN price;
+	ALTER TABLE products ADD COLUMN price REAL GENERATED ALWAYS AS (
+		price_minor * 1.0 / CASE currency WHEN 'CLP' THEN 1 WHEN 'JPY' THEN 1 WHEN 'KWD' THEN 1000 ELSE 100 END
+	) VIRTUAL;`,
+	`CREATE TABLE product_categories (
+		product_id  INTEGER NOT NULL,
+		category_id INTEGER NOT NULL,
+		PRIMARY KEY (product_id, category_id)
+	);
+	CREATE INDEX idx_product_categories_category_id ON product_categories (category_id);`,
+	// Variants point to their parent product, attributes are a JSON object.
+	`ALTER TABLE products ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN attributes TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_products_parent_id ON products (parent_id);`,
+	// Products in the trash have the time they were deleted at, in
+	// deletedAtLayout.
+	`ALTER TABLE products ADD COLUMN deleted_at TEXT;
+	ALTER TABLE products ADD COLUMN deleted_by TEXT NOT NULL DEFAULT '';`,
+}
+
+const productColumns = "id, uid, name, quantity, code_value, is_published, expiration, price_minor, currency, parent_id, attributes, version, deleted_at, deleted_by"
+
+// selectProducts reads the product columns and the categories of each
+// product as a comma separated list.
+const selectProducts = "SELECT " + productColumns + ", (SELECT group_concat(category_id) FROM product_categories WHERE product_id = products.id) FROM products"
+
+// notTrashed selects the products out of the trash.
+const notTrashed = "deleted_at IS NULL"
+
+// deletedAtLayout writes deletion times in UTC with a fixed width, so that
+// they sort in time order.
+const deletedAtLayout = "2006-01-02T15:04:05.000000000Z"
+
+// SQLiteRepository stores products in SQLite. IDs come from AUTOINCREMENT,
+// which never reuses the ID of a deleted row.
+type SQLiteRepository struct {
+	db     *sql.DB
+	scheme IDScheme
+}
+
+func NewSQLiteRepository(path string, scheme IDScheme) (*SQLiteRepository, error) {
+	if path == "" {
+		return nil, errors.New("error data: empty database path")
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	// SQLite serializes writers, a single connection avoids SQLITE_BUSY
+	// errors between concurrent handlers.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteRepository{db: db, scheme: scheme}, nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("error starting migration: %w", err)
+		}
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error applying migration %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error applying migration %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error applying migration %d: %w", version+1, err)
+		}
+	}
+	return nil
+}
+
+func (repository *SQLiteRepository) Close() error {
+	return repository.db.Close()
+}
+
+func (repository *SQLiteRepository) Create(product *domain.Product) error {
+	product.UID = repository.scheme.NewUID()
+	product.Categories = nil
+	product.Version = 1
+	attributes, err := encodeAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
+	result, err := repository.db.Exec(
+		"INSERT INTO products (uid, name, quantity, code_value, is_published, expiration, price_minor, currency, parent_id, attributes, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		nullString(product.UID), product.Name, product.Quantity, product.CodeValue, product.IsPublished, product.Expiration.ISO(), product.Price.Amount, product.Price.Currency, product.ParentID, attributes, product.Version,
+	)
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error reading inserted id: %w", err)
+	}
+	product.ID = int(id)
+	return nil
+}
+
+func (repository *SQLiteRepository) GetAll() ([]domain.Product, error) {
+	return repository.query(selectProducts + " WHERE " + notTrashed + " ORDER BY id")
+}
+
+// GetPage pushes sorting, the cursor condition and the limit down to SQLite.
+func (repository *SQLiteRepository) GetPage(query PageQuery) (Page, error) {
+	conditions := []string{notTrashed}
+	var args []interface{}
+	if len(query.Categories) > 0 {
+		conditions = append(conditions, "id IN (SELECT product_id FROM product_categories WHERE category_id IN ("+placeholders(len(query.Categories))+"))")
+		for _, category := range query.Categories {
+			args = append(args, category)
+		}
+	}
+	var page Page
+	if err := repository.db.QueryRow("SELECT COUNT(*) FROM products"+where(conditions), args...).Scan(&page.Total); err != nil {
+		return Page{}, fmt.Errorf("error counting products: %w", err)
+	}
+
+	fields := query.orderBy()
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, fields)
+		if err != nil {
+			return Page{}, err
+		}
+		condition, keys := keysetCondition(fields, after)
+		conditions = append(conditions, condition)
+		args = append(args, keys...)
+	}
+
+	order := make([]string, len(fields))
+	for i, field := range fields {
+		order[i] = field.Field
+		if field.Desc {
+			order[i] += " DESC"
+		}
+	}
+
+	// One extra row tells whether there is a next page.
+	limit := -1
+	if query.Limit > 0 {
+		limit = query.Limit + 1
+	}
+	args = append(args, limit, query.Offset)
+	products, err := repository.query(
+		selectProducts+where(conditions)+" ORDER BY "+strings.Join(order, ", ")+" LIMIT ? OFFSET ?",
+		args...,
+	)
+	if err != nil {
+		return Page{}, err
+	}
+	if query.Limit > 0 && len(products) > query.Limit {
+		products = products[:query.Limit]
+		page.NextCursor = encodeCursor(fields, products[len(products)-1])
+	}
+	page.Products = append([]domain.Product{}, products...)
+	return page, nil
+}
+
+// keysetCondition selects the rows sorted after keys, expanding the row value
+// comparison so each field can have its own direction:
+// (a > ?) OR (a = ? AND b < ?) OR ...
+func keysetCondition(fields []SortField, keys []interface{}) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	for i := range fields {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fields[j].Field+" = ?")
+			args = append(args, keys[j])
+		}
+		operator := " > ?"
+		if fields[i].Desc {
+			operator = " < ?"
+		}
+		parts = append(parts, fields[i].Field+operator)
+		args = append(args, keys[i])
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// where joins conditions into a WHERE clause, empty without conditions.
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", count), ",")
+}
+
+func (repository *SQLiteRepository) FindById(id int) (domain.Product, error) {
+	row := repository.db.QueryRow(selectProducts+" WHERE id = ? AND "+notTrashed, id)
+	product, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, ErrProductNotFound
+	}
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+func (repository *SQLiteRepository) FindByUID(uid string) (domain.Product, error) {
+	row := repository.db.QueryRow(selectProducts+" WHERE uid = ? COLLATE NOCASE AND "+notTrashed, uid)
+	product, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, ErrProductNotFound
+	}
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+func (repository *SQLiteRepository) Variants(parentID int) ([]domain.Product, error) {
+	variants, err := repository.query(selectProducts+" WHERE parent_id = ? AND parent_id <> 0 AND "+notTrashed+" ORDER BY id", parentID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]domain.Product{}, variants...), nil
+}
+
+// lower replaces the lower() of SQLite, which only lowers ASCII letters, with
+// strings.ToLower, so that the "~" searches ignore the case of every letter,
+// like the searches in memory.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("lower", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch value := args[0].(type) {
+		case string:
+			return strings.ToLower(value), nil
+		case []byte:
+			return strings.ToLower(string(value)), nil
+		default:
+			return value, nil
+		}
+	})
+}
+
+// filterColumns maps the search fields to SQL. Expirations are stored as
+// YYYY-MM-DD, like date literals.
+var filterColumns = map[string]string{
+	"id":           "id",
+	"name":         "name",
+	"quantity":     "quantity",
+	"code_value":   "code_value",
+	"is_published": "is_published",
+	"expiration":   "expiration",
+	"price":        "price",
+}
+
+func (repository *SQLiteRepository) Search(expression filter.Expression) ([]domain.Product, error) {
+	condition, args := filter.SQL(expression, filterColumns)
+	return repository.query(selectProducts+" WHERE "+notTrashed+" AND ("+condition+") ORDER BY id", args...)
+}
+
+// Update checks the expected version in the WHERE clause, so the check and
+// the write are a single statement.
+func (repository *SQLiteRepository) Update(product *domain.Product) error {
+	attributes, err := encodeAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
+	var uid, categories sql.NullString
+	var version int
+	err = repository.db.QueryRow(
+		"UPDATE products SET name = ?, quantity = ?, code_value = ?, is_published = ?, expiration = ?, price_minor = ?, currency = ?, parent_id = ?, attributes = ?, version = version + 1 WHERE id = ? AND "+notTrashed+" AND (? = 0 OR version = ?) RETURNING uid, version, (SELECT group_concat(category_id) FROM product_categories WHERE product_id = products.id)",
+		product.Name, product.Quantity, product.CodeValue, product.IsPublished, product.Expiration.ISO(), product.Price.Amount, product.Price.Currency, product.ParentID, attributes, product.ID, product.Version, product.Version,
+	).Scan(&uid, &version, &categories)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := repository.FindById(product.ID); err != nil {
+			return err
+		}
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	product.UID = uid.String
+	product.Version = version
+	product.DeletedAt, product.DeletedBy = nil, ""
+	if product.Categories, err = parseCategories(categories); err != nil {
+		return fmt.Errorf("error scanning product %d: %w", product.ID, err)
+	}
+	return nil
+}
+
+func (repository *SQLiteRepository) UpdateName(id int, name string) (domain.Product, error) {
+	result, err := repository.db.Exec("UPDATE products SET name = ?, version = version + 1 WHERE id = ? AND "+notTrashed, name, id)
+	if err != nil {
+		return domain.Product{}, mapSQLiteError(err)
+	}
+	if err := expectAffected(result); err != nil {
+		return domain.Product{}, err
+	}
+	return repository.FindById(id)
+}
+
+// SetCategories replaces the categories of the product and increments its
+// version in one transaction, which checks the version too.
+func (repository *SQLiteRepository) SetCategories(id int, version int, categories []int) (domain.Product, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return domain.Product{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRow("SELECT version FROM products WHERE id = ? AND "+notTrashed, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, ErrProductNotFound
+	}
+	if err != nil {
+		return domain.Product{}, mapSQLiteError(err)
+	}
+	if version != 0 && current != version {
+		return domain.Product{}, ErrVersionConflict
+	}
+	if _, err := tx.Exec("UPDATE products SET version = version + 1 WHERE id = ?", id); err != nil {
+		return domain.Product{}, mapSQLiteError(err)
+	}
+	if _, err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", id); err != nil {
+		return domain.Product{}, mapSQLiteError(err)
+	}
+	for _, category := range categories {
+		if _, err := tx.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)", id, category); err != nil {
+			return domain.Product{}, mapSQLiteError(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Product{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return repository.FindById(id)
+}
+
+func (repository *SQLiteRepository) Trash(id int, at time.Time, by string) (domain.Product, error) {
+	result, err := repository.db.Exec(
+		"UPDATE products SET deleted_at = ?, deleted_by = ?, version = version + 1 WHERE id = ? AND "+notTrashed,
+		deletedAt(&at), by, id,
+	)
+	if err != nil {
+		return domain.Product{}, mapSQLiteError(err)
+	}
+	if err := expectAffected(result); err != nil {
+		return domain.Product{}, err
+	}
+	return scanProduct(repository.db.QueryRow(selectProducts+" WHERE id = ?", id))
+}
+
+func (repository *SQLiteRepository) Trashed() ([]domain.Product, error) {
+	trashed, err := repository.query(selectProducts + " WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id")
+	if err != nil {
+		return nil, err
+	}
+	return append([]domain.Product{}, trashed...), nil
+}
+
+func (repository *SQLiteRepository) Restore(id int) (domain.Product, error) {
+	result, err := repository.db.Exec("UPDATE products SET deleted_at = NULL, deleted_by = '', version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return domain.Product{}, mapSQLiteError(err)
+	}
+	if err := expectAffected(result); err != nil {
+		return domain.Product{}, err
+	}
+	return repository.FindById(id)
+}
+
+func (repository *SQLiteRepository) Delete(id int) error {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM products WHERE id = ?", id)
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	if err := expectAffected(result); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", id); err != nil {
+		return mapSQLiteError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (repository *SQLiteRepository) ConsumerPrice(list []int) ([]domain.Product, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(list))
+	for i, id := range list {
+		args[i] = id
+	}
+	found, err := repository.query(
+		selectProducts+" WHERE is_published = 1 AND "+notTrashed+" AND id IN ("+placeholders(len(list))+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the same semantics as SliceBasedRepository: one entry per requested
+	// id, in request order, repeated ids counted every time they appear.
+	byID := make(map[int]domain.Product, len(found))
+	for _, product := range found {
+		byID[product.ID] = product
+	}
+	var filterProducts []domain.Product
+	for _, id := range list {
+		if product, ok := byID[id]; ok {
+			filterProducts = append(filterProducts, product)
+		}
+	}
+	return filterProducts, nil
+}
+
+func (repository *SQLiteRepository) query(query string, args ...interface{}) ([]domain.Product, error) {
+	rows, err := repository.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying products: %w", err)
+	}
+	defer rows.Close()
+
+	var products []domain.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading products: %w", err)
+	}
+	return products, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row scanner) (domain.Product, error) {
+	var product domain.Product
+	var uid, deletedAt, categories sql.NullString
+	var expiration, currency, attributes string
+	err := row.Scan(
+		&product.ID,
+		&uid,
+		&product.Name,
+		&product.Quantity,
+		&product.CodeValue,
+		&product.IsPublished,
+		&expiration,
+		&product.Price.Amount,
+		&currency,
+		&product.ParentID,
+		&attributes,
+		&product.Version,
+		&deletedAt,
+		&product.DeletedBy,
+		&categories,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, fmt.Errorf("error scanning product: %w", err)
+	}
+	product.UID = uid.String
+	product.Price.Currency = domain.Currency(currency)
+	if currency == "" {
+		product.Price.Currency = domain.DefaultCurrency()
+	}
+	if err == nil && expiration != "" {
+		if product.Expiration, err = domain.ParseDate(expiration); err != nil {
+			return domain.Product{}, fmt.Errorf("error scanning product %d: %w", product.ID, err)
+		}
+	}
+	if err == nil {
+		if product.Categories, err = parseCategories(categories); err != nil {
+			return domain.Product{}, fmt.Errorf("error scanning product %d: %w", product.ID, err)
+		}
+	}
+	if err == nil && deletedAt.Valid {
+		var at time.Time
+		if at, err = time.Parse(deletedAtLayout, deletedAt.String); err != nil {
+			return domain.Product{}, fmt.Errorf("error scanning product %d: %w", product.ID, err)
+		}
+		product.DeletedAt = &at
+	}
+	if err == nil && attributes != "" {
+		if err = json.Unmarshal([]byte(attributes), &product.Attributes); err != nil {
+			return domain.Product{}, fmt.Errorf("error scanning product %d: %w", product.ID, err)
+		}
+	}
+	return product, err
+}
+
+// encodeAttributes writes the attributes of a variant as a JSON object, or
+// as an empty string for products without attributes.
+func encodeAttributes(attributes map[string]string) (string, error) {
+	if len(attributes) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return "", fmt.Errorf("error encoding attributes: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseCategories reads the comma separated category IDs of a product,
+// which group_concat lists in no particular order.
+func parseCategories(list sql.NullString) ([]int, error) {
+	if !list.Valid || list.String == "" {
+		return nil, nil
+	}
+	parts := strings.Split(list.String, ",")
+	categories := make([]int, len(parts))
+	for i, part := range parts {
+		category, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		categories[i] = category
+	}
+	sort.Ints(categories)
+	return categories, nil
+}
+
+// deletedAt writes the time a product was moved to the trash, NULL for the
+// products out of it.
+func deletedAt(at *time.Time) sql.NullString {
+	if at == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: at.UTC().Format(deletedAtLayout), Valid: true}
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func expectAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error reading affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+func mapSQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return ErrProductAlreadyExists
+	}
+	return fmt.Errorf("error executing statement: %w", err)
+}
+
+// Seed loads the given products keeping their ids, only when the table is
+// empty. It lets a deployment switch from products.json to SQLite without
+// losing data.
+func (repository *SQLiteRepository) Seed(products []domain.Product) error {
+	var count int
+	if err := repository.db.QueryRow("SELECT COUNT(*) FROM products").Scan(&count); err != nil {
+		return fmt.Errorf("error counting products: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, product := range products {
+		if product.Version == 0 {
+			product.Version = 1
+		}
+		attributes, err := encodeAttributes(product.Attributes)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO products ("+productColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			product.ID, nullString(product.UID), product.Name, product.Quantity, product.CodeValue, product.IsPublished, product.Expiration.ISO(), product.Price.Amount, product.Price.Currency, product.ParentID, attributes, product.Version, deletedAt(product.DeletedAt), product.DeletedBy,
+		)
+		if err != nil {
+			return mapSQLiteError(err)
+		}
+		for _, category := range product.Categories {
+			if _, err := tx.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)", product.ID, category); err != nil {
+				return mapSQLiteError(err)
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,372 @@
+package products
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteRepository(t *testing.T) *SQLiteRepository {
+	repository, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "products.db"), IDSchemeSequential)
+	require.NoError(t, err)
+	t.Cleanup(func() { repository.Close() })
+	return repository
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	t.Run("should migrate expirations to ISO dates and prices to minor units", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "products.db")
+		db, err := sql.Open("sqlite", path)
+		require.NoError(t, err)
+		for _, migration := range migrations[:3] {
+			_, err = db.Exec(migration)
+			require.NoError(t, err)
+		}
+		_, err = db.Exec("PRAGMA user_version = 3")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO products (name, quantity, code_value, expiration, price) VALUES ('Wine', 1, 'T65812', '24/05/2021', 179.23)")
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		repository, err := NewSQLiteRepository(path, IDSchemeSequential)
+		require.NoError(t, err)
+		t.Cleanup(func() { repository.Close() })
+
+		product, err := repository.FindById(1)
+		require.NoError(t, err)
+		assert.Equal(t, domain.NewDate(2021, time.May, 24), product.Expiration)
+		assert.Equal(t, domain.NewMoney(17923, domain.DefaultCurrency()), product.Price)
+		var stored string
+		require.NoError(t, repository.db.QueryRow("SELECT expiration FROM products").Scan(&stored))
+		assert.Equal(t, "2021-05-24", stored)
+	})
+
+	t.Run("should create, update and delete a product", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		product := domain.Product{
+			Name:        "Cookie - Oatmeal",
+			Quantity:    130,
+			CodeValue:   "M7157",
+			IsPublished: true,
+			Expiration:  domain.NewDate(2022, time.January, 28),
+			Price:       domain.NewMoney(27547, "ARS"),
+		}
+
+		require.NoError(t, repository.Create(&product))
+		assert.Equal(t, 1, product.ID)
+		assert.Equal(t, 1, product.Version)
+
+		product.Price = domain.NewMoney(30000, "ARS")
+		require.NoError(t, repository.Update(&product))
+		assert.Equal(t, 2, product.Version)
+
+		renamed, err := repository.UpdateName(product.ID, "Cookie - Chocolate")
+		require.NoError(t, err)
+		assert.Equal(t, "Cookie - Chocolate", renamed.Name)
+		assert.Equal(t, domain.NewMoney(30000, "ARS"), renamed.Price)
+
+		require.NoError(t, repository.Delete(product.ID))
+		_, err = repository.FindById(product.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.ErrorIs(t, repository.Delete(product.ID), ErrProductNotFound)
+	})
+
+	t.Run("should only update the expected version", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		product := domain.Product{Name: "Wine", CodeValue: "T65812", Expiration: domain.NewDate(2021, time.May, 24)}
+		require.NoError(t, repository.Create(&product))
+
+		stale := product
+		product.Price = domain.NewMoney(10000, "ARS")
+		require.NoError(t, repository.Update(&product))
+
+		stale.Price = domain.NewMoney(20000, "ARS")
+		assert.ErrorIs(t, repository.Update(&stale), ErrVersionConflict)
+		found, err := repository.FindById(product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(10000, "ARS"), found.Price)
+
+		stale.Version = 0
+		require.NoError(t, repository.Update(&stale))
+		assert.Equal(t, 3, stale.Version)
+
+		missing := domain.Product{ID: 99, CodeValue: "X1", Version: 1}
+		assert.ErrorIs(t, repository.Update(&missing), ErrProductNotFound)
+	})
+
+	t.Run("should reject a duplicated code value", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		first := domain.Product{Name: "first", CodeValue: "M4637", Expiration: domain.NewDate(2021, time.August, 9)}
+		second := domain.Product{Name: "second", CodeValue: "M4637", Expiration: domain.NewDate(2021, time.August, 9)}
+
+		require.NoError(t, repository.Create(&first))
+		assert.ErrorIs(t, repository.Create(&second), ErrProductAlreadyExists)
+	})
+
+	t.Run("should search and price consumer lists", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		require.NoError(t, repository.Seed([]domain.Product{
+			{ID: 2, Name: "Pineapple", CodeValue: "M4637", IsPublished: true, Expiration: domain.NewDate(2021, time.August, 9), Price: domain.NewMoney(35279, "ARS")},
+			{ID: 3, Name: "Wine", CodeValue: "T65812", IsPublished: false, Expiration: domain.NewDate(2021, time.May, 24), Price: domain.NewMoney(17923, "ARS")},
+			{ID: 4, Name: "Cookie", CodeValue: "M7157", IsPublished: true, Expiration: domain.NewDate(2022, time.January, 28), Price: domain.NewMoney(27547, "ARS")},
+		}))
+
+		expression, err := ParseFilter("price>200")
+		require.NoError(t, err)
+		found, err := repository.Search(expression)
+		require.NoError(t, err)
+		assert.Len(t, found, 2)
+
+		consumer, err := repository.ConsumerPrice([]int{4, 3, 2, 4, 99})
+		require.NoError(t, err)
+		ids := []int{}
+		for _, product := range consumer {
+			ids = append(ids, product.ID)
+		}
+		assert.Equal(t, []int{4, 2, 4}, ids)
+
+		next := domain.Product{Name: "new", CodeValue: "N1", Expiration: domain.NewDate(2022, time.January, 1)}
+		require.NoError(t, repository.Create(&next))
+		assert.Equal(t, 5, next.ID)
+	})
+}
+
+func TestSQLiteRepository_SetCategories(t *testing.T) {
+	t.Run("should keep the categories of a product until they are set again", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		product := domain.Product{Name: "Wine", Quantity: 1, CodeValue: "T65812", Price: domain.NewMoney(17923, "ARS")}
+		require.NoError(t, repository.Create(&product))
+
+		updated, err := repository.SetCategories(product.ID, 1, []int{3, 1})
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 3}, updated.Categories)
+		assert.Equal(t, 2, updated.Version)
+		_, err = repository.SetCategories(product.ID, 1, []int{1})
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		updated.Quantity = 5
+		updated.Categories = nil
+		require.NoError(t, repository.Update(&updated))
+		assert.Equal(t, []int{1, 3}, updated.Categories)
+
+		updated, err = repository.SetCategories(product.ID, 0, nil)
+		require.NoError(t, err)
+		assert.Empty(t, updated.Categories)
+		_, err = repository.SetCategories(99, 0, []int{1})
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("should forget the categories of a deleted product", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		product := domain.Product{Name: "Wine", Quantity: 1, CodeValue: "T65812", Price: domain.NewMoney(17923, "ARS")}
+		require.NoError(t, repository.Create(&product))
+		_, err := repository.SetCategories(product.ID, 0, []int{1})
+		require.NoError(t, err)
+
+		require.NoError(t, repository.Delete(product.ID))
+		page, err := repository.GetPage(PageQuery{Categories: []int{1}})
+		require.NoError(t, err)
+		assert.Equal(t, 0, page.Total)
+	})
+}
+
+func TestSQLiteRepository_Variants(t *testing.T) {
+	t.Run("should list the variants of a product with their attributes", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		parent := domain.Product{Name: "Wine", Quantity: 0, CodeValue: "T65812", Price: domain.NewMoney(17923, "ARS")}
+		require.NoError(t, repository.Create(&parent))
+		for _, size := range []string{"750ml", "1.5l"} {
+			variant := domain.Product{Name: "Wine " + size, Quantity: 3, CodeValue: "T65812-" + size, Price: domain.NewMoney(17923, "ARS"), ParentID: parent.ID, Attributes: map[string]string{domain.AttributeSize: size}}
+			require.NoError(t, repository.Create(&variant))
+		}
+
+		variants, err := repository.Variants(parent.ID)
+		require.NoError(t, err)
+		require.Len(t, variants, 2)
+		assert.Equal(t, parent.ID, variants[0].ParentID)
+		assert.Equal(t, map[string]string{"size": "750ml"}, variants[0].Attributes)
+		assert.Equal(t, map[string]string{"size": "1.5l"}, variants[1].Attributes)
+
+		variants, err = repository.Variants(variants[0].ID)
+		require.NoError(t, err)
+		assert.Empty(t, variants)
+		stored, err := repository.FindById(parent.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.Attributes)
+	})
+}
+
+func TestSQLiteRepository_Trash(t *testing.T) {
+	t.Run("should hide the products in the trash until they are restored", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		wine := domain.Product{Name: "Wine", Quantity: 1, CodeValue: "T65812", IsPublished: true, Price: domain.NewMoney(17923, "ARS")}
+		cookie := domain.Product{Name: "Cookie", Quantity: 1, CodeValue: "M7157", IsPublished: true, Price: domain.NewMoney(27547, "ARS")}
+		require.NoError(t, repository.Create(&wine))
+		require.NoError(t, repository.Create(&cookie))
+
+		deletedAt := time.Date(2022, time.January, 1, 12, 0, 0, 500, time.UTC)
+		trashed, err := repository.Trash(wine.ID, deletedAt, "ana")
+		require.NoError(t, err)
+		assert.Equal(t, deletedAt, *trashed.DeletedAt)
+		assert.Equal(t, "ana", trashed.DeletedBy)
+		assert.Equal(t, 2, trashed.Version)
+		_, err = repository.Trash(wine.ID, deletedAt, "ana")
+		assert.ErrorIs(t, err, ErrProductNotFound)
+
+		_, err = repository.FindById(wine.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		all, err := repository.GetAll()
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+		page, err := repository.GetPage(PageQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.Total)
+		listed, err := repository.ConsumerPrice([]int{wine.ID, cookie.ID})
+		require.NoError(t, err)
+		assert.Len(t, listed, 1)
+		_, err = repository.UpdateName(wine.ID, "Red wine")
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.ErrorIs(t, repository.Update(&wine), ErrProductNotFound)
+		duplicated := domain.Product{Name: "Wine", Quantity: 1, CodeValue: "T65812", Price: domain.NewMoney(17923, "ARS")}
+		assert.ErrorIs(t, repository.Create(&duplicated), ErrProductAlreadyExists)
+
+		inTrash, err := repository.Trashed()
+		require.NoError(t, err)
+		require.Len(t, inTrash, 1)
+		assert.Equal(t, wine.ID, inTrash[0].ID)
+
+		restored, err := repository.Restore(wine.ID)
+		require.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		assert.Empty(t, restored.DeletedBy)
+		assert.Equal(t, 3, restored.Version)
+		_, err = repository.Restore(wine.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("should list the trash by deletion time", func(t *testing.T) {
+		repository := newTestSQLiteRepository(t)
+		deletedAt := time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+		for i, code := range []string{"A1", "B2", "C3"} {
+			product := domain.Product{Name: code, Quantity: 1, CodeValue: code, Price: domain.NewMoney(100, "ARS")}
+			require.NoError(t, repository.Create(&product))
+			// A whole second sorts after a fraction of it.
+			_, err := repository.Trash(product.ID, deletedAt.Add(time.Duration(2-i)*500*time.Millisecond), "ana")
+			require.NoError(t, err)
+		}
+
+		inTrash, err := repository.Trashed()
+		require.NoError(t, err)
+		codes := []string{}
+		for _, product := range inTrash {
+			codes = append(codes, product.CodeValue)
+		}
+		assert.Equal(t, []string{"C3", "B2", "A1"}, codes)
+
+		require.NoError(t, repository.Delete(inTrash[0].ID))
+		inTrash, err = repository.Trashed()
+		require.NoError(t, err)
+		assert.Len(t, inTrash, 2)
+	})
+}
+
+func TestSQLiteRepository_GetPage(t *testing.T) {
+	seed := []domain.Product{
+		{ID: 2, Name: "Pineapple", CodeValue: "M4637", Price: domain.NewMoney(35279, "ARS"), Categories: []int{1}, Version: 1},
+		{ID: 3, Name: "Wine", CodeValue: "T65812", Price: domain.NewMoney(17923, "ARS"), Categories: []int{2, 3}, Version: 1},
+		{ID: 4, Name: "Cookie", CodeValue: "M7157", Price: domain.NewMoney(27547, "ARS"), Version: 1},
+		{ID: 5, Name: "Apple", CodeValue: "A1", Price: domain.NewMoney(27547, "ARS"), Categories: []int{1}, Version: 1},
+		{ID: 6, Name: "Beer", CodeValue: "B1", Price: domain.NewMoney(17923, "ARS"), Categories: []int{2}, Version: 1},
+	}
+	repository := newTestSQLiteRepository(t)
+	require.NoError(t, repository.Seed(seed))
+
+	sortFields, err := ParseSort("-price,name")
+	require.NoError(t, err)
+
+	t.Run("should walk the same pages as the in-memory pagination", func(t *testing.T) {
+		query := PageQuery{Limit: 2, Sort: sortFields}
+		for {
+			fromSQL, err := repository.GetPage(query)
+			require.NoError(t, err)
+			inMemory, err := paginate(seed, query)
+			require.NoError(t, err)
+
+			assert.Equal(t, inMemory, fromSQL)
+			if fromSQL.NextCursor == "" {
+				break
+			}
+			query.Cursor = fromSQL.NextCursor
+		}
+	})
+
+	t.Run("should filter by categories like the in-memory pagination", func(t *testing.T) {
+		query := PageQuery{Limit: 1, Sort: sortFields, Categories: []int{1, 3}}
+		var names []string
+		for {
+			fromSQL, err := repository.GetPage(query)
+			require.NoError(t, err)
+			inMemory, err := paginate(seed, query)
+			require.NoError(t, err)
+
+			assert.Equal(t, inMemory, fromSQL)
+			assert.Equal(t, 3, fromSQL.Total)
+			names = append(names, fromSQL.Products[0].Name)
+			if fromSQL.NextCursor == "" {
+				break
+			}
+			query.Cursor = fromSQL.NextCursor
+		}
+		assert.Equal(t, []string{"Pineapple", "Apple", "Wine"}, names)
+	})
+
+	t.Run("should reject a cursor created for another sort", func(t *testing.T) {
+		page, err := repository.GetPage(PageQuery{Limit: 2, Sort: sortFields})
+		require.NoError(t, err)
+
+		_, err = repository.GetPage(PageQuery{Limit: 2, Cursor: page.NextCursor})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestSQLiteRepository_Search(t *testing.T) {
+	seed := []domain.Product{
+		{ID: 2, Name: "Pineapple - Canned", CodeValue: "M4637", IsPublished: true, Quantity: 345, Expiration: domain.NewDate(2021, time.August, 9), Price: domain.NewMoney(35279, "ARS"), Version: 1},
+		{ID: 3, Name: "Wine - Red Oakridge Merlot", CodeValue: "T65812", Quantity: 367, Expiration: domain.NewDate(2021, time.May, 24), Price: domain.NewMoney(17923, "ARS"), Version: 1},
+		{ID: 4, Name: "Cookie - Oatmeal", CodeValue: "M7157", Quantity: 130, Expiration: domain.NewDate(2022, time.January, 28), Price: domain.NewMoney(27547, "ARS"), Version: 1},
+		{ID: 5, Name: "Wine - White", CodeValue: "W1", IsPublished: true, Quantity: 10, Expiration: domain.NewDate(2023, time.January, 1), Price: domain.NewMoney(9950, "ARS"), Version: 1},
+		{ID: 6, Name: "LIMÓN - Sutil", CodeValue: "L1", IsPublished: true, Quantity: 20, Expiration: domain.NewDate(2023, time.March, 1), Price: domain.NewMoney(1500, "ARS"), Version: 1},
+	}
+	repository := newTestSQLiteRepository(t)
+	require.NoError(t, repository.Seed(seed))
+
+	expressions := []string{
+		`name~"wine"`,
+		`name~"limón"`,
+		`expiration<2022-01-01`,
+		`expiration>=2022-01-28 AND is_published=false`,
+		`quantity BETWEEN 100 AND 350 OR code_value IN ("W1")`,
+		`NOT is_published=true AND price>200`,
+	}
+	for _, expression := range expressions {
+		parsed, err := ParseFilter(expression)
+		require.NoError(t, err)
+
+		fromSQL, err := repository.Search(parsed)
+		require.NoError(t, err)
+		var inMemory []domain.Product
+		for _, product := range seed {
+			if filter.Match(parsed, productRecord(product)) {
+				inMemory = append(inMemory, product)
+			}
+		}
+		assert.NotEmpty(t, inMemory, expression)
+		assert.Equal(t, inMemory, fromSQL, expression)
+	}
+}
@@ -0,0 +1,140 @@
+package products
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+var (
+	ErrProductNotInTrash = errors.New("product not in trash")
+	ErrCodeValueInTrash  = errors.New("a product in the trash has this code value")
+	ErrParentInTrash     = errors.New("the parent of the variant is not restored")
+)
+
+// Delete moves product id to the trash, deleted by the actor of the service,
+// until it is restored or purged. A product with variants is only deleted
+// once its variants are.
+func (service DefaultService) Delete(id int) error {
+	variants, err := service.Storage.Variants(id)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if len(variants) > 0 {
+		return ErrProductHasVariants
+	}
+	trashed, err := service.Storage.Trash(id, service.now(), service.Author.Actor)
+	if errors.Is(err, ErrProductNotFound) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return ErrInternalServerError
+	}
+	previous := trashed
+	previous.DeletedAt, previous.DeletedBy = nil, ""
+	service.record(Revision{Operation: OperationDelete}, &previous, trashed)
+	return nil
+}
+
+// Trash returns the products in the trash, oldest deletion first.
+func (service DefaultService) Trash() ([]domain.Product, error) {
+	trashed, err := service.Storage.Trashed()
+	if err != nil {
+		return []domain.Product{}, ErrInternalServerError
+	}
+	return trashed, nil
+}
+
+// Restore moves product id out of the trash. A variant is only restored once
+// its parent is, and while no other variant has its attributes.
+func (service DefaultService) Restore(id int) (domain.Product, error) {
+	trashed, err := service.trashed(id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if trashed.ParentID != 0 {
+		if _, err := service.Storage.FindById(trashed.ParentID); err != nil {
+			return domain.Product{}, ErrParentInTrash
+		}
+		siblings, err := service.Storage.Variants(trashed.ParentID)
+		if err != nil {
+			return domain.Product{}, ErrInternalServerError
+		}
+		for _, sibling := range siblings {
+			if sameAttributes(sibling.Attributes, trashed.Attributes) {
+				return domain.Product{}, validation.Errors{{Field: "attributes", Code: "unique", Message: "must differ from the attributes of the other variants"}}
+			}
+		}
+	}
+	restored, err := service.Storage.Restore(id)
+	if errors.Is(err, ErrProductNotFound) {
+		return domain.Product{}, ErrProductNotInTrash
+	}
+	if err != nil {
+		return domain.Product{}, ErrInternalServerError
+	}
+	service.record(Revision{Operation: OperationRestore}, &trashed, restored)
+	service.changed(nil, restored)
+	return restored, nil
+}
+
+// Purge deletes for good the products moved to the trash before a time, and
+// returns them. Variants were deleted before their parents, so they are
+// purged first. The errors of the products it could not delete do not stop
+// it from deleting the others.
+func (service DefaultService) Purge(before time.Time) ([]domain.Product, error) {
+	trashed, err := service.Storage.Trashed()
+	if err != nil {
+		return []domain.Product{}, ErrInternalServerError
+	}
+	purged := []domain.Product{}
+	var errs []error
+	for _, product := range trashed {
+		if !product.DeletedAt.Before(before) {
+			break
+		}
+		err := service.Storage.Delete(product.ID)
+		if errors.Is(err, ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("product %d: %w", product.ID, err))
+			continue
+		}
+		service.record(Revision{Operation: OperationPurge}, &product, product)
+		purged = append(purged, product)
+	}
+	return purged, errors.Join(errs...)
+}
+
+// trashed returns product id from the trash.
+func (service DefaultService) trashed(id int) (domain.Product, error) {
+	trashed, err := service.Storage.Trashed()
+	if err != nil {
+		return domain.Product{}, ErrInternalServerError
+	}
+	for _, product := range trashed {
+		if product.ID == id {
+			return product, nil
+		}
+	}
+	return domain.Product{}, ErrProductNotInTrash
+}
+
+// codeInTrash reports whether a product in the trash has code, since code
+// values stay taken until the products are purged.
+func (service DefaultService) codeInTrash(code string) (bool, error) {
+	trashed, err := service.Storage.Trashed()
+	if err != nil {
+		return false, err
+	}
+	for _, product := range trashed {
+		if product.CodeValue == code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,75 @@
+package products
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+const (
+	MaxNameLength      = 100
+	MaxCodeValueLength = 20
+)
+
+// codeValuePattern matches uppercase letters and digits, optionally split in
+// groups by hyphens, e.g. S82254D or RACE-1-2.
+var codeValuePattern = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9]+)*$`)
+
+// nameRules are shared by every write that sets the name of a product.
+var nameRules = []validation.Rule[string]{validation.Required[string](), validation.MaxLength(MaxNameLength)}
+
+// ValidateProduct checks the fields of product and returns validation.Errors
+// listing every violation. Previous is the stored product an update replaces,
+// nil on create: the expiration of a published product must not be in the
+// past, as of now, only when the write publishes it or changes its
+// expiration, so products that expired while published can still be edited.
+func ValidateProduct(product domain.Product, previous *domain.Product, now time.Time) error {
+	publishing := product.IsPublished &&
+		(previous == nil || !previous.IsPublished || previous.Expiration != product.Expiration)
+
+	return validation.Validate(
+		validation.Field("name", product.Name, nameRules...),
+		validation.Field("quantity", product.Quantity, validation.Min(0)),
+		validation.Field("code_value", product.CodeValue,
+			validation.Required[string](),
+			validation.MaxLength(MaxCodeValueLength),
+			validation.Pattern(codeValuePattern, "uppercase letters and digits, optionally separated by hyphens")),
+		validation.Field("expiration", product.Expiration,
+			validation.Required[domain.Date](),
+			validation.When(publishing, notBefore(domain.DateOf(now)))),
+		validation.Field("price", product.Price, positive),
+		validation.Field("currency", product.Price.Currency, supportedCurrency),
+	)
+}
+
+// ValidateName checks a new name for a product.
+func ValidateName(name string) error {
+	return validation.Validate(validation.Field("name", name, nameRules...))
+}
+
+// notBefore rejects dates earlier than today.
+func notBefore(today domain.Date) validation.Rule[domain.Date] {
+	return func(date domain.Date) *validation.Violation {
+		if date.Before(today) {
+			return &validation.Violation{Code: "past", Message: "must not be in the past"}
+		}
+		return nil
+	}
+}
+
+// positive rejects amounts that are not greater than zero.
+func positive(price domain.Money) *validation.Violation {
+	if price.Amount <= 0 {
+		return &validation.Violation{Code: "positive", Message: "must be greater than 0"}
+	}
+	return nil
+}
+
+func supportedCurrency(currency domain.Currency) *validation.Violation {
+	if _, err := domain.ParseCurrency(string(currency)); err != nil || currency == "" {
+		return &validation.Violation{Code: "currency", Message: "must be a supported ISO 4217 currency code"}
+	}
+	return nil
+}
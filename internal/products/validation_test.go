@@ -0,0 +1,51 @@
+package products
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateProduct(t *testing.T) {
+	now := time.Date(2022, time.March, 15, 10, 0, 0, 0, time.UTC)
+	valid := domain.Product{Name: "Wine", Quantity: 0, CodeValue: "T65812", IsPublished: true, Expiration: domain.NewDate(2022, time.March, 15), Price: domain.NewMoney(17923, "ARS")}
+
+	t.Run("should accept a valid product", func(t *testing.T) {
+		assert.NoError(t, ValidateProduct(valid, nil, now))
+	})
+
+	t.Run("should list every violation", func(t *testing.T) {
+		product := domain.Product{Name: strings.Repeat("a", MaxNameLength+1), Quantity: -1, CodeValue: "t-1", Price: domain.NewMoney(-100, "ARS")}
+
+		assert.Equal(t, validation.Errors{
+			{Field: "name", Code: "max_length", Message: "must be at most 100 characters long"},
+			{Field: "quantity", Code: "min", Message: "must be at least 0"},
+			{Field: "code_value", Code: "pattern", Message: "must be uppercase letters and digits, optionally separated by hyphens"},
+			{Field: "expiration", Code: "required", Message: "is required"},
+			{Field: "price", Code: "positive", Message: "must be greater than 0"},
+		}, ValidateProduct(product, nil, now))
+	})
+
+	t.Run("should reject publishing an expired product", func(t *testing.T) {
+		expired := valid
+		expired.Expiration = domain.NewDate(2022, time.March, 14)
+		unpublished := expired
+		unpublished.IsPublished = false
+
+		expected := validation.Errors{{Field: "expiration", Code: "past", Message: "must not be in the past"}}
+		assert.Equal(t, expected, ValidateProduct(expired, nil, now))
+		assert.Equal(t, expected, ValidateProduct(expired, &unpublished, now), "publishing on update")
+		assert.Equal(t, expected, ValidateProduct(expired, &valid, now), "changing the expiration of a published product")
+		assert.NoError(t, ValidateProduct(unpublished, nil, now))
+		assert.NoError(t, ValidateProduct(expired, &expired, now), "editing a product that expired while published")
+	})
+}
+
+func TestValidateName(t *testing.T) {
+	assert.NoError(t, ValidateName("Wine"))
+	assert.Equal(t, validation.Errors{{Field: "name", Code: "required", Message: "is required"}}, ValidateName(""))
+}
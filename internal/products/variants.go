@@ -0,0 +1,185 @@
+package products
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+)
+
+const MaxAttributeLength = 50
+
+var (
+	ErrVariantNotFound    = errors.New("variant not found")
+	ErrNestedVariant      = errors.New("variants cannot have variants")
+	ErrProductHasVariants = errors.New("product has variants")
+)
+
+// Variants returns the variants of the product parentID.
+func (service DefaultService) Variants(parentID int) ([]domain.Product, error) {
+	if _, err := service.Storage.FindById(parentID); err != nil {
+		return []domain.Product{}, ErrProductNotFound
+	}
+	variants, err := service.Storage.Variants(parentID)
+	if err != nil {
+		return []domain.Product{}, ErrInternalServerError
+	}
+	return variants, nil
+}
+
+// CreateVariant creates variant as a variant of the product parentID. A
+// variant is a product of its own, with its code value, price, stock and
+// expiration, named after its parent and its attributes when it has no name.
+func (service DefaultService) CreateVariant(parentID int, variant *domain.Product) error {
+	parent, err := service.variantParent(parentID)
+	if err != nil {
+		return err
+	}
+	variant.ID = 0
+	variant.ParentID = parentID
+	if err := service.validateVariant(parent, variant); err != nil {
+		return err
+	}
+	if err := service.Storage.Create(variant); err != nil {
+		return err
+	}
+	service.written(OperationCreate, nil, *variant)
+	return nil
+}
+
+// UpdateVariant replaces a variant of the product parentID. A product that
+// is not a variant yet, nor has variants, becomes a variant of parentID,
+// which lets products created separately be grouped under a parent.
+func (service DefaultService) UpdateVariant(parentID int, variant *domain.Product) error {
+	parent, err := service.variantParent(parentID)
+	if err != nil {
+		return err
+	}
+	stored, err := service.Storage.FindById(variant.ID)
+	if err != nil {
+		return ErrVariantNotFound
+	}
+	if stored.ParentID != parentID {
+		if stored.ParentID != 0 || stored.ID == parentID {
+			return ErrVariantNotFound
+		}
+		variants, err := service.Storage.Variants(stored.ID)
+		if err != nil {
+			return ErrInternalServerError
+		}
+		if len(variants) > 0 {
+			return ErrNestedVariant
+		}
+	}
+	variant.ParentID = parentID
+	if err := service.validateVariant(parent, variant); err != nil {
+		return err
+	}
+	if err := service.Storage.Update(variant); err != nil {
+		return err
+	}
+	service.written(OperationUpdate, &stored, *variant)
+	return nil
+}
+
+// variantParent returns the product parentID, which must not be a variant.
+func (service DefaultService) variantParent(parentID int) (domain.Product, error) {
+	parent, err := service.Storage.FindById(parentID)
+	if err != nil {
+		return domain.Product{}, ErrProductNotFound
+	}
+	if parent.ParentID != 0 {
+		return domain.Product{}, ErrNestedVariant
+	}
+	return parent, nil
+}
+
+// validateVariant names variant after parent when it has no name, and then
+// checks its attributes, which must tell it apart from the other variants of
+// parent, and the rest of it like any product, so that its code value is
+// unique across products and variants alike.
+func (service DefaultService) validateVariant(parent domain.Product, variant *domain.Product) error {
+	for name, value := range variant.Attributes {
+		variant.Attributes[name] = strings.TrimSpace(value)
+	}
+	if err := validateAttributes(variant.Attributes); err != nil {
+		return err
+	}
+	if strings.TrimSpace(variant.Name) == "" {
+		variant.Name = variantName(parent, variant.Attributes)
+	}
+
+	siblings, err := service.Storage.Variants(parent.ID)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	for _, sibling := range siblings {
+		if sibling.ID != variant.ID && sameAttributes(sibling.Attributes, variant.Attributes) {
+			return validation.Errors{{Field: "attributes", Code: "unique", Message: "must differ from the attributes of the other variants"}}
+		}
+	}
+	return service.validations(variant)
+}
+
+// validateAttributes checks that a variant has some of the
+// domain.VariantAttributes and no other attribute.
+func validateAttributes(attributes map[string]string) error {
+	if len(attributes) == 0 {
+		return validation.Errors{{Field: "attributes", Code: "required", Message: "must have a " + strings.Join(domain.VariantAttributes, ", ") + " or more"}}
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations validation.Errors
+	for _, name := range names {
+		field := "attributes." + name
+		if !isVariantAttribute(name) {
+			violations = append(violations, validation.Violation{Field: field, Code: "enum", Message: "must be one of " + strings.Join(domain.VariantAttributes, ", ")})
+			continue
+		}
+		violations = append(violations, validation.Field(field, attributes[name], validation.Required[string](), validation.MaxLength(MaxAttributeLength))...)
+	}
+	return validation.Validate(violations)
+}
+
+func isVariantAttribute(name string) bool {
+	for _, attribute := range domain.VariantAttributes {
+		if attribute == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sameAttributes(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// variantName is the name of parent followed by the attributes of a variant
+// in the order of domain.VariantAttributes, e.g. Wine 750ml red.
+func variantName(parent domain.Product, attributes map[string]string) string {
+	parts := []string{parent.Name}
+	for _, name := range domain.VariantAttributes {
+		if value := attributes[name]; value != "" {
+			parts = append(parts, value)
+		}
+	}
+	name := []rune(strings.Join(parts, " "))
+	if len(name) > MaxNameLength {
+		name = name[:MaxNameLength]
+	}
+	return strings.TrimSpace(string(name))
+}
@@ -0,0 +1,227 @@
+// Package filter parses boolean filter expressions such as
+//
+//	price>=100 AND is_published=true AND name~"wine"
+//
+// into an AST checked against a schema, and evaluates them either in memory
+// or as a SQL WHERE clause.
+package filter
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type Type string
+
+const (
+	TypeNumber Type = "number"
+	TypeString Type = "text"
+	TypeBool   Type = "boolean"
+	TypeDate   Type = "date"
+)
+
+func (fieldType Type) ordered() bool {
+	return fieldType == TypeNumber || fieldType == TypeDate || fieldType == TypeString
+}
+
+// Schema maps the field names an expression may use to their type.
+type Schema map[string]Type
+
+func (schema Schema) fields() string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+type Operator string
+
+const (
+	Equal          Operator = "="
+	NotEqual       Operator = "!="
+	Less           Operator = "<"
+	LessOrEqual    Operator = "<="
+	Greater        Operator = ">"
+	GreaterOrEqual Operator = ">="
+	Contains       Operator = "~"
+)
+
+func (operator Operator) ordering() bool {
+	switch operator {
+	case Less, LessOrEqual, Greater, GreaterOrEqual:
+		return true
+	}
+	return false
+}
+
+const (
+	And = "AND"
+	Or  = "OR"
+)
+
+// Expression is a node of the AST: Logical, Not, Comparison or In.
+type Expression interface {
+	expression()
+}
+
+type Logical struct {
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+type Not struct {
+	Operand Expression
+}
+
+type Comparison struct {
+	Field    string
+	Operator Operator
+	Value    Value
+}
+
+type In struct {
+	Field  string
+	Values []Value
+}
+
+func (Logical) expression()    {}
+func (Not) expression()        {}
+func (Comparison) expression() {}
+func (In) expression()         {}
+
+// Value is a literal of the expression. Only the field matching Type is set.
+type Value struct {
+	Type   Type
+	Number float64
+	String string
+	Bool   bool
+	Time   time.Time
+}
+
+// Record gives the value of a field for in-memory evaluation: float64 for
+// numbers, string for text, bool for booleans and time.Time for dates.
+type Record func(field string) interface{}
+
+// Match evaluates expression against record.
+func Match(expression Expression, record Record) bool {
+	switch node := expression.(type) {
+	case Logical:
+		if node.Operator == And {
+			return Match(node.Left, record) && Match(node.Right, record)
+		}
+		return Match(node.Left, record) || Match(node.Right, record)
+	case Not:
+		return !Match(node.Operand, record)
+	case In:
+		actual := record(node.Field)
+		for _, value := range node.Values {
+			if compare(actual, value) == 0 {
+				return true
+			}
+		}
+		return false
+	case Comparison:
+		actual := record(node.Field)
+		if node.Operator == Contains {
+			text, _ := actual.(string)
+			return strings.Contains(strings.ToLower(text), strings.ToLower(node.Value.String))
+		}
+		result := compare(actual, node.Value)
+		switch node.Operator {
+		case Equal:
+			return result == 0
+		case NotEqual:
+			return result != 0
+		case Less:
+			return result < 0
+		case LessOrEqual:
+			return result <= 0
+		case Greater:
+			return result > 0
+		case GreaterOrEqual:
+			return result >= 0
+		}
+	}
+	return false
+}
+
+func compare(actual interface{}, value Value) int {
+	switch actual := actual.(type) {
+	case float64:
+		switch {
+		case actual < value.Number:
+			return -1
+		case actual > value.Number:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(actual, value.String)
+	case bool:
+		if actual == value.Bool {
+			return 0
+		}
+		if actual {
+			return 1
+		}
+		return -1
+	case time.Time:
+		return actual.Compare(value.Time)
+	}
+	return -1
+}
+
+// SQL translates expression into a WHERE condition with ? placeholders.
+// columns maps every field to the SQL expression that reads it; date columns
+// must produce YYYY-MM-DD text so they compare in date order. "~" compares
+// the text with lower(), which must lower the case of every letter like
+// strings.ToLower for the condition to match what Match does: the lower() of
+// SQLite, like its LIKE, only knows the ASCII letters.
+func SQL(expression Expression, columns map[string]string) (string, []interface{}) {
+	switch node := expression.(type) {
+	case Logical:
+		left, leftArgs := SQL(node.Left, columns)
+		right, rightArgs := SQL(node.Right, columns)
+		return "(" + left + " " + node.Operator + " " + right + ")", append(leftArgs, rightArgs...)
+	case Not:
+		operand, args := SQL(node.Operand, columns)
+		return "NOT " + operand, args
+	case In:
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(node.Values)), ", ")
+		args := make([]interface{}, len(node.Values))
+		for i, value := range node.Values {
+			args[i] = sqlValue(value)
+		}
+		return columns[node.Field] + " IN (" + placeholders + ")", args
+	case Comparison:
+		if node.Operator == Contains {
+			escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(node.Value.String))
+			return "lower(" + columns[node.Field] + `) LIKE ? ESCAPE '\'`, []interface{}{"%" + escaped + "%"}
+		}
+		operator := string(node.Operator)
+		if node.Operator == NotEqual {
+			operator = "<>"
+		}
+		return columns[node.Field] + " " + operator + " ?", []interface{}{sqlValue(node.Value)}
+	}
+	return "1 = 0", nil
+}
+
+func sqlValue(value Value) interface{} {
+	switch value.Type {
+	case TypeNumber:
+		return value.Number
+	case TypeBool:
+		if value.Bool {
+			return 1
+		}
+		return 0
+	case TypeDate:
+		return value.Time.Format(DateLayout)
+	}
+	return value.String
+}
@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var schema = Schema{
+	"name":         TypeString,
+	"price":        TypeNumber,
+	"quantity":     TypeNumber,
+	"code_value":   TypeString,
+	"is_published": TypeBool,
+	"expiration":   TypeDate,
+}
+
+func record(values map[string]interface{}) Record {
+	return func(field string) interface{} { return values[field] }
+}
+
+func TestParse(t *testing.T) {
+	wine := record(map[string]interface{}{
+		"name":         "Wine - Red Oakridge Merlot",
+		"price":        179.23,
+		"quantity":     367.0,
+		"code_value":   "T65812",
+		"is_published": true,
+		"expiration":   time.Date(2021, 5, 24, 0, 0, 0, 0, time.UTC),
+	})
+
+	t.Run("should match valid expressions", func(t *testing.T) {
+		cases := map[string]bool{
+			`price>=100 AND is_published=true AND name~"wine"`: true,
+			`expiration<2022-01-01`:                            true,
+			`expiration>"2022-01-01"`:                          false,
+			`quantity BETWEEN 300 AND 400`:                     true,
+			`code_value IN ("M4637", "T65812")`:                true,
+			`code_value in ("M4637")`:                          false,
+			`NOT (price < 100 OR quantity > 1000)`:             true,
+			`price > 500 OR name ~ "MERLOT"`:                   true,
+			`is_published != true`:                             false,
+			`price = -1`:                                       false,
+		}
+		for input, expected := range cases {
+			expression, err := Parse(input, schema)
+			require.NoError(t, err, input)
+			assert.Equal(t, expected, Match(expression, wine), input)
+		}
+	})
+
+	t.Run("should bind AND tighter than OR", func(t *testing.T) {
+		expression, err := Parse(`price > 500 OR price > 100 AND quantity < 10`, schema)
+		require.NoError(t, err)
+		assert.False(t, Match(expression, wine))
+	})
+
+	t.Run("should report invalid expressions with their position", func(t *testing.T) {
+		cases := map[string]string{
+			``:                          "at position 0: empty expression",
+			`color = "red"`:             `at position 0: unknown field "color", expected one of code_value, expiration, is_published, name, price, quantity`,
+			`price >= "cheap"`:          `at position 9: field "price" expects a number value, got "cheap"`,
+			`is_published > true`:       `at position 13: operator > cannot be used with boolean field "is_published"`,
+			`price ~ "1"`:               `at position 6: operator ~ needs a text field, "price" is number`,
+			`expiration < 2022-13-01`:   `at position 13: invalid date "2022-13-01", expected YYYY-MM-DD`,
+			`(price > 1`:                `at position 10: expected ")"`,
+			`name = "wine`:              "at position 7: unterminated string",
+			`price > 1 quantity`:        `at position 10: unexpected "quantity"`,
+			`code_value IN ("a" "b")`:   `at position 19: expected "," or ")"`,
+			`price BETWEEN 1 OR 2`:      "at position 16: expected AND in BETWEEN",
+			`price ! 1`:                 `at position 6: expected "!="`,
+			`expiration = "01/01/2022"`: `at position 13: invalid date "01/01/2022", expected YYYY-MM-DD`,
+		}
+		for input, expected := range cases {
+			_, err := Parse(input, schema)
+			assert.EqualError(t, err, expected, input)
+		}
+	})
+}
+
+func TestSQL(t *testing.T) {
+	expression, err := Parse(`price>=100 AND NOT is_published=false AND (name~"50%" OR code_value IN ("a", "b")) AND expiration<2022-01-01`, schema)
+	require.NoError(t, err)
+
+	where, args := SQL(expression, map[string]string{
+		"name":         "name",
+		"price":        "price",
+		"code_value":   "code_value",
+		"is_published": "is_published",
+		"expiration":   "expiration_iso",
+	})
+
+	assert.Equal(t, `(((price >= ? AND NOT is_published = ?) AND (lower(name) LIKE ? ESCAPE '\' OR code_value IN (?, ?))) AND expiration_iso < ?)`, where)
+	assert.Equal(t, []interface{}{100.0, 0, `%50\%%`, "a", "b", "2022-01-01"}, args)
+}
@@ -0,0 +1,335 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DateLayout is the layout of date literals, e.g. expiration<2022-01-01.
+const DateLayout = "2006-01-02"
+
+// Error reports an invalid expression with the position of the offending
+// token, counted in bytes from the start of the expression.
+type Error struct {
+	Position int
+	Message  string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("at position %d: %s", err.Position, err.Message)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenDate
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		char := rune(input[i])
+		start := i
+		switch {
+		case unicode.IsSpace(char):
+			i++
+			continue
+		case char == '(':
+			tokens = append(tokens, token{tokenOpen, "(", start})
+			i++
+		case char == ')':
+			tokens = append(tokens, token{tokenClose, ")", start})
+			i++
+		case char == ',':
+			tokens = append(tokens, token{tokenComma, ",", start})
+			i++
+		case strings.ContainsRune("=!<>~", char):
+			i++
+			if i < len(input) && input[i] == '=' && char != '=' && char != '~' {
+				i++
+			}
+			text := input[start:i]
+			if text == "!" {
+				return nil, &Error{start, `expected "!="`}
+			}
+			tokens = append(tokens, token{tokenOperator, text, start})
+		case char == '"':
+			var value strings.Builder
+			i++
+			for ; i < len(input) && input[i] != '"'; i++ {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				value.WriteByte(input[i])
+			}
+			if i >= len(input) {
+				return nil, &Error{start, "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{tokenString, value.String(), start})
+		case char == '-' || unicode.IsDigit(char):
+			i++
+			for i < len(input) && (unicode.IsDigit(rune(input[i])) || input[i] == '.' || input[i] == '-') {
+				i++
+			}
+			text := input[start:i]
+			if len(text) == len(DateLayout) && text[4] == '-' && text[7] == '-' {
+				if _, err := time.Parse(DateLayout, text); err != nil {
+					return nil, &Error{start, fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", text)}
+				}
+				tokens = append(tokens, token{tokenDate, text, start})
+				break
+			}
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, &Error{start, fmt.Sprintf("invalid number %q", text)}
+			}
+			tokens = append(tokens, token{tokenNumber, text, start})
+		case unicode.IsLetter(char) || char == '_':
+			for i < len(input) && (unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i])) || input[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, input[start:i], start})
+		default:
+			return nil, &Error{start, fmt.Sprintf("unexpected character %q", char)}
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+// Parse parses input and checks it against schema: fields must exist and
+// operators and values must suit the field type.
+//
+//	expression := or
+//	or         := and { "OR" and }
+//	and        := unary { "AND" unary }
+//	unary      := "NOT" unary | "(" expression ")" | predicate
+//	predicate  := field operator value
+//	            | field "IN" "(" value { "," value } ")"
+//	            | field "BETWEEN" value "AND" value
+//	operator   := "=" | "!=" | "<" | "<=" | ">" | ">=" | "~"
+//
+// Keywords are case insensitive; "~" matches a case insensitive substring.
+func Parse(input string, schema Schema) (Expression, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, &Error{0, "empty expression"}
+	}
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	parser := &parser{tokens: tokens, schema: schema}
+	expression, err := parser.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := parser.peek(); next.kind != tokenEOF {
+		return nil, &Error{next.position, fmt.Sprintf("unexpected %q", next.text)}
+	}
+	return expression, nil
+}
+
+type parser struct {
+	tokens []token
+	index  int
+	schema Schema
+}
+
+func (parser *parser) peek() token {
+	return parser.tokens[parser.index]
+}
+
+func (parser *parser) next() token {
+	current := parser.tokens[parser.index]
+	if current.kind != tokenEOF {
+		parser.index++
+	}
+	return current
+}
+
+func (parser *parser) keyword(word string) bool {
+	current := parser.peek()
+	if current.kind == tokenIdent && strings.EqualFold(current.text, word) {
+		parser.index++
+		return true
+	}
+	return false
+}
+
+func (parser *parser) or() (Expression, error) {
+	left, err := parser.and()
+	if err != nil {
+		return nil, err
+	}
+	for parser.keyword("OR") {
+		right, err := parser.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Operator: Or, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (parser *parser) and() (Expression, error) {
+	left, err := parser.unary()
+	if err != nil {
+		return nil, err
+	}
+	for parser.keyword("AND") {
+		right, err := parser.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Operator: And, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (parser *parser) unary() (Expression, error) {
+	if parser.keyword("NOT") {
+		operand, err := parser.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: operand}, nil
+	}
+	if parser.peek().kind == tokenOpen {
+		parser.next()
+		expression, err := parser.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := parser.next(); closing.kind != tokenClose {
+			return nil, &Error{closing.position, `expected ")"`}
+		}
+		return expression, nil
+	}
+	return parser.predicate()
+}
+
+func (parser *parser) predicate() (Expression, error) {
+	field := parser.next()
+	if field.kind != tokenIdent {
+		return nil, &Error{field.position, "expected a field name"}
+	}
+	fieldType, ok := parser.schema[strings.ToLower(field.text)]
+	if !ok {
+		return nil, &Error{field.position, fmt.Sprintf("unknown field %q, expected one of %s", field.text, parser.schema.fields())}
+	}
+	name := strings.ToLower(field.text)
+
+	switch {
+	case parser.keyword("IN"):
+		if open := parser.next(); open.kind != tokenOpen {
+			return nil, &Error{open.position, `expected "(" after IN`}
+		}
+		in := In{Field: name}
+		for {
+			value, err := parser.value(name, fieldType)
+			if err != nil {
+				return nil, err
+			}
+			in.Values = append(in.Values, value)
+			separator := parser.next()
+			if separator.kind == tokenClose {
+				return in, nil
+			}
+			if separator.kind != tokenComma {
+				return nil, &Error{separator.position, `expected "," or ")"`}
+			}
+		}
+	case parser.keyword("BETWEEN"):
+		if !fieldType.ordered() {
+			return nil, &Error{field.position, fmt.Sprintf("field %q cannot be used with BETWEEN", name)}
+		}
+		low, err := parser.value(name, fieldType)
+		if err != nil {
+			return nil, err
+		}
+		if !parser.keyword("AND") {
+			return nil, &Error{parser.peek().position, `expected AND in BETWEEN`}
+		}
+		high, err := parser.value(name, fieldType)
+		if err != nil {
+			return nil, err
+		}
+		return Logical{
+			Operator: And,
+			Left:     Comparison{Field: name, Operator: GreaterOrEqual, Value: low},
+			Right:    Comparison{Field: name, Operator: LessOrEqual, Value: high},
+		}, nil
+	}
+
+	operatorToken := parser.next()
+	if operatorToken.kind != tokenOperator {
+		return nil, &Error{operatorToken.position, "expected an operator"}
+	}
+	operator := Operator(operatorToken.text)
+	switch {
+	case operator == Contains && fieldType != TypeString:
+		return nil, &Error{operatorToken.position, fmt.Sprintf("operator ~ needs a text field, %q is %s", name, fieldType)}
+	case operator.ordering() && !fieldType.ordered():
+		return nil, &Error{operatorToken.position, fmt.Sprintf("operator %s cannot be used with %s field %q", operator, fieldType, name)}
+	}
+	value, err := parser.value(name, fieldType)
+	if err != nil {
+		return nil, err
+	}
+	return Comparison{Field: name, Operator: operator, Value: value}, nil
+}
+
+func (parser *parser) value(field string, fieldType Type) (Value, error) {
+	current := parser.next()
+	mismatch := &Error{current.position, fmt.Sprintf("field %q expects a %s value, got %q", field, fieldType, current.text)}
+	switch fieldType {
+	case TypeNumber:
+		if current.kind != tokenNumber {
+			return Value{}, mismatch
+		}
+		number, _ := strconv.ParseFloat(current.text, 64)
+		return Value{Type: TypeNumber, Number: number}, nil
+	case TypeString:
+		if current.kind != tokenString {
+			return Value{}, mismatch
+		}
+		return Value{Type: TypeString, String: current.text}, nil
+	case TypeBool:
+		if current.kind != tokenIdent {
+			return Value{}, mismatch
+		}
+		switch strings.ToLower(current.text) {
+		case "true":
+			return Value{Type: TypeBool, Bool: true}, nil
+		case "false":
+			return Value{Type: TypeBool, Bool: false}, nil
+		}
+		return Value{}, mismatch
+	case TypeDate:
+		if current.kind != tokenDate && current.kind != tokenString {
+			return Value{}, mismatch
+		}
+		date, err := time.Parse(DateLayout, current.text)
+		if err != nil {
+			return Value{}, &Error{current.position, fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", current.text)}
+		}
+		return Value{Type: TypeDate, Time: date}, nil
+	}
+	return Value{}, mismatch
+}
@@ -0,0 +1,87 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	// Test cases from RFC 7396, Appendix A.
+	cases := []struct{ document, patch, expected string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		merged, err := Merge([]byte(c.document), []byte(c.patch))
+		require.NoError(t, err, c.patch)
+		assert.JSONEq(t, c.expected, string(merged), c.patch)
+	}
+
+	_, err := Merge([]byte(`{}`), []byte(`{"a":`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestApply(t *testing.T) {
+	t.Run("should apply every operation", func(t *testing.T) {
+		// Examples from RFC 6902, Appendix A.
+		cases := []struct{ document, patch, expected string }{
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+			{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+			{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+			{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+			{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+			{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+			{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+			{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+			{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+			{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+			{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"copy","from":"/~1","path":"/a"}]`, `{"/":9,"~1":10,"a":9}`},
+			{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		}
+		for _, c := range cases {
+			applied, err := Apply([]byte(c.document), []byte(c.patch))
+			require.NoError(t, err, c.patch)
+			assert.JSONEq(t, c.expected, string(applied), c.patch)
+		}
+	})
+
+	t.Run("should fail a test operation that does not match", func(t *testing.T) {
+		_, err := Apply([]byte(`{"baz":"qux"}`), []byte(`[{"op":"test","path":"/baz","value":"bar"}]`))
+		assert.ErrorIs(t, err, ErrTestFailed)
+		assert.EqualError(t, err, "patch test failed: operation 0")
+	})
+
+	t.Run("should reject invalid operations", func(t *testing.T) {
+		cases := map[string]string{
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`:     "invalid patch: operation 0: path /baz not found",
+			`[{"op":"remove","path":"/missing"}]`:                "invalid patch: operation 0: path /missing not found",
+			`[{"op":"replace","path":"/foo"}]`:                   "invalid patch: operation 0: missing value",
+			`[{"op":"add","path":"/foo/01","value":1}]`:          "invalid patch: operation 0: invalid array index \"01\"",
+			`[{"op":"move","from":"/foo","path":"/foo/0"}]`:      "invalid patch: operation 0: cannot move a value into itself",
+			`[{"op":"test","path":"/foo"},{"op":"nope"}]`:        "invalid patch: operation 0: missing value",
+			`[{"op":"add","path":"/a","value":1},{"op":"nope"}]`: "invalid patch: operation 1: unknown operation \"nope\"",
+			`{"op":"add"}`: "invalid patch: json: cannot unmarshal object into Go value of type []patch.Operation",
+		}
+		for operations, message := range cases {
+			_, err := Apply([]byte(`{"foo":["bar"]}`), []byte(operations))
+			assert.ErrorIs(t, err, ErrInvalidPatch, operations)
+			assert.EqualError(t, err, message, operations)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrMalformedBody = errors.New("malformed request body")
+
+func init() {
+	// Report binding violations with the JSON names clients send.
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// BindingError turns the error of binding a JSON body into validation.Errors
+// listing the invalid fields, or ErrMalformedBody when the body is not JSON.
+func BindingError(err error) error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		violations := make(validation.Errors, len(validationErrors))
+		for i, fieldError := range validationErrors {
+			violations[i] = Violation{Field: fieldError.Field(), Code: fieldError.Tag(), Message: ruleMessage(fieldError)}
+		}
+		return violations
+	}
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return InvalidField(typeError.Field, "type", "must be "+jsonType(typeError.Type))
+	}
+	return fmt.Errorf("%w: %s", ErrMalformedBody, err)
+}
+
+func ruleMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	default:
+		return "must satisfy " + fieldError.Tag()
+	}
+}
+
+func jsonType(goType reflect.Type) string {
+	switch goType.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,34 @@
+package rest
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Link formats an RFC 8288 Link header from relation names to URLs, in the
+// given order of relations.
+func Link(relations []string, links map[string]*url.URL) string {
+	var parts []string
+	for _, relation := range relations {
+		if link, ok := links[relation]; ok {
+			parts = append(parts, "<"+link.String()+`>; rel="`+relation+`"`)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WithQuery returns a copy of base with the given query parameters replaced;
+// empty values remove the parameter.
+func WithQuery(base *url.URL, params map[string]string) *url.URL {
+	link := *base
+	query := link.Query()
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+			continue
+		}
+		query.Set(key, value)
+	}
+	link.RawQuery = query.Encode()
+	return &link
+}
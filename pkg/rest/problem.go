@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Andrea-Reyna/go-web/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase prefixes the code of a problem to build its type URI.
+const ProblemTypeBase = "/problems/"
+
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable name of the problem and Errors lists the violations of
+// each invalid request field.
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code"`
+	Errors   []Violation `json:"errors,omitempty"`
+}
+
+// Violation is a problem with one field of a request.
+type Violation = validation.Violation
+
+// InvalidField returns the validation error of a single field.
+func InvalidField(field, code, message string) error {
+	return validation.Errors{{Field: field, Code: code, Message: message}}
+}
+
+// ProblemType describes the problem an error maps to. Field names the request
+// field the error is about, if any, and Detail replaces the error message
+// when it is not meant for clients.
+type ProblemType struct {
+	Status int
+	Code   string
+	Title  string
+	Field  string
+	Detail string
+}
+
+var (
+	validationProblem = ProblemType{Status: http.StatusBadRequest, Code: "validation_failed", Title: "Validation Failed"}
+	internalProblem   = ProblemType{Status: http.StatusInternalServerError, Code: "internal_error", Detail: "an internal error has occurred"}
+)
+
+type problemMapping struct {
+	err         error
+	problemType ProblemType
+}
+
+// Problems maps errors to problem types. validation.Errors are always
+// validation_failed problems, other errors are matched with errors.Is in
+// registration order and unknown errors are internal_error problems.
+type Problems struct {
+	mappings []problemMapping
+}
+
+func NewProblems() *Problems {
+	return (&Problems{}).Register(ErrMalformedBody, ProblemType{Status: http.StatusBadRequest, Code: "malformed_body"})
+}
+
+func (problems *Problems) Register(err error, problemType ProblemType) *Problems {
+	problems.mappings = append(problems.mappings, problemMapping{err: err, problemType: problemType})
+	return problems
+}
+
+// Problem describes err as a problem that occurred at instance, the URI of
+// the request.
+func (problems *Problems) Problem(err error, instance string) Problem {
+	var violations validation.Errors
+	if errors.As(err, &violations) {
+		problem := validationProblem.problem(err, instance)
+		problem.Errors = violations
+		return problem
+	}
+	for _, mapping := range problems.mappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.problemType.problem(err, instance)
+		}
+	}
+	return internalProblem.problem(err, instance)
+}
+
+func (problemType ProblemType) problem(err error, instance string) Problem {
+	problem := Problem{
+		Type:     ProblemTypeBase + problemType.Code,
+		Title:    problemType.Title,
+		Status:   problemType.Status,
+		Detail:   problemType.Detail,
+		Instance: instance,
+		Code:     problemType.Code,
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Detail == "" {
+		problem.Detail = err.Error()
+	}
+	if problemType.Field != "" {
+		problem.Errors = []Violation{{Field: problemType.Field, Code: problemType.Code, Message: problem.Detail}}
+	}
+	return problem
+}
+
+// Abort responds to the request with the problem err maps to and stops the
+// handler chain. The error is kept in ctx.Errors for the logger.
+func (problems *Problems) Abort(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	problem := problems.Problem(err, ctx.Request.URL.RequestURI())
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
@@ -2,10 +2,14 @@ package rest
 
 type SuccessfulResponse struct {
 	Data interface{} `json:"data"`
+	*Pagination
 }
 
-type ErrorResponse struct {
-	Status  int    `json:"status"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+// Pagination describes the position of a page inside a collection. It is
+// flattened into SuccessfulResponse for paged endpoints.
+type Pagination struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
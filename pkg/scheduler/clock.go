@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time to a Scheduler and wakes it up when jobs are due.
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the channel once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// ManualClock only moves when it is told to, so that tests can fast-forward
+// the time to the runs of the jobs.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+type waiter struct {
+	at      time.Time
+	channel chan time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (clock *ManualClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *ManualClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	channel := make(chan time.Time, 1)
+	at := clock.now.Add(d)
+	if d <= 0 {
+		channel <- clock.now
+		return channel
+	}
+	clock.waiters = append(clock.waiters, waiter{at: at, channel: channel})
+	return channel
+}
+
+// Advance moves the clock d forward and wakes up the waiters due by then.
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+	pending := clock.waiters[:0]
+	for _, waiter := range clock.waiters {
+		if waiter.at.After(clock.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+		waiter.channel <- clock.now
+	}
+	clock.waiters = pending
+}
+
+// Waiters is the number of calls to After still waiting, which lets tests
+// wait for the scheduler to be waiting before they advance the clock.
+func (clock *ManualClock) Waiters() int {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return len(clock.waiters)
+}
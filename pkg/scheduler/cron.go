@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next returns the first time after t the job runs at.
+	Next(t time.Time) time.Time
+}
+
+// macros are the shorthands of common cron expressions.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse reads a cron expression of five fields, minute, hour, day of month,
+// month and day of week, e.g. "*/15 8-18 * * 1-5". Fields are *, numbers,
+// ranges and lists of them, each with an optional /step; Sunday is 0 or 7.
+// The macros @hourly, @daily, @weekly, @monthly and @yearly are accepted, and
+// "@every <duration>" runs at a fixed interval, e.g. "@every 90s".
+func Parse(expression string) (Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if value, ok := strings.CutPrefix(expression, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("%w: %q is not a positive duration", ErrInvalidSchedule, value)
+		}
+		return every(interval), nil
+	}
+	if macro, ok := macros[expression]; ok {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q must have 5 fields: minute, hour, day of month, month and day of week", ErrInvalidSchedule, expression)
+	}
+	var schedule cron
+	var err error
+	if schedule.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("%w: minute %v", ErrInvalidSchedule, err)
+	}
+	if schedule.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("%w: hour %v", ErrInvalidSchedule, err)
+	}
+	if schedule.days, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("%w: day of month %v", ErrInvalidSchedule, err)
+	}
+	if schedule.months, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("%w: month %v", ErrInvalidSchedule, err)
+	}
+	if schedule.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("%w: day of week %v", ErrInvalidSchedule, err)
+	}
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+	return schedule, nil
+}
+
+// parseField returns the values of a field between min and max as a bit set.
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		valueRange, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("%q has an invalid step", part)
+			}
+		}
+		low, high := min, max
+		if valueRange != "*" {
+			lowText, highText, isRange := strings.Cut(valueRange, "-")
+			var err error
+			if low, err = strconv.Atoi(lowText); err != nil {
+				return 0, fmt.Errorf("%q is not a number", part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highText); err != nil {
+					return 0, fmt.Errorf("%q is not a range", part)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of %d-%d", part, min, max)
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// cron is a parsed cron expression, each field a bit set of its values.
+type cron struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday tell the fields that were *: when both days
+	// are restricted, either matches, as in cron.
+	anyDay, anyWeekday bool
+}
+
+// maxSearch bounds the search of Next, for expressions that never match,
+// e.g. February 30.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Next walks from t one field at a time, skipping whole months, days and
+// hours that do not match. It returns the zero time when nothing matches
+// in the next five years.
+func (schedule cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+	for t.Before(limit) {
+		if schedule.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !schedule.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (schedule cron) dayMatches(t time.Time) bool {
+	day := schedule.days&(1<<uint(t.Day())) != 0
+	weekday := schedule.weekdays&(1<<uint(t.Weekday())) != 0
+	if !schedule.anyDay && !schedule.anyWeekday {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// every runs at a fixed interval from the time it is asked about.
+type every time.Duration
+
+func (interval every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(interval))
+}
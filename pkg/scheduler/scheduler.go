@@ -0,0 +1,262 @@
+// Package scheduler runs jobs in the background on cron-like schedules. A
+// job never runs twice at the same time, and the scheduler stops by waiting
+// for the runs in progress.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobRunning       = errors.New("job is already running")
+	ErrJobAlreadyExists = errors.New("job already exists")
+	ErrNotRunning       = errors.New("scheduler is not running")
+)
+
+// Job is a task run on a schedule. Run gets a context canceled when the
+// scheduler gives up waiting for it on Stop, and returns a result, kept as
+// the result of the last run.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Expression is the expression the schedule was parsed from, reported
+	// in the status of the job.
+	Expression string
+	// Jitter delays every scheduled run by a random duration up to Jitter,
+	// so that instances of the server do not all run the job at once.
+	Jitter time.Duration
+	Run    func(ctx context.Context) (any, error)
+}
+
+// Run is a run of a job.
+type Run struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Result     any       `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is the state of a job: whether it is running, when it runs next and
+// how its last run went. Skipped counts the scheduled runs skipped because
+// the job was still running.
+type Status struct {
+	Name     string    `json:"name" example:"unpublish-expired"`
+	Schedule string    `json:"schedule" example:"0 * * * *"`
+	Running  bool      `json:"running"`
+	NextRun  time.Time `json:"next_run"`
+	Runs     int       `json:"runs"`
+	Skipped  int       `json:"skipped"`
+	LastRun  *Run      `json:"last_run,omitempty"`
+}
+
+type job struct {
+	Job
+	status Status
+}
+
+type Scheduler struct {
+	// Clock is the time of the scheduler, SystemClock when nil.
+	Clock Clock
+	// Random returns a random duration in [0, n) for the jitter,
+	// rand.Int63n when nil.
+	Random func(n int64) int64
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	started bool
+	stopped bool
+	// stopping is closed by Stop, and ctx is the context of the runs.
+	stopping chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// loops are the goroutines waiting for the next runs and runs the runs
+	// in progress, which Stop waits for.
+	loops sync.WaitGroup
+	runs  sync.WaitGroup
+}
+
+func (scheduler *Scheduler) clock() Clock {
+	if scheduler.Clock == nil {
+		return SystemClock{}
+	}
+	return scheduler.Clock
+}
+
+// Add adds a job, which runs on its schedule once the scheduler starts.
+func (scheduler *Scheduler) Add(added Job) error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.jobs == nil {
+		scheduler.jobs = map[string]*job{}
+	}
+	if _, ok := scheduler.jobs[added.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobAlreadyExists, added.Name)
+	}
+	entry := &job{Job: added, status: Status{Name: added.Name, Schedule: added.Expression}}
+	scheduler.jobs[added.Name] = entry
+	if scheduler.started && !scheduler.stopped {
+		scheduler.loops.Add(1)
+		go scheduler.loop(entry)
+	}
+	return nil
+}
+
+// Start runs every job on its schedule until Stop is called.
+func (scheduler *Scheduler) Start() {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.started {
+		return
+	}
+	scheduler.started = true
+	scheduler.stopping = make(chan struct{})
+	scheduler.ctx, scheduler.cancel = context.WithCancel(context.Background())
+	for _, entry := range scheduler.jobs {
+		scheduler.loops.Add(1)
+		go scheduler.loop(entry)
+	}
+}
+
+// loop waits for the next run of a job until the scheduler stops.
+func (scheduler *Scheduler) loop(entry *job) {
+	defer scheduler.loops.Done()
+	clock := scheduler.clock()
+	for {
+		now := clock.Now()
+		next := entry.Schedule.Next(now)
+		if next.IsZero() {
+			return
+		}
+		if entry.Jitter > 0 {
+			random := scheduler.Random
+			if random == nil {
+				random = rand.Int63n
+			}
+			next = next.Add(time.Duration(random(int64(entry.Jitter))))
+		}
+		scheduler.mu.Lock()
+		entry.status.NextRun = next
+		scheduler.mu.Unlock()
+
+		select {
+		case <-scheduler.stopping:
+			return
+		case <-clock.After(next.Sub(now)):
+		}
+		if _, err := scheduler.start(entry); errors.Is(err, ErrJobRunning) {
+			scheduler.mu.Lock()
+			entry.status.Skipped++
+			scheduler.mu.Unlock()
+		}
+	}
+}
+
+// RunNow runs a job right away and waits for the run to finish, unless the
+// job is already running.
+func (scheduler *Scheduler) RunNow(name string) (Run, error) {
+	scheduler.mu.Lock()
+	entry, ok := scheduler.jobs[name]
+	scheduler.mu.Unlock()
+	if !ok {
+		return Run{}, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	done, err := scheduler.start(entry)
+	if err != nil {
+		return Run{}, err
+	}
+	return <-done, nil
+}
+
+// start runs a job in the background and returns a channel that receives
+// the run once it finishes.
+func (scheduler *Scheduler) start(entry *job) (<-chan Run, error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.stopped || !scheduler.started {
+		return nil, ErrNotRunning
+	}
+	if entry.status.Running {
+		return nil, fmt.Errorf("%w: %s", ErrJobRunning, entry.Name)
+	}
+	entry.status.Running = true
+	scheduler.runs.Add(1)
+
+	done := make(chan Run, 1)
+	go func() {
+		defer scheduler.runs.Done()
+		run := Run{StartedAt: scheduler.clock().Now()}
+		result, err := scheduler.run(entry)
+		run.FinishedAt = scheduler.clock().Now()
+		run.Result = result
+		if err != nil {
+			run.Error = err.Error()
+		}
+
+		scheduler.mu.Lock()
+		entry.status.Running = false
+		entry.status.Runs++
+		entry.status.LastRun = &run
+		scheduler.mu.Unlock()
+		done <- run
+	}()
+	return done, nil
+}
+
+// run runs a job, reporting a panic as the error of the run.
+func (scheduler *Scheduler) run(entry *job) (result any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return entry.Run(scheduler.ctx)
+}
+
+// Jobs returns the status of every job, sorted by name.
+func (scheduler *Scheduler) Jobs() []Status {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	statuses := make([]Status, 0, len(scheduler.jobs))
+	for _, entry := range scheduler.jobs {
+		statuses = append(statuses, entry.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Stop stops scheduling runs and waits for the runs in progress to finish.
+// When ctx is done first, Stop cancels the context of the runs and returns
+// the error of ctx.
+func (scheduler *Scheduler) Stop(ctx context.Context) error {
+	scheduler.mu.Lock()
+	if !scheduler.started || scheduler.stopped {
+		scheduler.stopped = true
+		scheduler.mu.Unlock()
+		return nil
+	}
+	scheduler.stopped = true
+	close(scheduler.stopping)
+	scheduler.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.loops.Wait()
+		scheduler.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		scheduler.cancel()
+		return nil
+	case <-ctx.Done():
+		scheduler.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expression string
+		from       time.Time
+		next       time.Time
+	}{
+		{"*/15 * * * *", testNow, testNow.Add(15 * time.Minute)},
+		{"@hourly", testNow.Add(time.Second), testNow.Add(time.Hour)},
+		{"30 8-18 * * 1-5", testNow, time.Date(2022, time.January, 3, 8, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", testNow, time.Date(2022, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", testNow, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// With both days restricted, either matches: the 13th or a Friday.
+		{"0 0 13 * 5", testNow, time.Date(2022, time.January, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", testNow, time.Date(2022, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", testNow, testNow.Add(90 * time.Second)},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			schedule, err := Parse(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.next, schedule.Next(test.from))
+		})
+	}
+
+	t.Run("should reject invalid expressions", func(t *testing.T) {
+		for _, expression := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every -1m", "@sometimes"} {
+			_, err := Parse(expression)
+			assert.ErrorIs(t, err, ErrInvalidSchedule, expression)
+		}
+	})
+
+	t.Run("should not find a run on a day that does not exist", func(t *testing.T) {
+		schedule, err := Parse("0 0 30 2 *")
+		require.NoError(t, err)
+		assert.True(t, schedule.Next(testNow).IsZero())
+	})
+}
+
+// newTestScheduler returns a started scheduler on a manual clock, stopped at
+// the end of the test.
+func newTestScheduler(t *testing.T, jobs ...Job) (*Scheduler, *ManualClock) {
+	clock := NewManualClock(testNow)
+	scheduler := &Scheduler{Clock: clock, Random: func(n int64) int64 { return n / 2 }}
+	for _, job := range jobs {
+		require.NoError(t, scheduler.Add(job))
+	}
+	scheduler.Start()
+	t.Cleanup(func() { scheduler.Stop(context.Background()) })
+	return scheduler, clock
+}
+
+// advance waits for the jobs to wait for their next run and moves the clock.
+func advance(t *testing.T, clock *ManualClock, waiters int, d time.Duration) {
+	require.Eventually(t, func() bool { return clock.Waiters() == waiters }, time.Second, time.Millisecond)
+	clock.Advance(d)
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("should run a job on its schedule with its jitter", func(t *testing.T) {
+		var runs atomic.Int32
+		schedule, err := Parse("@every 1m")
+		require.NoError(t, err)
+		scheduler, clock := newTestScheduler(t, Job{Name: "count", Schedule: schedule, Expression: "@every 1m", Jitter: 10 * time.Second, Run: func(ctx context.Context) (any, error) {
+			return int(runs.Add(1)), nil
+		}})
+
+		require.Eventually(t, func() bool { return !scheduler.Jobs()[0].NextRun.IsZero() }, time.Second, time.Millisecond)
+		assert.Equal(t, testNow.Add(65*time.Second), scheduler.Jobs()[0].NextRun)
+		advance(t, clock, 1, time.Minute)
+		assert.Equal(t, int32(0), runs.Load())
+		clock.Advance(5 * time.Second)
+		require.Eventually(t, func() bool { return scheduler.Jobs()[0].Runs == 1 }, time.Second, time.Millisecond)
+
+		status := scheduler.Jobs()[0]
+		assert.Equal(t, "count", status.Name)
+		assert.Equal(t, "@every 1m", status.Schedule)
+		require.NotNil(t, status.LastRun)
+		assert.Equal(t, 1, status.LastRun.Result)
+		assert.Equal(t, testNow.Add(65*time.Second), status.LastRun.StartedAt)
+	})
+
+	t.Run("should not run a job twice at the same time", func(t *testing.T) {
+		release := make(chan struct{})
+		schedule, err := Parse("@every 1m")
+		require.NoError(t, err)
+		scheduler, clock := newTestScheduler(t, Job{Name: "slow", Schedule: schedule, Run: func(ctx context.Context) (any, error) {
+			<-release
+			return nil, errors.New("failed")
+		}})
+
+		advance(t, clock, 1, time.Minute)
+		require.Eventually(t, func() bool { return scheduler.Jobs()[0].Running }, time.Second, time.Millisecond)
+		_, err = scheduler.RunNow("slow")
+		assert.ErrorIs(t, err, ErrJobRunning)
+		advance(t, clock, 1, time.Minute)
+		require.Eventually(t, func() bool { return scheduler.Jobs()[0].Skipped == 1 }, time.Second, time.Millisecond)
+
+		close(release)
+		require.Eventually(t, func() bool { return scheduler.Jobs()[0].Runs == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, "failed", scheduler.Jobs()[0].LastRun.Error)
+		_, err = scheduler.RunNow("unknown")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+
+	t.Run("should wait for the runs in progress when it stops", func(t *testing.T) {
+		started := make(chan struct{})
+		var finished atomic.Bool
+		scheduler, _ := newTestScheduler(t, Job{Name: "slow", Schedule: every(time.Hour), Run: func(ctx context.Context) (any, error) {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+			finished.Store(true)
+			return nil, nil
+		}})
+		go scheduler.RunNow("slow")
+		<-started
+
+		require.NoError(t, scheduler.Stop(context.Background()))
+		assert.True(t, finished.Load())
+		_, err := scheduler.RunNow("slow")
+		assert.ErrorIs(t, err, ErrNotRunning)
+	})
+
+	t.Run("should cancel the runs that outlast the stop", func(t *testing.T) {
+		started := make(chan struct{})
+		scheduler, _ := newTestScheduler(t, Job{Name: "stuck", Schedule: every(time.Hour), Run: func(ctx context.Context) (any, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}})
+		go scheduler.RunNow("stuck")
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, scheduler.Stop(ctx), context.DeadlineExceeded)
+		require.Eventually(t, func() bool { return scheduler.Jobs()[0].Runs == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, context.Canceled.Error(), scheduler.Jobs()[0].LastRun.Error)
+	})
+}
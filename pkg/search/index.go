@@ -0,0 +1,233 @@
+// Package search implements an in-memory inverted index over short texts
+// such as product names, ranked with BM25.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Weights of the ways a query term can match an indexed term.
+const (
+	exactWeight  = 1.0
+	prefixWeight = 0.8
+	typoWeight   = 0.6
+)
+
+type Result struct {
+	ID    int
+	Score float64
+}
+
+// Index maps terms to the documents containing them. It is safe for
+// concurrent use.
+type Index struct {
+	mu          sync.RWMutex
+	postings    map[string]map[int]int
+	documents   map[int][]string
+	totalLength int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings:  map[string]map[int]int{},
+		documents: map[int][]string{},
+	}
+}
+
+// Put indexes text as the content of document id, replacing the previous
+// content if any.
+func (index *Index) Put(id int, text string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	index.remove(id)
+	terms := Tokenize(text)
+	index.documents[id] = terms
+	index.totalLength += len(terms)
+	for _, term := range terms {
+		if index.postings[term] == nil {
+			index.postings[term] = map[int]int{}
+		}
+		index.postings[term][id]++
+	}
+}
+
+func (index *Index) Remove(id int) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.remove(id)
+}
+
+func (index *Index) remove(id int) {
+	terms, ok := index.documents[id]
+	if !ok {
+		return
+	}
+	for _, term := range terms {
+		delete(index.postings[term], id)
+		if len(index.postings[term]) == 0 {
+			delete(index.postings, term)
+		}
+	}
+	index.totalLength -= len(terms)
+	delete(index.documents, id)
+}
+
+// Search returns up to limit documents matching any term of query, best
+// first. Query terms also match indexed terms they are a prefix of and, from
+// four characters on, terms within one or two typos.
+func (index *Index) Search(query string, limit int) []Result {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	if len(index.documents) == 0 {
+		return []Result{}
+	}
+	averageLength := float64(index.totalLength) / float64(len(index.documents))
+
+	scores := map[int]float64{}
+	for _, queryTerm := range unique(Tokenize(query)) {
+		// Each document scores the best way it matches this query term.
+		best := map[int]float64{}
+		for term, weight := range index.expand(queryTerm) {
+			documents := index.postings[term]
+			idf := math.Log(1 + (float64(len(index.documents))-float64(len(documents))+0.5)/(float64(len(documents))+0.5))
+			for id, frequency := range documents {
+				length := float64(len(index.documents[id]))
+				tf := float64(frequency) * (k1 + 1) / (float64(frequency) + k1*(1-b+b*length/averageLength))
+				if score := weight * idf * tf; score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: math.Round(score*1e4) / 1e4})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// expand returns the indexed terms matching queryTerm with their weight.
+func (index *Index) expand(queryTerm string) map[string]float64 {
+	matches := map[string]float64{}
+	maxTypos := 0
+	switch length := len([]rune(queryTerm)); {
+	case length >= 8:
+		maxTypos = 2
+	case length >= 4:
+		maxTypos = 1
+	}
+	for term := range index.postings {
+		switch {
+		case term == queryTerm:
+			matches[term] = exactWeight
+		case strings.HasPrefix(term, queryTerm):
+			matches[term] = prefixWeight
+		case maxTypos > 0 && withinDistance(queryTerm, term, maxTypos):
+			matches[term] = typoWeight
+		}
+	}
+	return matches
+}
+
+var folder = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// Tokenize lowercases text, folds accents (so "Limón" and "limon" are the
+// same term) and splits it on everything but letters and digits.
+func Tokenize(text string) []string {
+	folded, _, err := transform.String(folder, strings.ToLower(text))
+	if err != nil {
+		folded = strings.ToLower(text)
+	}
+	return strings.FieldsFunc(folded, func(char rune) bool {
+		return !unicode.IsLetter(char) && !unicode.IsDigit(char)
+	})
+}
+
+func unique(terms []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// withinDistance reports whether the Damerau-Levenshtein (optimal string
+// alignment) distance between a and b is at most max.
+func withinDistance(a, b string, max int) bool {
+	source, target := []rune(a), []rune(b)
+	if diff := len(source) - len(target); diff > max || -diff > max {
+		return false
+	}
+
+	previous2 := make([]int, len(target)+1)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && source[i-1] == target[j-2] && source[i-2] == target[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+			if current[j] < rowMin {
+				rowMin = current[j]
+			}
+		}
+		if rowMin > max {
+			return false
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(target)] <= max
+}
+
+func min(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
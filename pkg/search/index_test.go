@@ -0,0 +1,64 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(results []Result) []int {
+	found := []int{}
+	for _, result := range results {
+		found = append(found, result.ID)
+	}
+	return found
+}
+
+func TestIndex(t *testing.T) {
+	index := NewIndex()
+	index.Put(3, "Wine - Red Oakridge Merlot")
+	index.Put(4, "Cookie - Oatmeal")
+	index.Put(7, "Wine - White, Colubia Cresh")
+	index.Put(9, "Limón - Jugo de limón")
+	index.Put(12, "Cookies - Chocolate Chip")
+
+	t.Run("should rank the best match first", func(t *testing.T) {
+		results := index.Search("red wine", 0)
+		assert.Equal(t, []int{3, 7}, ids(results))
+		assert.Greater(t, results[0].Score, results[1].Score)
+	})
+
+	t.Run("should fold case and accents", func(t *testing.T) {
+		assert.Equal(t, []int{9}, ids(index.Search("LIMON", 0)))
+		assert.Equal(t, []int{9}, ids(index.Search("jugo", 0)))
+	})
+
+	t.Run("should match prefixes", func(t *testing.T) {
+		assert.Equal(t, []int{4, 12}, ids(index.Search("cook", 0)))
+		assert.Equal(t, []int{3}, ids(index.Search("oak", 0)))
+	})
+
+	t.Run("should tolerate typos", func(t *testing.T) {
+		assert.Equal(t, []int{3}, ids(index.Search("merlto", 0)))
+		assert.Equal(t, []int{4}, ids(index.Search("oatmaal", 0)))
+		assert.Equal(t, []int{12}, ids(index.Search("chocolat chpi", 0)))
+		assert.Empty(t, ids(index.Search("zzz", 0)))
+	})
+
+	t.Run("should follow updates and deletes", func(t *testing.T) {
+		index.Put(4, "Biscuit - Oatmeal")
+		index.Remove(12)
+
+		assert.Empty(t, ids(index.Search("cookie", 0)))
+		assert.Equal(t, []int{4}, ids(index.Search("biscuit", 0)))
+		assert.Len(t, index.Search("wine", 1), 1)
+	})
+}
+
+func TestWithinDistance(t *testing.T) {
+	assert.True(t, withinDistance("merlot", "merlto", 1))
+	assert.True(t, withinDistance("oatmeal", "oatmaal", 1))
+	assert.False(t, withinDistance("oatmeal", "oatmaaal", 1))
+	assert.True(t, withinDistance("chocolate", "chocolat", 2))
+	assert.False(t, withinDistance("wine", "white", 1))
+}
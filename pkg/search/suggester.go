@@ -0,0 +1,199 @@
+package search
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type entry struct {
+	key string
+	id  int
+}
+
+// bucketLength is the number of leading characters that select the bucket
+// of a key.
+const bucketLength = 2
+
+// Suggester finds documents by key prefix. Keys live in sorted slices, one
+// per two leading characters so writes only shift a fraction of the keys,
+// and a lookup is a binary search for the first key with the prefix followed
+// by a scan of the matching range. It is safe for concurrent use.
+type Suggester struct {
+	mu      sync.RWMutex
+	buckets map[string][]entry
+	keys    map[int][]string
+}
+
+func NewSuggester() *Suggester {
+	return &Suggester{
+		buckets: map[string][]entry{},
+		keys:    map[int][]string{},
+	}
+}
+
+func bucketOf(key string) string {
+	count := 0
+	for i := range key {
+		if count == bucketLength {
+			return key[:i]
+		}
+		count++
+	}
+	return key
+}
+
+// Load replaces the content of the suggester with documents, sorting once
+// instead of inserting key by key.
+func (suggester *Suggester) Load(documents map[int][]string) {
+	suggester.mu.Lock()
+	defer suggester.mu.Unlock()
+
+	suggester.buckets = map[string][]entry{}
+	suggester.keys = map[int][]string{}
+	for id, keys := range documents {
+		keys = unique(keys)
+		for _, key := range keys {
+			bucket := bucketOf(key)
+			suggester.buckets[bucket] = append(suggester.buckets[bucket], entry{key: key, id: id})
+		}
+		suggester.keys[id] = keys
+	}
+	for _, entries := range suggester.buckets {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key || (entries[i].key == entries[j].key && entries[i].id < entries[j].id)
+		})
+	}
+}
+
+// SuggestionKeys returns the keys a text can be suggested by: the whole text
+// and every word of it, folded like Tokenize.
+func SuggestionKeys(text string) []string {
+	terms := Tokenize(text)
+	keys := []string{strings.Join(terms, " ")}
+	if len(terms) > 1 {
+		keys = append(keys, terms...)
+	}
+	return unique(keys)
+}
+
+// Put replaces the keys of document id.
+func (suggester *Suggester) Put(id int, keys []string) {
+	suggester.mu.Lock()
+	defer suggester.mu.Unlock()
+
+	suggester.remove(id)
+	keys = unique(keys)
+	for _, key := range keys {
+		bucket := bucketOf(key)
+		entries := suggester.buckets[bucket]
+		position := search(entries, key, id)
+		entries = append(entries, entry{})
+		copy(entries[position+1:], entries[position:])
+		entries[position] = entry{key: key, id: id}
+		suggester.buckets[bucket] = entries
+	}
+	suggester.keys[id] = keys
+}
+
+func (suggester *Suggester) Remove(id int) {
+	suggester.mu.Lock()
+	defer suggester.mu.Unlock()
+	suggester.remove(id)
+}
+
+func (suggester *Suggester) remove(id int) {
+	for _, key := range suggester.keys[id] {
+		bucket := bucketOf(key)
+		entries := suggester.buckets[bucket]
+		position := search(entries, key, id)
+		if position < len(entries) && entries[position] == (entry{key: key, id: id}) {
+			suggester.buckets[bucket] = append(entries[:position], entries[position+1:]...)
+		}
+	}
+	delete(suggester.keys, id)
+}
+
+// search returns the position of (key, id) in the sorted entries.
+func search(entries []entry, key string, id int) int {
+	return sort.Search(len(entries), func(i int) bool {
+		current := entries[i]
+		return current.key > key || (current.key == key && current.id >= id)
+	})
+}
+
+// Suggest returns up to limit documents with a key starting with prefix,
+// highest rank first. prefix is folded like the keys.
+func (suggester *Suggester) Suggest(prefix string, limit int, rank func(id int) float64) []int {
+	prefix = strings.Join(Tokenize(prefix), " ")
+	if prefix == "" || limit <= 0 {
+		return []int{}
+	}
+
+	suggester.mu.RLock()
+	defer suggester.mu.RUnlock()
+
+	// A bounded min-heap keeps the best limit documents of the range.
+	best := &rankHeap{}
+	seen := map[int]bool{}
+	for bucket, entries := range suggester.buckets {
+		// Short prefixes span every bucket they start, longer ones one bucket.
+		// Buckets are counted in characters, not bytes, like bucketOf.
+		if bucket != bucketOf(prefix) && !(utf8.RuneCountInString(prefix) < bucketLength && strings.HasPrefix(bucket, prefix)) {
+			continue
+		}
+		start := sort.Search(len(entries), func(i int) bool {
+			return entries[i].key >= prefix
+		})
+		for _, current := range entries[start:] {
+			if !strings.HasPrefix(current.key, prefix) {
+				break
+			}
+			if seen[current.id] {
+				continue
+			}
+			seen[current.id] = true
+			candidate := ranked{id: current.id, rank: rank(current.id)}
+			if best.Len() < limit {
+				heap.Push(best, candidate)
+			} else if candidate.better((*best)[0]) {
+				(*best)[0] = candidate
+				heap.Fix(best, 0)
+			}
+		}
+	}
+
+	ids := make([]int, best.Len())
+	for i := len(ids) - 1; i >= 0; i-- {
+		ids[i] = heap.Pop(best).(ranked).id
+	}
+	return ids
+}
+
+type ranked struct {
+	id   int
+	rank float64
+}
+
+func (candidate ranked) better(other ranked) bool {
+	if candidate.rank != other.rank {
+		return candidate.rank > other.rank
+	}
+	return candidate.id < other.id
+}
+
+// rankHeap is a min-heap: the worst kept candidate is at the root.
+type rankHeap []ranked
+
+func (h rankHeap) Len() int            { return len(h) }
+func (h rankHeap) Less(i, j int) bool  { return h[j].better(h[i]) }
+func (h rankHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *rankHeap) Push(x interface{}) { *h = append(*h, x.(ranked)) }
+func (h *rankHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggester(t *testing.T) {
+	prices := map[int]float64{2: 352.79, 3: 179.23, 4: 275.47, 7: 90.5, 9: 12}
+	byPrice := func(id int) float64 { return prices[id] }
+
+	suggester := NewSuggester()
+	suggester.Load(map[int][]string{
+		2: append(SuggestionKeys("Pineapple - Canned, Rings"), SuggestionKeys("M4637")...),
+		3: append(SuggestionKeys("Wine - Red Oakridge Merlot"), SuggestionKeys("T65812")...),
+		4: append(SuggestionKeys("Cookie - Oatmeal"), SuggestionKeys("M7157")...),
+	})
+	suggester.Put(7, append(SuggestionKeys("Wine - White"), SuggestionKeys("W7")...))
+	suggester.Put(9, append(SuggestionKeys("Limón"), SuggestionKeys("M4")...))
+
+	t.Run("should suggest by name, word and code value", func(t *testing.T) {
+		assert.Equal(t, []int{3, 7}, suggester.Suggest("wi", 10, byPrice))
+		assert.Equal(t, []int{3}, suggester.Suggest("Wine - R", 10, byPrice))
+		assert.Equal(t, []int{4}, suggester.Suggest("oat", 10, byPrice))
+		assert.Equal(t, []int{2, 9}, suggester.Suggest("M4", 10, byPrice))
+		assert.Equal(t, []int{9}, suggester.Suggest("limo", 10, byPrice))
+		assert.Empty(t, suggester.Suggest("", 10, byPrice))
+	})
+
+	t.Run("should suggest by a prefix of one character of several bytes", func(t *testing.T) {
+		suggester := NewSuggester()
+		suggester.Put(1, SuggestionKeys("Ωmega"))
+		suggester.Put(2, SuggestionKeys("ω"))
+		assert.Equal(t, []int{1, 2}, suggester.Suggest("ω", 10, func(id int) float64 { return float64(-id) }))
+		assert.Equal(t, []int{1}, suggester.Suggest("ωm", 10, byPrice))
+	})
+
+	t.Run("should keep the best ranked suggestions", func(t *testing.T) {
+		assert.Equal(t, []int{3}, suggester.Suggest("wi", 1, byPrice))
+		assert.Equal(t, []int{7}, suggester.Suggest("wi", 1, func(id int) float64 { return float64(id) }))
+	})
+
+	t.Run("should follow updates and deletes", func(t *testing.T) {
+		suggester.Put(4, SuggestionKeys("Biscuit - Oatmeal"))
+		suggester.Remove(3)
+
+		assert.Equal(t, []int{7}, suggester.Suggest("wi", 10, byPrice))
+		assert.Empty(t, suggester.Suggest("cook", 10, byPrice))
+		assert.Equal(t, []int{4}, suggester.Suggest("bis", 10, byPrice))
+	})
+}
+
+func BenchmarkSuggester(b *testing.B) {
+	words := []string{"Wine", "Cookie", "Pineapple", "Oil", "Flavouring", "Cake", "Bread", "Tea", "Rice", "Juice",
+		"Lemon", "Sauce", "Beef", "Yogurt", "Nuts", "Gin", "Ham", "Kiwi", "Duck", "Veal"}
+
+	for _, size := range []int{500, 1000000} {
+		suggester := NewSuggester()
+		documents := make(map[int][]string, size)
+		for id := 0; id < size; id++ {
+			name := fmt.Sprintf("%s - %s %d", words[id%len(words)], words[id/len(words)%len(words)], id)
+			documents[id] = append(SuggestionKeys(name), SuggestionKeys(fmt.Sprintf("M%d", id))...)
+		}
+		suggester.Load(documents)
+		rank := func(id int) float64 { return float64(id % 1000) }
+
+		b.Run(fmt.Sprintf("suggest/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				suggester.Suggest("wine - ki", 10, rank)
+			}
+		})
+		b.Run(fmt.Sprintf("put/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				id := i % size
+				suggester.Put(id, SuggestionKeys(fmt.Sprintf("%s - Renamed %d", words[i%len(words)], id)))
+			}
+		})
+	}
+}
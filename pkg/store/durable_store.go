@@ -0,0 +1,215 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+)
+
+// DefaultCompactEvery is the number of logged operations after which a
+// DurableStore folds its write-ahead log into a new snapshot.
+const DefaultCompactEvery = 100
+
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Entry is one line of the write-ahead log.
+type Entry struct {
+	Operation Operation      `json:"op"`
+	Product   domain.Product `json:"product"`
+}
+
+// apply replays the entry over products. Creates and updates are upserts and
+// deletes of missing products are ignored, so replaying a log that was already
+// folded into the snapshot (a crash between rename and truncate) is harmless.
+func (entry Entry) apply(products []domain.Product) []domain.Product {
+	for i := range products {
+		if products[i].ID != entry.Product.ID {
+			continue
+		}
+		if entry.Operation == OperationDelete {
+			return append(products[:i], products[i+1:]...)
+		}
+		products[i] = entry.Product
+		return products
+	}
+	if entry.Operation == OperationDelete {
+		return products
+	}
+	return append(products, entry.Product)
+}
+
+// DurableStore persists products as a JSON snapshot plus an append-only
+// write-ahead log next to it (<snapshot>.wal). Every operation is synced to
+// the log before Append returns and the log is compacted into the snapshot
+// every CompactEvery operations.
+type DurableStore struct {
+	CompactEvery int
+
+	mu       sync.Mutex
+	path     string
+	log      logFile
+	products []domain.Product
+	pending  int
+}
+
+// logFile is the file the log is appended to, an *os.File but in tests.
+type logFile interface {
+	io.Writer
+	io.Closer
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+}
+
+func OpenDurableStore(path string) (*DurableStore, error) {
+	products, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	store := &DurableStore{
+		CompactEvery: DefaultCompactEvery,
+		path:         path,
+		products:     products,
+	}
+	// Start every run from a fresh snapshot, which also drops a torn last
+	// entry left by a crash in the middle of an append.
+	if err := store.compact(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Products returns a copy of the persisted products.
+func (store *DurableStore) Products() []domain.Product {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return append([]domain.Product{}, store.products...)
+}
+
+func (store *DurableStore) Append(operation Operation, product domain.Product) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry := Entry{Operation: operation, Product: product}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.New("error converting text to json")
+	}
+	info, err := store.log.Stat()
+	if err != nil {
+		return fmt.Errorf("error writting log: %w", err)
+	}
+	// A failed write is cut off the log, so that the next entry does not
+	// follow a torn line and an entry the caller was told failed is not
+	// replayed.
+	if _, err := store.log.Write(append(data, '\n')); err != nil {
+		store.log.Truncate(info.Size())
+		return fmt.Errorf("error writting log: %w", err)
+	}
+	if err := store.log.Sync(); err != nil {
+		store.log.Truncate(info.Size())
+		return fmt.Errorf("error syncing log: %w", err)
+	}
+	store.products = entry.apply(store.products)
+	store.pending++
+
+	// The operation is already durable in the log, so a failed compaction
+	// is only logged, and tried again on the next append.
+	if store.CompactEvery > 0 && store.pending >= store.CompactEvery {
+		if err := store.compact(); err != nil {
+			log.Printf("error compacting %s: %v", store.path, err)
+		}
+	}
+	return nil
+}
+
+// Compact writes the current state as the new snapshot and empties the log.
+func (store *DurableStore) Compact() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.compact()
+}
+
+func (store *DurableStore) compact() error {
+	if err := saveSnapshot(store.path, store.products); err != nil {
+		return err
+	}
+	// The current log is only closed once the new one is open, so that the
+	// store can still append when it cannot be.
+	file, err := os.OpenFile(logPath(store.path), os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error syncing log: %w", err)
+	}
+	if store.log != nil {
+		store.log.Close()
+	}
+	store.log = file
+	store.pending = 0
+	return nil
+}
+
+// Close compacts the log and releases the log file.
+func (store *DurableStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err := store.compact(); err != nil {
+		return err
+	}
+	return store.log.Close()
+}
+
+func logPath(path string) string {
+	return path + ".wal"
+}
+
+// readLog returns the entries of the log at path. A missing log is empty and
+// a last line that cannot be decoded is a torn write and is skipped.
+func readLog(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening log: %w", err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, line) {
+				break
+			}
+			return nil, fmt.Errorf("error decoding log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Andrea-Reyna/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSnapshot(t *testing.T, products []domain.Product) string {
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, saveSnapshot(path, products))
+	return path
+}
+
+// failingLog writes the first half of every write to the log and fails.
+type failingLog struct {
+	*os.File
+}
+
+func (file failingLog) Write(data []byte) (int, error) {
+	written, _ := file.File.Write(data[:len(data)/2])
+	return written, errors.New("disk full")
+}
+
+func TestDurableStore(t *testing.T) {
+	initial := []domain.Product{
+		{ID: 2, Name: "Pineapple", CodeValue: "M4637", Expiration: domain.NewDate(2021, time.August, 9), Price: domain.NewMoney(35279, "ARS")},
+		{ID: 3, Name: "Wine", CodeValue: "T65812", Expiration: domain.NewDate(2021, time.May, 24), Price: domain.NewMoney(17923, "ARS")},
+	}
+
+	t.Run("should replay the log when the snapshot was not compacted", func(t *testing.T) {
+		path := newSnapshot(t, initial)
+		store, err := OpenDurableStore(path)
+		require.NoError(t, err)
+		store.CompactEvery = 0
+
+		cookie := domain.Product{ID: 4, Name: "Cookie", Price: domain.NewMoney(27547, "ARS")}
+		pineapple := domain.Product{ID: 2, Name: "Pineapple MOD", Price: domain.NewMoney(35279, "ARS")}
+		require.NoError(t, store.Append(OperationCreate, cookie))
+		require.NoError(t, store.Append(OperationUpdate, pineapple))
+		require.NoError(t, store.Append(OperationDelete, domain.Product{ID: 3}))
+
+		// Simulates a crash: the snapshot still has the initial products.
+		snapshot, err := loadSnapshot(path)
+		require.NoError(t, err)
+		assert.Equal(t, initial, snapshot)
+
+		products, err := loadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Product{pineapple, cookie}, products)
+	})
+
+	t.Run("should rewrite dates in the canonical layout on open", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "products.json")
+		snapshot := `[{"id":2,"expiration":"09/08/2021"},{"id":3,"expiration":"2021-05-24"},{"id":4,"expiration":"2022-01-28T23:00:00-03:00"}]`
+		require.NoError(t, os.WriteFile(path, []byte(snapshot), 0644))
+		require.NoError(t, domain.SetDateLayout(domain.DateLayoutISO))
+		t.Cleanup(func() { domain.SetDateLayout(domain.DateLayoutDMY) })
+
+		store, err := OpenDurableStore(path)
+		require.NoError(t, err)
+		require.NoError(t, store.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"expiration":"2021-08-09"`)
+		assert.Contains(t, string(data), `"expiration":"2021-05-24"`)
+		assert.Contains(t, string(data), `"expiration":"2022-01-28"`)
+	})
+
+	t.Run("should ignore a torn last entry", func(t *testing.T) {
+		path := newSnapshot(t, initial)
+		log := `{"op":"delete","product":{"id":2}}` + "\n" + `{"op":"create","product":{"id":5,"na`
+		require.NoError(t, os.WriteFile(logPath(path), []byte(log), 0644))
+
+		store, err := OpenDurableStore(path)
+		require.NoError(t, err)
+		assert.Equal(t, initial[1:], store.Products())
+
+		data, err := os.ReadFile(logPath(path))
+		require.NoError(t, err)
+		assert.Empty(t, data)
+	})
+
+	t.Run("should compact after the configured number of operations", func(t *testing.T) {
+		path := newSnapshot(t, initial)
+		store, err := OpenDurableStore(path)
+		require.NoError(t, err)
+		store.CompactEvery = 2
+
+		require.NoError(t, store.Append(OperationDelete, domain.Product{ID: 2}))
+		require.NoError(t, store.Append(OperationDelete, domain.Product{ID: 3}))
+
+		snapshot, err := loadSnapshot(path)
+		require.NoError(t, err)
+		assert.Empty(t, snapshot)
+		require.NoError(t, store.Close())
+	})
+
+	t.Run("should keep an operation logged when it cannot compact", func(t *testing.T) {
+		path := newSnapshot(t, initial)
+		store, err := OpenDurableStore(path)
+		require.NoError(t, err)
+		store.CompactEvery = 1
+		// A directory in place of the snapshot cannot be replaced by a file.
+		require.NoError(t, os.Remove(path))
+		require.NoError(t, os.MkdirAll(filepath.Join(path, "blocked"), 0755))
+
+		require.NoError(t, store.Append(OperationDelete, domain.Product{ID: 2}))
+		assert.Equal(t, initial[1:], store.Products())
+		entries, err := readLog(logPath(path))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, OperationDelete, entries[0].Operation)
+		assert.Equal(t, 2, entries[0].Product.ID)
+
+		require.NoError(t, os.RemoveAll(path))
+		require.NoError(t, store.Append(OperationDelete, domain.Product{ID: 3}))
+		snapshot, err := loadSnapshot(path)
+		require.NoError(t, err)
+		assert.Empty(t, snapshot)
+		require.NoError(t, store.Close())
+	})
+
+	t.Run("should cut a failed append off the log", func(t *testing.T) {
+		path := newSnapshot(t, initial)
+		store, err := OpenDurableStore(path)
+		require.NoError(t, err)
+		store.CompactEvery = 0
+		require.NoError(t, store.Append(OperationDelete, domain.Product{ID: 2}))
+
+		file := store.log.(*os.File)
+		store.log = failingLog{file}
+		assert.Error(t, store.Append(OperationDelete, domain.Product{ID: 3}))
+		store.log = file
+		require.NoError(t, store.Append(OperationCreate, domain.Product{ID: 4, Name: "Cookie"}))
+
+		reopened, err := OpenDurableStore(path)
+		require.NoError(t, err)
+		defer reopened.Close()
+		products := reopened.Products()
+		require.Len(t, products, 2)
+		assert.Equal(t, 3, products[0].ID)
+		assert.Equal(t, 4, products[1].ID)
+	})
+
+	t.Run("should fail on a corrupted entry in the middle of the log", func(t *testing.T) {
+		path := newSnapshot(t, initial)
+		log := "not json\n" + `{"op":"delete","product":{"id":2}}` + "\n"
+		require.NoError(t, os.WriteFile(logPath(path), []byte(log), 0644))
+
+		_, err := OpenDurableStore(path)
+		assert.Error(t, err)
+	})
+}
@@ -5,13 +5,35 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/Andrea-Reyna/go-web/internal/domain"
 )
 
+// LoadFile reads the snapshot in FILE and replays its write-ahead log, so the
+// result includes every operation acknowledged before the last shutdown.
 func LoadFile() ([]domain.Product, error) {
+	return loadFile(os.Getenv("FILE"))
+}
+
+func loadFile(path string) ([]domain.Product, error) {
+	products, err := loadSnapshot(path)
+	if err != nil {
+		return products, err
+	}
+	entries, err := readLog(logPath(path))
+	if err != nil {
+		return products, err
+	}
+	for _, entry := range entries {
+		products = entry.apply(products)
+	}
+	return products, nil
+}
+
+func loadSnapshot(path string) ([]domain.Product, error) {
 	products := []domain.Product{}
-	file, err := os.Open(os.Getenv("FILE"))
+	file, err := os.Open(path)
 	if err != nil {
 		return products, fmt.Errorf("error opening file: %w", err)
 	}
@@ -25,21 +47,62 @@ func LoadFile() ([]domain.Product, error) {
 	return products, nil
 }
 
+// SaveProducts replaces FILE with the given products. The data is written to
+// a temporary file, synced and renamed over FILE, so readers either see the
+// old or the new content and never a partially written file.
 func SaveProducts(products []domain.Product) error {
-	file, err := os.Open(os.Getenv("FILE"))
-	if err != nil {
-		return fmt.Errorf("error opening file: %w", err)
-	}
-	defer file.Close()
+	return saveSnapshot(os.Getenv("FILE"), products)
+}
 
+func saveSnapshot(path string, products []domain.Product) error {
 	data, err := json.Marshal(products)
 	if err != nil {
 		return errors.New("error converting text to json")
 	}
+	return WriteFileAtomic(path, data, 0644)
+}
 
-	err = os.WriteFile(os.Getenv("FILE"), data, 0644)
+// WriteFileAtomic writes data to a temporary file in the same directory as
+// path, syncs it and renames it over path.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	temp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
 	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	// Removing a renamed file fails silently, so this only cleans up on error.
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
 		return errors.New("error writting file")
 	}
+	if err := temp.Chmod(perm); err != nil {
+		temp.Close()
+		return fmt.Errorf("error setting file mode: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("error syncing file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing file: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes a rename in dir durable.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("error opening directory: %w", err)
+	}
+	defer file.Close()
+	if err := file.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return fmt.Errorf("error syncing directory: %w", err)
+	}
 	return nil
 }
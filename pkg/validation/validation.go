@@ -0,0 +1,141 @@
+// Package validation checks values against composable rules and collects
+// every violation found instead of stopping at the first one.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation is a rule broken by one field. Code is a stable name of the rule
+// and Message tells what the value must be, e.g. "must be at least 0".
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is the error returned for the violations of a validation.
+type Errors []Violation
+
+func (errors Errors) Error() string {
+	messages := make([]string, len(errors))
+	for i, violation := range errors {
+		messages[i] = violation.Field + " " + violation.Message
+	}
+	return "invalid data: " + strings.Join(messages, ", ")
+}
+
+// Prefix returns the violations with prefix added to their fields, e.g. to
+// tell which element of a list they belong to.
+func (errors Errors) Prefix(prefix string) Errors {
+	prefixed := make(Errors, len(errors))
+	for i, violation := range errors {
+		violation.Field = prefix + violation.Field
+		prefixed[i] = violation
+	}
+	return prefixed
+}
+
+// Rule checks a value. It returns nil when the value is valid, otherwise the
+// violation with an empty Field, which Field fills in.
+type Rule[T any] func(value T) *Violation
+
+// Number is the type of the values numeric rules accept.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+// Field checks value against rules in order and returns the violation of the
+// first rule broken, so that a field reports one problem at a time.
+func Field[T any](name string, value T, rules ...Rule[T]) Errors {
+	for _, rule := range rules {
+		if violation := rule(value); violation != nil {
+			violation.Field = name
+			return Errors{*violation}
+		}
+	}
+	return nil
+}
+
+// Validate collects the violations of every field. It returns nil when there
+// are none and Errors otherwise.
+func Validate(fields ...Errors) error {
+	var violations Errors
+	for _, field := range fields {
+		violations = append(violations, field...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+// When applies rules only if condition holds.
+func When[T any](condition bool, rules ...Rule[T]) Rule[T] {
+	return func(value T) *Violation {
+		if !condition {
+			return nil
+		}
+		for _, rule := range rules {
+			if violation := rule(value); violation != nil {
+				return violation
+			}
+		}
+		return nil
+	}
+}
+
+// Required rejects the zero value.
+func Required[T comparable]() Rule[T] {
+	return func(value T) *Violation {
+		var zero T
+		if value == zero {
+			return &Violation{Code: "required", Message: "is required"}
+		}
+		return nil
+	}
+}
+
+// Min rejects values lower than min.
+func Min[T Number](min T) Rule[T] {
+	return func(value T) *Violation {
+		if value < min {
+			return &Violation{Code: "min", Message: fmt.Sprintf("must be at least %v", min)}
+		}
+		return nil
+	}
+}
+
+// Positive rejects zero and negative values.
+func Positive[T Number]() Rule[T] {
+	return func(value T) *Violation {
+		if value <= 0 {
+			return &Violation{Code: "positive", Message: "must be greater than 0"}
+		}
+		return nil
+	}
+}
+
+// MaxLength rejects strings of more than max characters.
+func MaxLength(max int) Rule[string] {
+	return func(value string) *Violation {
+		if utf8.RuneCountInString(value) > max {
+			return &Violation{Code: "max_length", Message: fmt.Sprintf("must be at most %d characters long", max)}
+		}
+		return nil
+	}
+}
+
+// Pattern rejects strings that do not match pattern. Description tells
+// clients what a matching value looks like.
+func Pattern(pattern *regexp.Regexp, description string) Rule[string] {
+	return func(value string) *Violation {
+		if !pattern.MatchString(value) {
+			return &Violation{Code: "pattern", Message: "must be " + description}
+		}
+		return nil
+	}
+}
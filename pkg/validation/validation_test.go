@@ -0,0 +1,60 @@
+package validation
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("should collect the violations of every field", func(t *testing.T) {
+		err := Validate(
+			Field("name", "", Required[string](), MaxLength(3)),
+			Field("quantity", -1, Min(0)),
+			Field("price", 2.5, Positive[float64]()),
+		)
+
+		assert.Equal(t, Errors{
+			{Field: "name", Code: "required", Message: "is required"},
+			{Field: "quantity", Code: "min", Message: "must be at least 0"},
+		}, err)
+		assert.EqualError(t, err, "invalid data: name is required, quantity must be at least 0")
+	})
+
+	t.Run("should return nil without violations", func(t *testing.T) {
+		assert.NoError(t, Validate(Field("quantity", 0, Min(0))))
+	})
+
+	t.Run("should report the first rule a field breaks", func(t *testing.T) {
+		err := Validate(Field("name", "abcd", Required[string](), MaxLength(3), Pattern(regexp.MustCompile(`^\d+$`), "digits")))
+
+		assert.Equal(t, Errors{{Field: "name", Code: "max_length", Message: "must be at most 3 characters long"}}, err)
+	})
+}
+
+func TestRules(t *testing.T) {
+	cases := []struct {
+		name      string
+		violation *Violation
+	}{
+		{"min", Min(1)(0)},
+		{"positive", Positive[float64]()(0)},
+		{"max length", MaxLength(2)("ñañ")},
+		{"pattern", Pattern(regexp.MustCompile(`^[A-Z]+$`), "uppercase letters")("abc")},
+	}
+	for _, c := range cases {
+		assert.NotNil(t, c.violation, c.name)
+	}
+
+	assert.Nil(t, MaxLength(3)("ñañ"))
+	assert.Nil(t, When(false, Min(1))(0))
+	assert.Equal(t, &Violation{Code: "min", Message: "must be at least 1"}, When(true, Min(1))(0))
+}
+
+func TestErrors_Prefix(t *testing.T) {
+	errors := Errors{{Field: "price", Code: "positive", Message: "must be greater than 0"}}
+
+	assert.Equal(t, Errors{{Field: "[2].price", Code: "positive", Message: "must be greater than 0"}}, errors.Prefix("[2]."))
+	assert.Equal(t, "price", errors[0].Field)
+}